@@ -0,0 +1,133 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"context-doctor/rules"
+)
+
+// defaultBaselinePath is used by -update-baseline when -baseline isn't given
+const defaultBaselinePath = ".context-doctor/baseline.json"
+
+var (
+	// activeBaseline hides known findings during analysis (nil when no baseline is used)
+	activeBaseline *rules.Baseline
+	// baselineRoot is the directory baseline file paths are relative to
+	baselineRoot string
+)
+
+// setupBaseline resolves -baseline/-update-baseline and loads the baseline file.
+// A missing file is only an error when findings are being filtered.
+func setupBaseline() error {
+	if baselinePath == "" {
+		if !updateBaseline {
+			return nil
+		}
+		baselinePath = defaultBaselinePath
+	}
+
+	baselineRoot = baselineRootFor(baselinePath)
+
+	if updateBaseline {
+		return nil
+	}
+
+	b, err := rules.LoadBaseline(baselinePath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("baseline %s not found (create it with -update-baseline)", baselinePath)
+		}
+		return err
+	}
+	activeBaseline = b
+	return nil
+}
+
+// baselineRootFor keys baseline entries relative to the repository root so the
+// same baseline works no matter where context-doctor is run from
+func baselineRootFor(path string) string {
+	dir, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		dir = filepath.Dir(path)
+	}
+	// The baseline directory may not exist yet when it's first written
+	for {
+		if _, err := os.Stat(dir); err == nil {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	if root := rules.GetGitRoot(dir); root != "" {
+		return root
+	}
+	return dir
+}
+
+// baselineKey returns the path used to identify a file in the baseline
+func baselineKey(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		abs = path
+	}
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		abs = resolved
+	}
+	return filepath.ToSlash(relPath(baselineRoot, abs))
+}
+
+// applyBaseline hides baselined findings in the primary results and every referenced doc
+func applyBaseline(filePath string, results []rules.RuleResult, refs []rules.RefInfo, refResults map[string][]rules.RuleResult) []rules.RuleResult {
+	if activeBaseline == nil {
+		return results
+	}
+	for _, ref := range rules.FlattenRefs(refs) {
+		if rr, ok := refResults[ref.Path]; ok {
+			refResults[ref.Path] = activeBaseline.Filter(baselineKey(ref.ResolvedPath), rr)
+		}
+	}
+	return activeBaseline.Filter(baselineKey(filePath), results)
+}
+
+// writeBaseline records every current finding of the analysed files
+func writeBaseline(analyses []*fileAnalysis) error {
+	b := &rules.Baseline{}
+	for _, fa := range analyses {
+		b.Findings = append(b.Findings, rules.BaselineEntries(baselineKey(fa.FilePath), fa.Results)...)
+		for _, ref := range rules.FlattenRefs(fa.Refs) {
+			if rr, ok := fa.RefResults[ref.Path]; ok {
+				b.Findings = append(b.Findings, rules.BaselineEntries(baselineKey(ref.ResolvedPath), rr)...)
+			}
+		}
+	}
+
+	if err := b.Save(baselinePath); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Baseline written to %s (%d findings)\n", baselinePath, len(b.Findings))
+	return nil
+}
+
+// countBaselined returns how many findings of the analysis are hidden by the baseline
+func countBaselined(fa *fileAnalysis) int {
+	count := 0
+	tally := func(results []rules.RuleResult) {
+		for _, r := range results {
+			if r.Baselined && len(r.BaselinedSpans) == 0 {
+				count++
+			}
+			count += len(r.BaselinedSpans)
+		}
+	}
+	tally(fa.Results)
+	for _, rr := range fa.RefResults {
+		tally(rr)
+	}
+	return count
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"context-doctor/rules"
+)
+
+func TestBaselineRootFor_MissingDirectory(t *testing.T) {
+	dir := t.TempDir()
+	got := baselineRootFor(filepath.Join(dir, "not", "yet", "baseline.json"))
+
+	want, _ := filepath.EvalSymlinks(dir)
+	if resolved, _ := filepath.EvalSymlinks(got); resolved != want {
+		t.Errorf("expected nearest existing directory %s, got %s", want, got)
+	}
+}
+
+func TestApplyBaseline_FiltersPrimaryAndRefs(t *testing.T) {
+	dir := t.TempDir()
+	dir, _ = filepath.EvalSymlinks(dir)
+	saved, savedRoot := activeBaseline, baselineRoot
+	defer func() { activeBaseline, baselineRoot = saved, savedRoot }()
+
+	baselineRoot = dir
+	primary := filepath.Join(dir, "CLAUDE.md")
+	doc := filepath.Join(dir, "docs", "guide.md")
+	for _, p := range []string{primary, doc} {
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte("# doc"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	finding := func(code string) rules.RuleResult {
+		return rules.RuleResult{Rule: rules.Rule{Code: code, Severity: rules.SeverityWarning}, Passed: true}
+	}
+	activeBaseline = &rules.Baseline{Findings: []rules.BaselineEntry{
+		{Code: "CD001", File: "CLAUDE.md"},
+		{Code: "CD050", File: "docs/guide.md"},
+	}}
+
+	refs := []rules.RefInfo{{Path: "docs/guide.md", ResolvedPath: doc, Exists: true}}
+	refResults := map[string][]rules.RuleResult{"docs/guide.md": {finding("CD050")}}
+	results := applyBaseline(primary, []rules.RuleResult{finding("CD001"), finding("CD002")}, refs, refResults)
+
+	if results[0].Passed || !results[0].Baselined {
+		t.Errorf("expected CD001 baselined, got %+v", results[0])
+	}
+	if !results[1].Passed {
+		t.Errorf("expected CD002 still reported, got %+v", results[1])
+	}
+	if r := refResults["docs/guide.md"][0]; r.Passed || !r.Baselined {
+		t.Errorf("expected referenced doc finding baselined, got %+v", r)
+	}
+}
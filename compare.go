@@ -0,0 +1,371 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"context-doctor/rules"
+)
+
+// cmdDiff is the subcommand that compares context files with a base revision
+const cmdDiff = "diff"
+
+var diffBase string
+
+// diffFinding is one location of a detected problem, or a problem of a
+// whole file
+type diffFinding struct {
+	File string // repo-relative, or absolute outside the repository
+	Rule rules.Rule
+	Span *rules.Span // nil for file-level findings
+}
+
+// key identifies the finding across revisions the way the baseline does:
+// by file, rule and the fingerprint of the matched text
+func (f diffFinding) key() rules.BaselineEntry {
+	e := rules.BaselineEntry{Code: f.Rule.Code, File: f.File}
+	if f.Span != nil {
+		e.Fingerprint = rules.Fingerprint(*f.Span)
+	}
+	return e
+}
+
+// addedInstruction is an instruction whose text the base revision doesn't have
+type addedInstruction struct {
+	File string
+	Span rules.Span
+}
+
+// fileDiff compares a context file and its reference tree at the base
+// revision with the working tree
+type fileDiff struct {
+	Path              string        // repo-relative
+	Base              *fileAnalysis // nil when the file doesn't exist at the base revision
+	Head              *fileAnalysis
+	New               []diffFinding
+	Fixed             []diffFinding // located in the base revision
+	Unchanged         []diffFinding
+	AddedInstructions []addedInstruction
+}
+
+// diffReport is the result of the diff command
+type diffReport struct {
+	Base   string // the revision asked for
+	Commit string // the commit it resolved to
+	Files  []*fileDiff
+}
+
+// runDiff analyses the context files at target in the working tree and at
+// the base revision, read from git objects, and reports what changed. Gates
+// apply to the change: -fail-on to new findings and -regression to score
+// drops, while -min-score and -min-dimension still check the working tree.
+func runDiff(target string, isDir bool, gates gateOptions) int {
+	dir := target
+	if !isDir {
+		dir = filepath.Dir(absPath(target))
+	}
+	root := rules.GetGitRoot(dir)
+	if root == "" {
+		fmt.Fprintf(os.Stderr, "Error: %s is not in a git repository\n", target)
+		return exitError
+	}
+	if outputFormat == formatSARIF {
+		fmt.Fprintln(os.Stderr, "Error: the diff command has no SARIF output")
+		return exitError
+	}
+
+	files := []string{target}
+	if isDir {
+		files = findContextFiles(target)
+		if len(files) == 0 {
+			fmt.Fprintf(os.Stderr, "No context files found in %s\n", target)
+			return exitError
+		}
+	}
+
+	base, err := rules.NewGitSource(root, diffBase)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitError
+	}
+	defer base.Close()
+
+	report := &diffReport{Base: diffBase, Commit: base.Commit}
+	for _, file := range files {
+		fd, err := diffFile(root, base, file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s: %v\n", file, err)
+			return exitError
+		}
+		report.Files = append(report.Files, fd)
+	}
+
+	if outputFormat == formatJSON {
+		writeJSON(os.Stdout, newJSONDiffReport(report, buildFilterOpts()))
+	} else {
+		printDiff(report)
+	}
+	return reportGateFailures(gates.checkDiff(report))
+}
+
+// diffFile analyses file in the working tree and at the base revision and
+// compares the two
+func diffFile(root string, base rules.Source, file string) (*fileDiff, error) {
+	rel, ok := rules.RepoPath(root, file)
+	if !ok {
+		return nil, fmt.Errorf("outside the repository at %s", root)
+	}
+	head, err := buildAnalysis(file)
+	if err != nil {
+		return nil, err
+	}
+	before, err := analyzeFrom(base, filepath.Join(root, filepath.FromSlash(rel)))
+	if errors.Is(err, fs.ErrNotExist) {
+		before, err = nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	fd := &fileDiff{Path: rel, Base: before, Head: head}
+	headFindings := collectFindings(root, head)
+	var baseFindings []diffFinding
+	if before != nil {
+		baseFindings = collectFindings(root, before)
+	}
+	fd.New, fd.Fixed, fd.Unchanged = compareFindings(baseFindings, headFindings)
+	fd.AddedInstructions = addedInstructions(root, before, head)
+	return fd, nil
+}
+
+// collectFindings lists every location of a detected problem in the file
+// and its referenced docs, in report order. Like the gates, it ignores the
+// display filters.
+func collectFindings(root string, fa *fileAnalysis) []diffFinding {
+	var findings []diffFinding
+	collect := func(path string, results []rules.RuleResult) {
+		file := diffPath(root, path)
+		for _, r := range results {
+			if !r.Passed || r.Rule.Category == "good-practice" {
+				continue
+			}
+			if len(r.Spans) == 0 {
+				findings = append(findings, diffFinding{File: file, Rule: r.Rule})
+				continue
+			}
+			for _, span := range r.Spans {
+				findings = append(findings, diffFinding{File: file, Rule: r.Rule, Span: &span})
+			}
+		}
+	}
+
+	collect(fa.FilePath, fa.Results)
+	for _, ref := range rules.FlattenRefs(fa.Refs) {
+		if rr, ok := fa.RefResults[ref.Path]; ok {
+			collect(ref.ResolvedPath, rr)
+		}
+	}
+	return findings
+}
+
+// compareFindings splits the findings into those only in head, only in base
+// and in both. Each finding matches at most one on the other side, so a
+// second copy of a known problem is new.
+func compareFindings(base, head []diffFinding) (added, fixed, unchanged []diffFinding) {
+	inBase := make(map[rules.BaselineEntry]int)
+	for _, f := range base {
+		inBase[f.key()]++
+	}
+	inHead := make(map[rules.BaselineEntry]int)
+	for _, f := range head {
+		k := f.key()
+		inHead[k]++
+		if inBase[k] > 0 {
+			inBase[k]--
+			unchanged = append(unchanged, f)
+			continue
+		}
+		added = append(added, f)
+	}
+	for _, f := range base {
+		k := f.key()
+		if inHead[k] > 0 {
+			inHead[k]--
+			continue
+		}
+		fixed = append(fixed, f)
+	}
+	return added, fixed, unchanged
+}
+
+// addedInstructions lists the instructions in head's file and reference tree
+// whose text, ignoring case and spacing, appears nowhere in base's
+func addedInstructions(root string, base, head *fileAnalysis) []addedInstruction {
+	known := make(map[string]int)
+	if base != nil {
+		forEachInstruction(base, func(path string, span rules.Span) {
+			known[normalizeInstructionText(span.Text)]++
+		})
+	}
+
+	var added []addedInstruction
+	forEachInstruction(head, func(path string, span rules.Span) {
+		text := normalizeInstructionText(span.Text)
+		if known[text] > 0 {
+			known[text]--
+			return
+		}
+		added = append(added, addedInstruction{File: diffPath(root, path), Span: span})
+	})
+	return added
+}
+
+// forEachInstruction calls fn for every instruction of the file and the
+// referenced docs that exist
+func forEachInstruction(fa *fileAnalysis, fn func(path string, span rules.Span)) {
+	for _, span := range rules.InstructionSpans(fa.Ctx) {
+		fn(fa.FilePath, span)
+	}
+	for _, ref := range rules.FlattenRefs(fa.Refs) {
+		if ref.Exists && ref.Context != nil {
+			for _, span := range rules.InstructionSpans(ref.Context) {
+				fn(ref.ResolvedPath, span)
+			}
+		}
+	}
+}
+
+func normalizeInstructionText(s string) string {
+	return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+}
+
+// diffPath shows path relative to the repository, the same at both revisions
+func diffPath(root, path string) string {
+	if rel, ok := rules.RepoPath(root, path); ok {
+		return rel
+	}
+	return path
+}
+
+// scoreDelta returns the base and head scores of a file; the base is zero
+// for a file that is new
+func (fd *fileDiff) scoreDelta() (base, head int) {
+	if fd.Base != nil {
+		base = fd.Base.Score
+	}
+	return base, fd.Head.Score
+}
+
+// dimensionDelta returns the base and head scores of a dimension
+func (fd *fileDiff) dimensionDelta(dim rules.Dimension) (base, head int) {
+	score := func(fa *fileAnalysis) int {
+		if fa == nil || fa.DimensionScores == nil || fa.DimensionScores.Scores[dim] == nil {
+			return 0
+		}
+		return fa.DimensionScores.Scores[dim].Score
+	}
+	return score(fd.Base), score(fd.Head)
+}
+
+// checkDiff returns a description of every gate the comparison fails
+func (g gateOptions) checkDiff(report *diffReport) []string {
+	var failures []string
+
+	// Only new findings count against -fail-on
+	absolute := g
+	absolute.FailOn = ""
+	for _, fd := range report.Files {
+		if g.FailOn != "" {
+			n := 0
+			for _, f := range fd.New {
+				if severityRank[f.Rule.Severity] >= severityRank[g.FailOn] {
+					n++
+				}
+			}
+			if n > 0 {
+				failures = append(failures, fmt.Sprintf("%s: %d new finding(s) at or above %s", fd.Path, n, g.FailOn))
+			}
+		}
+
+		if regressionThreshold > 0 && fd.Base != nil {
+			if base, head := fd.scoreDelta(); base-head >= regressionThreshold {
+				failures = append(failures, fmt.Sprintf("%s: score dropped %d points (%d → %d)", fd.Path, base-head, base, head))
+			}
+			for _, dim := range rules.AllDimensions() {
+				if base, head := fd.dimensionDelta(dim); base-head >= regressionThreshold {
+					failures = append(failures, fmt.Sprintf("%s: %s score dropped %d points (%d → %d)", fd.Path, dim, base-head, base, head))
+				}
+			}
+		}
+
+		failures = append(failures, absolute.checkFile(fd.Head, fd.Path)...)
+	}
+	return failures
+}
+
+func printDiff(report *diffReport) {
+	fmt.Println("=" + strings.Repeat("=", 59))
+	fmt.Println("  Context File Diff")
+	fmt.Println("=" + strings.Repeat("=", 59))
+	fmt.Println()
+	fmt.Printf("Base: %s (%s)\n", report.Base, shortCommit(report.Commit))
+	fmt.Println()
+
+	for _, fd := range report.Files {
+		fmt.Println(fd.Path)
+		fmt.Println(strings.Repeat("-", 40))
+		base, head := fd.scoreDelta()
+		if fd.Base == nil {
+			fmt.Printf("  New file, score %d\n", head)
+		} else {
+			fmt.Printf("  Score: %d → %d (%s)\n", base, head, signed(head-base))
+			for _, dim := range rules.AllDimensions() {
+				if base, head := fd.dimensionDelta(dim); base != head {
+					fmt.Printf("    %-12s %3d → %3d (%s)\n", dim, base, head, signed(head-base))
+				}
+			}
+		}
+		fmt.Printf("  Findings: %d new, %d fixed, %d unchanged\n", len(fd.New), len(fd.Fixed), len(fd.Unchanged))
+		fmt.Println()
+
+		printDiffFindings("NEW FINDINGS", fd.New, getSeverityIcon)
+		printDiffFindings("FIXED FINDINGS", fd.Fixed, func(rules.Severity) string { return "✓" })
+
+		if len(fd.AddedInstructions) > 0 {
+			fmt.Printf("  ADDED INSTRUCTIONS (%d)\n", len(fd.AddedInstructions))
+			for _, in := range fd.AddedInstructions {
+				fmt.Printf("  + %s:%d: %s\n", in.File, in.Span.Line, truncate(in.Span.Text, 70))
+			}
+			fmt.Println()
+		}
+	}
+}
+
+// printDiffFindings prints findings under title, each rule once per file
+// with its locations below it
+func printDiffFindings(title string, findings []diffFinding, icon func(rules.Severity) string) {
+	if len(findings) == 0 {
+		return
+	}
+	fmt.Printf("  %s (%d)\n", title, len(findings))
+	for i, f := range findings {
+		if i == 0 || f.File != findings[i-1].File || f.Rule.Code != findings[i-1].Rule.Code {
+			fmt.Printf("  %s [%s] %s\n", icon(f.Rule.Severity), f.Rule.Code, f.Rule.ErrorMessage)
+		}
+		if f.Span == nil {
+			fmt.Printf("       %s (entire file)\n", f.File)
+			continue
+		}
+		fmt.Printf("       %s:%d: %s\n", f.File, f.Span.Line, truncate(f.Span.Snippet, 70))
+	}
+	fmt.Println()
+}
+
+// signed formats a delta with its sign, e.g. +3 or -12
+func signed(n int) string {
+	return fmt.Sprintf("%+d", n)
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"context-doctor/rules"
+)
+
+func sampleFinding(code, text string) diffFinding {
+	f := diffFinding{File: "CLAUDE.md", Rule: rules.Rule{Code: code, Severity: rules.SeverityWarning}}
+	if text != "" {
+		f.Span = &rules.Span{Line: 1, Text: text, Snippet: text}
+	}
+	return f
+}
+
+func findingCodes(findings []diffFinding) string {
+	var codes []string
+	for _, f := range findings {
+		s := f.Rule.Code
+		if f.Span != nil {
+			s += " " + f.Span.Text
+		}
+		codes = append(codes, s)
+	}
+	return strings.Join(codes, ", ")
+}
+
+func TestCompareFindings(t *testing.T) {
+	base := []diffFinding{
+		sampleFinding("CD001", ""),
+		sampleFinding("CD081", "src/a.go"),
+		sampleFinding("CD081", "src/b.go"),
+	}
+	head := []diffFinding{
+		sampleFinding("CD001", ""),
+		sampleFinding("CD081", "SRC/A.GO"), // same fingerprint
+		sampleFinding("CD081", "src/a.go"), // a second copy is new
+		sampleFinding("CD080", "make lint"),
+	}
+
+	added, fixed, unchanged := compareFindings(base, head)
+	if got := findingCodes(added); got != "CD081 src/a.go, CD080 make lint" {
+		t.Errorf("new: %s", got)
+	}
+	if got := findingCodes(fixed); got != "CD081 src/b.go" {
+		t.Errorf("fixed: %s", got)
+	}
+	if got := findingCodes(unchanged); got != "CD001, CD081 SRC/A.GO" {
+		t.Errorf("unchanged: %s", got)
+	}
+}
+
+func TestCheckDiff(t *testing.T) {
+	scores := func(score, style int) *fileAnalysis {
+		return &fileAnalysis{Score: score, DimensionScores: &rules.DimensionScores{
+			Scores: map[rules.Dimension]*rules.DimensionScoreResult{rules.DimensionStyle: {Score: style}},
+		}}
+	}
+	report := &diffReport{Files: []*fileDiff{
+		{
+			Path: "CLAUDE.md",
+			Base: scores(90, 100),
+			Head: scores(84, 80),
+			New:  []diffFinding{sampleFinding("CD081", "src/a.go"), {Rule: rules.Rule{Code: "CD020", Severity: rules.SeverityInfo}}},
+		},
+		{Path: "api/CLAUDE.md", Head: scores(40, 40)}, // new file: no regression
+	}}
+
+	defer func(prev int) { regressionThreshold = prev }(regressionThreshold)
+	regressionThreshold = 10
+
+	failures := gateOptions{FailOn: rules.SeverityWarning}.checkDiff(report)
+	want := []string{
+		"CLAUDE.md: 1 new finding(s) at or above warning",
+		"CLAUDE.md: style score dropped 20 points (100 → 80)",
+	}
+	if strings.Join(failures, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got:\n%s\nwant:\n%s", strings.Join(failures, "\n"), strings.Join(want, "\n"))
+	}
+
+	regressionThreshold = 0
+	failures = gateOptions{MinScore: 50}.checkDiff(report)
+	if len(failures) != 1 || failures[0] != "api/CLAUDE.md: score 40 is below minimum 50" {
+		t.Errorf("expected -min-score to check the working tree, got %v", failures)
+	}
+}
+
+func TestDiffFile(t *testing.T) {
+	root, git := gitRepo(t)
+
+	defer func(prev float64) { similarityThreshold = prev }(similarityThreshold)
+	similarityThreshold = rules.DefaultSimilarityThreshold
+
+	writeFiles(t, root, map[string]string{
+		"src/app.go":    "package src\n",
+		"docs/guide.md": "# Guide\n\n- Indent with 4 spaces\n",
+		"CLAUDE.md":     "# Project\n\n- Code lives in `src/app.go`\n\nSee docs/guide.md for details.\n",
+	})
+	git("add", ".")
+	git("commit", "-qm", "initial")
+
+	// The referenced doc is fixed and the context file gains a dead path,
+	// without committing either
+	writeFiles(t, root, map[string]string{
+		"docs/guide.md": "# Guide\n\n- Keep handlers small and focused\n",
+		"CLAUDE.md":     "# Project\n\n- Code lives in `src/app.go`\n- Always update `src/gone.go` first\n\nSee docs/guide.md for details.\n",
+	})
+
+	base, err := rules.NewGitSource(root, "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer base.Close()
+
+	fd, err := diffFile(root, base, filepath.Join(root, "CLAUDE.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fd.Base == nil || fd.Path != "CLAUDE.md" {
+		t.Fatalf("expected the base revision to be analysed, got %+v", fd)
+	}
+	if len(fd.New) != 1 || fd.New[0].Rule.Code != "CD081" || fd.New[0].Span.Text != "src/gone.go" {
+		t.Errorf("expected the dead path as the only new finding, got %s", findingCodes(fd.New))
+	}
+	if len(fd.Fixed) != 1 || fd.Fixed[0].File != "docs/guide.md" || fd.Fixed[0].Rule.Code != "CD010" {
+		t.Errorf("expected the referenced doc's dead path as fixed, got %s", findingCodes(fd.Fixed))
+	}
+	var added []string
+	for _, in := range fd.AddedInstructions {
+		added = append(added, in.File+":"+in.Span.Text)
+	}
+	want := "CLAUDE.md:- Always update `src/gone.go` first, docs/guide.md:- Keep handlers small and focused"
+	if strings.Join(added, ", ") != want {
+		t.Errorf("added instructions: %s", strings.Join(added, ", "))
+	}
+
+	// A file missing at the base revision has only new findings
+	writeFiles(t, root, map[string]string{"api/CLAUDE.md": "# API\n\n- Handlers are in `api/gone.go`\n"})
+	fd, err = diffFile(root, base, filepath.Join(root, "api", "CLAUDE.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fd.Base != nil || len(fd.Fixed) != 0 || len(fd.Unchanged) != 0 || len(fd.New) == 0 {
+		t.Errorf("expected a new file with only new findings, got %+v", fd)
+	}
+}
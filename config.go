@@ -0,0 +1,185 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"context-doctor/rules"
+)
+
+// envPrefix prefixes environment variables that set CLI options,
+// e.g. CONTEXT_DOCTOR_STALE_THRESHOLD=60 for -stale-threshold
+const envPrefix = "CONTEXT_DOCTOR_"
+
+// activeConfig is the loaded project config (nil when there is none)
+var activeConfig *rules.Config
+
+// pathOptions are config options holding paths, resolved against the project
+// root rather than the working directory
+var pathOptions = map[string]bool{
+	"rules-dir":       true,
+	"baseline":        true,
+	"tokenizer-vocab": true,
+}
+
+// ignoredOptions can't be set from the environment or a config file
+var ignoredOptions = map[string]bool{
+	"config":  true,
+	"version": true,
+}
+
+// setupOptions applies environment variables and the project config to every
+// flag not given on the command line. Precedence: flags > environment > config > defaults.
+func setupOptions(fs *flag.FlagSet, target string) error {
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+
+	fromEnv, err := applyEnvOptions(fs, explicit, os.LookupEnv)
+	if err != nil {
+		return err
+	}
+
+	path, err := resolveConfigPath(target)
+	if err != nil || path == "" {
+		return err
+	}
+
+	cfg, err := rules.LoadConfig(path)
+	if err != nil {
+		return err
+	}
+	activeConfig = cfg
+
+	for name := range fromEnv {
+		explicit[name] = true
+	}
+	return applyConfigOptions(fs, cfg.Options, explicit, projectRoot(path))
+}
+
+// applyEnvOptions sets flags from CONTEXT_DOCTOR_* variables and returns the names it set
+func applyEnvOptions(fs *flag.FlagSet, explicit map[string]bool, lookup func(string) (string, bool)) (map[string]bool, error) {
+	set := make(map[string]bool)
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		if err != nil || explicit[f.Name] || f.Name == "version" {
+			return
+		}
+		key := envPrefix + strings.ToUpper(strings.ReplaceAll(f.Name, "-", "_"))
+		value, ok := lookup(key)
+		if !ok {
+			return
+		}
+		if setErr := fs.Set(f.Name, value); setErr != nil {
+			err = fmt.Errorf("invalid %s: %w", key, setErr)
+			return
+		}
+		set[f.Name] = true
+	})
+	return set, err
+}
+
+// applyConfigOptions sets flags from the config's options block
+func applyConfigOptions(fs *flag.FlagSet, options map[string]any, explicit map[string]bool, root string) error {
+	names := make([]string, 0, len(options))
+	for name := range options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if fs.Lookup(name) == nil || ignoredOptions[name] {
+			return fmt.Errorf("config: unknown option %q", name)
+		}
+		if explicit[name] {
+			continue
+		}
+
+		value := optionString(options[name])
+		if pathOptions[name] && value != "" && !filepath.IsAbs(value) {
+			value = filepath.Join(root, value)
+		}
+		if err := fs.Set(name, value); err != nil {
+			return fmt.Errorf("config: invalid option %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// optionString renders a YAML value as flag text; lists become comma-separated
+func optionString(v any) string {
+	switch val := v.(type) {
+	case []any:
+		parts := make([]string, len(val))
+		for i, p := range val {
+			parts[i] = fmt.Sprint(p)
+		}
+		return strings.Join(parts, ",")
+	case map[string]any:
+		// e.g. min-dimension: {correctness: 90}
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		parts := make([]string, len(keys))
+		for i, k := range keys {
+			parts[i] = fmt.Sprintf("%s=%v", k, val[k])
+		}
+		return strings.Join(parts, ",")
+	default:
+		return fmt.Sprint(val)
+	}
+}
+
+// resolveConfigPath returns -config, or the nearest .context-doctor/config.yaml
+// from the target's directory up to the repository root ("" when there is none)
+func resolveConfigPath(target string) (string, error) {
+	if configPath != "" {
+		if _, err := os.Stat(configPath); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return "", fmt.Errorf("config %s not found", configPath)
+			}
+			return "", err
+		}
+		return configPath, nil
+	}
+
+	dir := target
+	if info, err := os.Stat(target); err != nil || !info.IsDir() {
+		dir = filepath.Dir(target)
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", nil
+	}
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = resolved
+	}
+	root := rules.GetGitRoot(dir)
+
+	for {
+		candidate := filepath.Join(dir, ".context-doctor", rules.ConfigFileName)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, nil
+		}
+		parent := filepath.Dir(dir)
+		if root == "" || dir == root || parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// projectRoot is the directory containing the config's .context-doctor/ directory
+func projectRoot(configFile string) string {
+	dir := filepath.Dir(configFile)
+	if filepath.Base(dir) == ".context-doctor" {
+		return filepath.Dir(dir)
+	}
+	return dir
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func newTestFlagSet() (*flag.FlagSet, *int, *string, *string) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	stale := fs.Int("stale-threshold", 90, "")
+	failOn := fs.String("fail-on", "", "")
+	rulesDir := fs.String("rules-dir", "", "")
+	return fs, stale, failOn, rulesDir
+}
+
+func TestOptionPrecedence(t *testing.T) {
+	fs, stale, failOn, rulesDir := newTestFlagSet()
+	if err := fs.Parse([]string{"-fail-on", "error"}); err != nil {
+		t.Fatal(err)
+	}
+	explicit := map[string]bool{"fail-on": true}
+
+	env := map[string]string{
+		"CONTEXT_DOCTOR_FAIL_ON":         "info",
+		"CONTEXT_DOCTOR_STALE_THRESHOLD": "30",
+	}
+	fromEnv, err := applyEnvOptions(fs, explicit, func(k string) (string, bool) {
+		v, ok := env[k]
+		return v, ok
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name := range fromEnv {
+		explicit[name] = true
+	}
+
+	options := map[string]any{
+		"fail-on":         "warning",
+		"stale-threshold": 60,
+		"rules-dir":       "rules",
+	}
+	if err := applyConfigOptions(fs, options, explicit, "/project"); err != nil {
+		t.Fatal(err)
+	}
+
+	if *failOn != "error" {
+		t.Errorf("flag should win over env and config, got %q", *failOn)
+	}
+	if *stale != 30 {
+		t.Errorf("env should win over config, got %d", *stale)
+	}
+	if *rulesDir != filepath.Join("/project", "rules") {
+		t.Errorf("config path option should resolve against the project root, got %q", *rulesDir)
+	}
+}
+
+func TestApplyConfigOptions_Errors(t *testing.T) {
+	fs, _, _, _ := newTestFlagSet()
+	if err := applyConfigOptions(fs, map[string]any{"no-such-flag": true}, nil, ""); err == nil {
+		t.Error("expected error for unknown option")
+	}
+	if err := applyConfigOptions(fs, map[string]any{"stale-threshold": "soon"}, nil, ""); err == nil {
+		t.Error("expected error for invalid value")
+	}
+}
+
+func TestOptionString(t *testing.T) {
+	tests := []struct {
+		in   any
+		want string
+	}{
+		{"warning", "warning"},
+		{60, "60"},
+		{true, "true"},
+		{[]any{"error", "warning"}, "error,warning"},
+		{map[string]any{"style": 70, "correctness": 90}, "correctness=90,style=70"},
+	}
+	for _, tc := range tests {
+		if got := optionString(tc.in); got != tc.want {
+			t.Errorf("optionString(%v) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestResolveConfigPath_WalksUpToRepoRoot(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	root, _ := filepath.EvalSymlinks(t.TempDir())
+	if err := exec.Command("git", "init", "-q", root).Run(); err != nil {
+		t.Skip("git init failed")
+	}
+	sub := filepath.Join(root, "services", "api")
+	cfgDir := filepath.Join(root, ".context-doctor")
+	for _, d := range []string{sub, cfgDir} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := filepath.Join(cfgDir, "config.yaml")
+	if err := os.WriteFile(want, []byte("version: \"1\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	saved := configPath
+	defer func() { configPath = saved }()
+	configPath = ""
+
+	got, err := resolveConfigPath(filepath.Join(sub, "CLAUDE.md"))
+	if err != nil || got != want {
+		t.Errorf("got %q, %v; want %q", got, err, want)
+	}
+	if projectRoot(got) != root {
+		t.Errorf("projectRoot = %q, want %q", projectRoot(got), root)
+	}
+
+	configPath = filepath.Join(root, "missing.yaml")
+	if _, err := resolveConfigPath(sub); err == nil {
+		t.Error("expected error for a missing -config file")
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff_Equal(t *testing.T) {
+	if got := unifiedDiff("a/x", "b/x", "same\n", "same\n"); got != "" {
+		t.Errorf("expected no diff, got %q", got)
+	}
+}
+
+func TestUnifiedDiff_SingleHunk(t *testing.T) {
+	before := "# Project\n\n- Run make test\n- Use tabs\n- Keep PRs small\n"
+	after := "# Project\n\n- Run make test\n- Keep PRs small\n"
+
+	want := strings.Join([]string{
+		"--- a/CLAUDE.md",
+		"+++ b/CLAUDE.md",
+		"@@ -1,5 +1,4 @@",
+		" # Project",
+		" ",
+		" - Run make test",
+		"-- Use tabs",
+		" - Keep PRs small",
+		"",
+	}, "\n")
+	if got := unifiedDiff("a/CLAUDE.md", "b/CLAUDE.md", before, after); got != want {
+		t.Errorf("unexpected diff:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestUnifiedDiff_SeparateHunks(t *testing.T) {
+	var lines []string
+	for i := 1; i <= 20; i++ {
+		lines = append(lines, "line "+string(rune('a'+i-1)))
+	}
+	before := strings.Join(lines, "\n") + "\n"
+	changed := append([]string(nil), lines...)
+	changed[1] = "line B"
+	changed[18] = "line S"
+	after := strings.Join(changed, "\n") + "\n"
+
+	got := unifiedDiff("a/f", "b/f", before, after)
+	if n := strings.Count(got, "@@ -"); n != 2 {
+		t.Fatalf("expected 2 hunks, got %d:\n%s", n, got)
+	}
+	if !strings.Contains(got, "@@ -1,5 +1,5 @@") || !strings.Contains(got, "@@ -16,5 +16,5 @@") {
+		t.Errorf("unexpected hunk headers:\n%s", got)
+	}
+}
+
+func TestUnifiedDiff_EmptyBefore(t *testing.T) {
+	got := unifiedDiff("a/f", "b/f", "", "new\n")
+	if !strings.Contains(got, "@@ -0,0 +1 @@\n+new\n") {
+		t.Errorf("unexpected diff:\n%s", got)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"context-doctor/rules"
+)
+
+// cmdEffective is the subcommand that shows what an agent loads in a directory
+const cmdEffective = "effective"
+
+var agentName string
+
+// runEffective prints the context an agent started in dir would load
+func runEffective(dir string) int {
+	flavor, err := rules.LookupAgent(agentName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitError
+	}
+
+	home, _ := os.UserHomeDir()
+	ec, err := rules.LoadEffectiveContext(dir, flavor, home, similarityThreshold)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitError
+	}
+
+	switch outputFormat {
+	case formatJSON:
+		writeJSON(os.Stdout, newJSONEffectiveReport(ec, home))
+	case formatSARIF:
+		fmt.Fprintln(os.Stderr, "Error: the effective command has no SARIF output")
+		return exitError
+	default:
+		printEffectiveContext(ec, home)
+	}
+
+	if len(ec.Files) == 0 {
+		return exitError
+	}
+	return exitOK
+}
+
+func printEffectiveContext(ec *rules.EffectiveContext, home string) {
+	fmt.Println("=" + strings.Repeat("=", 59))
+	fmt.Println("  Effective Context Report")
+	fmt.Println("=" + strings.Repeat("=", 59))
+	fmt.Println()
+
+	fmt.Printf("Directory: %s\n", ec.Dir)
+	fmt.Printf("Agent:     %s\n\n", ec.Agent)
+
+	if len(ec.Files) == 0 {
+		fmt.Println("  No context files are loaded in this directory.")
+		fmt.Println()
+		return
+	}
+
+	fmt.Println("LOAD ORDER")
+	fmt.Println(strings.Repeat("-", 40))
+	for i, f := range ec.Files {
+		indent := ""
+		if f.Source == rules.SourceImport {
+			indent = "  "
+		}
+		fmt.Printf("  %2d. %s%s (%s)\n", i+1, indent, effectivePath(ec.Dir, home, f.Path), f.Source)
+		fmt.Printf("      %s%d lines, ~%d instructions, %d tokens\n",
+			indent, f.Context.LineCount, f.Context.InstructionCount, f.Context.TokenCount)
+	}
+	fmt.Println()
+
+	agg := ec.Aggregate
+	fmt.Println("TOTAL")
+	fmt.Println(strings.Repeat("-", 40))
+	fmt.Printf("  Files:        %d\n", agg.FileCount)
+	fmt.Printf("  Lines:        %d\n", agg.TotalLineCount)
+	fmt.Printf("  Instructions: ~%d\n", agg.TotalInstructionCount)
+	fmt.Printf("  Tokens:       %d (%s)\n", agg.TotalTokenCount, rules.ActiveTokenizer().Name())
+	if len(agg.Duplicates) > 0 {
+		fmt.Printf("  Duplicated:   %d instruction(s) appear in more than one loaded file\n", len(agg.Duplicates))
+	}
+	if len(agg.Conflicts) > 0 {
+		fmt.Printf("  Conflicts:    %d pair(s) of loaded instructions contradict each other\n", len(agg.Conflicts))
+	}
+	fmt.Println()
+}
+
+// effectivePath shows a loaded file relative to the working directory, or to
+// the home directory for user-level files
+func effectivePath(dir, home, path string) string {
+	if rel, err := filepath.Rel(dir, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	if home != "" {
+		if rel, err := filepath.Rel(home, path); err == nil && !strings.HasPrefix(rel, "..") {
+			return filepath.Join("~", rel)
+		}
+	}
+	return relPath(dir, path)
+}
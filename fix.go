@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"context-doctor/rules"
+)
+
+// cmdFix is the subcommand that applies rule fixes
+const cmdFix = "fix"
+
+var dryRun bool
+
+// runFix applies the fixes of every detected rule to the context files at
+// target and the docs they reference. With -dry-run the changes are printed
+// as a unified diff instead of written.
+func runFix(target string, isDir bool) int {
+	files := []string{target}
+	if isDir {
+		files = findContextFiles(target)
+		if len(files) == 0 {
+			fmt.Fprintf(os.Stderr, "No context files found in %s\n", target)
+			return exitError
+		}
+	}
+
+	// The diff goes to stdout, so a dry run reports on stderr
+	var summary io.Writer = os.Stdout
+	if dryRun {
+		summary = os.Stderr
+	}
+
+	results, err := collectFixes(files)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitError
+	}
+
+	changed := 0
+	for _, res := range results {
+		if !res.Changed() {
+			continue
+		}
+		changed++
+		label := displayPath(res.Path)
+		if dryRun {
+			name := strings.TrimPrefix(label, "/")
+			fmt.Print(unifiedDiff("a/"+name, "b/"+name, res.Original, res.Fixed))
+		} else if err := writeFixed(res); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return exitError
+		}
+		printFixSummary(summary, label, res)
+	}
+
+	if changed == 0 {
+		fmt.Fprintln(summary, "Nothing to fix")
+	}
+	return exitOK
+}
+
+// collectFixes fixes each context file and its referenced docs in memory.
+// A doc referenced from several context files is fixed once.
+func collectFixes(files []string) ([]*rules.FixResult, error) {
+	var results []*rules.FixResult
+	seen := make(map[string]bool)
+
+	for _, file := range files {
+		fa, err := buildAnalysis(file)
+		if err != nil {
+			return nil, err
+		}
+
+		res, err := fa.Engine.Fix(fa.Ctx, false)
+		if err != nil {
+			return nil, err
+		}
+		seen[absPath(file)] = true
+		results = append(results, res)
+
+		for _, ref := range rules.FlattenRefs(fa.Refs) {
+			path := absPath(ref.ResolvedPath)
+			if !ref.Exists || ref.Context == nil || seen[path] {
+				continue
+			}
+			seen[path] = true
+			res, err := fa.Engine.Fix(ref.Context, true)
+			if err != nil {
+				return nil, err
+			}
+			results = append(results, res)
+		}
+	}
+	return results, nil
+}
+
+// writeFixed writes a fixed file back, keeping its permissions
+func writeFixed(res *rules.FixResult) error {
+	info, err := os.Stat(res.Path)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(res.Path, []byte(res.Fixed), info.Mode().Perm())
+}
+
+func printFixSummary(w io.Writer, label string, res *rules.FixResult) {
+	verb := "Fixed"
+	if dryRun {
+		verb = "Would fix"
+	}
+	fmt.Fprintf(w, "%s %s (%d changes)\n", verb, label, len(res.Applied))
+	for _, a := range res.Applied {
+		fmt.Fprintf(w, "  %s line %d: %s\n", a.Code, a.Line, a.Action)
+	}
+	for _, code := range res.Remaining {
+		fmt.Fprintf(w, "  %s still fails after fixing, review it by hand\n", code)
+	}
+}
+
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+// displayPath shows path relative to the working directory when it is below it
+func displayPath(path string) string {
+	if wd, err := os.Getwd(); err == nil {
+		if rel, err := filepath.Rel(wd, absPath(path)); err == nil && !strings.HasPrefix(rel, "..") {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return filepath.ToSlash(filepath.Clean(path))
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"context-doctor/rules"
+)
+
+// Exit codes. Findings over a configured threshold and tool failures are kept
+// separate so CI can tell "the context file needs work" from "the run broke".
+const (
+	exitOK       = 0
+	exitFindings = 1 // a -fail-on, -min-score, -min-dimension or diff -regression gate failed
+	exitError    = 2 // bad arguments, unreadable input or no context files found
+)
+
+// gateOptions holds the CI failure thresholds
+type gateOptions struct {
+	FailOn       rules.Severity          // fail when a finding has this severity or higher ("" = never)
+	MinScore     int                     // fail when the overall score is below this (0 = disabled)
+	MinDimension map[rules.Dimension]int // fail when a dimension score is below its threshold
+}
+
+// severityRank orders severities so thresholds can be compared
+var severityRank = map[rules.Severity]int{
+	rules.SeverityInfo:    1,
+	rules.SeverityWarning: 2,
+	rules.SeverityError:   3,
+}
+
+func buildGateOptions() (gateOptions, error) {
+	opts := gateOptions{MinScore: minScore}
+
+	switch failOnFlag {
+	case "", "none":
+	default:
+		sev := rules.Severity(failOnFlag)
+		if _, ok := severityRank[sev]; !ok {
+			return opts, fmt.Errorf("invalid -fail-on %q (expected error, warning, info or none)", failOnFlag)
+		}
+		opts.FailOn = sev
+	}
+
+	if minScore < 0 || minScore > 100 {
+		return opts, fmt.Errorf("invalid -min-score %d (expected 0-100)", minScore)
+	}
+
+	dims, err := parseDimensionThresholds(minDimensionFlag)
+	if err != nil {
+		return opts, err
+	}
+	opts.MinDimension = dims
+
+	return opts, nil
+}
+
+// parseDimensionThresholds parses "correctness=90,style=70" into per-dimension minimums
+func parseDimensionThresholds(s string) (map[rules.Dimension]int, error) {
+	thresholds := make(map[rules.Dimension]int)
+	if strings.TrimSpace(s) == "" {
+		return thresholds, nil
+	}
+
+	known := make(map[rules.Dimension]bool)
+	for _, d := range rules.AllDimensions() {
+		known[d] = true
+	}
+
+	for _, part := range strings.Split(s, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid -min-dimension entry %q (expected name=score)", part)
+		}
+		dim := rules.Dimension(strings.ToLower(strings.TrimSpace(name)))
+		if !known[dim] {
+			return nil, fmt.Errorf("unknown dimension %q in -min-dimension", name)
+		}
+		score, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || score < 0 || score > 100 {
+			return nil, fmt.Errorf("invalid score %q for dimension %s (expected 0-100)", value, dim)
+		}
+		thresholds[dim] = score
+	}
+	return thresholds, nil
+}
+
+// checkFile returns a description of every gate the analysis fails
+func (g gateOptions) checkFile(fa *fileAnalysis, label string) []string {
+	var failures []string
+
+	if g.FailOn != "" {
+		if n := countFindingsAtOrAbove(fa, g.FailOn); n > 0 {
+			failures = append(failures, fmt.Sprintf("%s: %d finding(s) at or above %s", label, n, g.FailOn))
+		}
+	}
+
+	if g.MinScore > 0 && fa.Score < g.MinScore {
+		failures = append(failures, fmt.Sprintf("%s: score %d is below minimum %d", label, fa.Score, g.MinScore))
+	}
+
+	if fa.DimensionScores != nil {
+		for _, dim := range sortedDimensions(g.MinDimension) {
+			entry := fa.DimensionScores.Scores[dim]
+			if entry != nil && entry.Score < g.MinDimension[dim] {
+				failures = append(failures, fmt.Sprintf("%s: %s score %d is below minimum %d",
+					label, dim, entry.Score, g.MinDimension[dim]))
+			}
+		}
+	}
+
+	return failures
+}
+
+// checkRepo applies the per-file gates to every analysed file, then the
+// severity and score gates to repo-level findings and the average score
+func (g gateOptions) checkRepo(ra *repoAnalysis) []string {
+	var failures []string
+
+	// The repo average replaces per-file scores for -min-score
+	perFile := g
+	perFile.MinScore = 0
+	for _, fa := range ra.Analyses {
+		failures = append(failures, perFile.checkFile(fa, relPath(ra.Dir, fa.FilePath))...)
+	}
+
+	if g.FailOn != "" {
+		for _, f := range ra.Findings {
+			if severityRank[f.Severity] >= severityRank[g.FailOn] {
+				failures = append(failures, fmt.Sprintf("repo: [%s] %s", f.Code, f.Message))
+			}
+		}
+	}
+
+	if g.MinScore > 0 && ra.AvgScore < g.MinScore {
+		failures = append(failures, fmt.Sprintf("repo: average score %d is below minimum %d", ra.AvgScore, g.MinScore))
+	}
+
+	return failures
+}
+
+// reportGateFailures prints the failed gates and returns the exit code for them
+func reportGateFailures(failures []string) int {
+	if len(failures) == 0 {
+		return exitOK
+	}
+	fmt.Fprintln(os.Stderr, "Quality gate failed:")
+	for _, f := range failures {
+		fmt.Fprintf(os.Stderr, "  - %s\n", f)
+	}
+	return exitFindings
+}
+
+// countFindingsAtOrAbove counts detected problems, in the file and its referenced docs,
+// whose severity is at least the given level
+func countFindingsAtOrAbove(fa *fileAnalysis, level rules.Severity) int {
+	count := 0
+	check := func(results []rules.RuleResult) {
+		for _, r := range results {
+			if r.Rule.Category == "good-practice" || !r.Passed {
+				continue
+			}
+			if severityRank[r.Rule.Severity] >= severityRank[level] {
+				count++
+			}
+		}
+	}
+
+	check(fa.Results)
+	for _, results := range fa.RefResults {
+		check(results)
+	}
+	return count
+}
+
+func sortedDimensions(m map[rules.Dimension]int) []rules.Dimension {
+	dims := make([]rules.Dimension, 0, len(m))
+	for d := range m {
+		dims = append(dims, d)
+	}
+	sort.Slice(dims, func(i, j int) bool { return dims[i] < dims[j] })
+	return dims
+}
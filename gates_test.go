@@ -0,0 +1,116 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"context-doctor/rules"
+)
+
+func TestParseDimensionThresholds(t *testing.T) {
+	t.Run("empty", func(t *testing.T) {
+		got, err := parseDimensionThresholds("")
+		if err != nil || len(got) != 0 {
+			t.Errorf("got %v, %v", got, err)
+		}
+	})
+
+	t.Run("multiple entries", func(t *testing.T) {
+		got, err := parseDimensionThresholds("correctness=90, Style=70")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got[rules.DimensionCorrectness] != 90 || got[rules.DimensionStyle] != 70 {
+			t.Errorf("got %v", got)
+		}
+	})
+
+	for _, bad := range []string{"correctness", "speed=10", "style=abc", "style=101"} {
+		t.Run("rejects "+bad, func(t *testing.T) {
+			if _, err := parseDimensionThresholds(bad); err == nil {
+				t.Errorf("expected error for %q", bad)
+			}
+		})
+	}
+}
+
+func TestBuildGateOptions(t *testing.T) {
+	saveFailOn, saveMin, saveDim := failOnFlag, minScore, minDimensionFlag
+	defer func() { failOnFlag, minScore, minDimensionFlag = saveFailOn, saveMin, saveDim }()
+
+	failOnFlag, minScore, minDimensionFlag = "warning", 80, "freshness=50"
+	opts, err := buildGateOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.FailOn != rules.SeverityWarning || opts.MinScore != 80 || opts.MinDimension[rules.DimensionFreshness] != 50 {
+		t.Errorf("unexpected options: %+v", opts)
+	}
+
+	failOnFlag = "none"
+	if opts, _ := buildGateOptions(); opts.FailOn != "" {
+		t.Error("expected none to disable -fail-on")
+	}
+
+	failOnFlag = "fatal"
+	if _, err := buildGateOptions(); err == nil {
+		t.Error("expected error for unknown severity")
+	}
+}
+
+func TestGateOptions_CheckFile(t *testing.T) {
+	// sampleAnalysis has one warning (CD011), score 95 and style 95
+	tests := []struct {
+		name  string
+		gates gateOptions
+		want  int
+	}{
+		{"no gates", gateOptions{}, 0},
+		{"fail-on error passes with only warnings", gateOptions{FailOn: rules.SeverityError}, 0},
+		{"fail-on warning fails", gateOptions{FailOn: rules.SeverityWarning}, 1},
+		{"fail-on info includes warnings", gateOptions{FailOn: rules.SeverityInfo}, 1},
+		{"min-score met", gateOptions{MinScore: 95}, 0},
+		{"min-score missed", gateOptions{MinScore: 96}, 1},
+		{"min-dimension missed", gateOptions{MinDimension: map[rules.Dimension]int{rules.DimensionStyle: 100}}, 1},
+		{"min-dimension met", gateOptions{MinDimension: map[rules.Dimension]int{rules.DimensionCorrectness: 100}}, 0},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := tc.gates.checkFile(sampleAnalysis("CLAUDE.md"), "CLAUDE.md")
+			if len(got) != tc.want {
+				t.Errorf("expected %d failures, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestGateOptions_CheckFile_CountsReferencedDocs(t *testing.T) {
+	fa := sampleAnalysis("CLAUDE.md")
+	fa.RefResults = map[string][]rules.RuleResult{
+		"docs/a.md": {{Rule: rules.Rule{Code: "CD010", Severity: rules.SeverityError}, Passed: true}},
+	}
+
+	got := gateOptions{FailOn: rules.SeverityError}.checkFile(fa, "CLAUDE.md")
+	if len(got) != 1 || !strings.Contains(got[0], "1 finding(s) at or above error") {
+		t.Errorf("unexpected failures: %v", got)
+	}
+}
+
+func TestGateOptions_CheckRepo(t *testing.T) {
+	ra := &repoAnalysis{
+		Dir:      "/repo",
+		Analyses: []*fileAnalysis{sampleAnalysis("/repo/CLAUDE.md")},
+		Findings: []repoFinding{{Code: "CD061", Severity: rules.SeverityError, Message: "pkg/CLAUDE.md contradicts 1 instruction(s) of CLAUDE.md"}},
+		AvgScore: 65,
+	}
+
+	got := gateOptions{MinScore: 90}.checkRepo(ra)
+	if len(got) != 1 || !strings.Contains(got[0], "average score 65") {
+		t.Errorf("expected only the repo average to be checked, got %v", got)
+	}
+
+	got = gateOptions{FailOn: rules.SeverityError}.checkRepo(ra)
+	if len(got) != 1 || !strings.Contains(got[0], "CD061") {
+		t.Errorf("expected repo-level finding to fail the gate, got %v", got)
+	}
+}
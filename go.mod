@@ -2,4 +2,4 @@ module context-doctor
 
 go 1.25.4
 
-require gopkg.in/yaml.v3 v3.0.1 // indirect
+require gopkg.in/yaml.v3 v3.0.1
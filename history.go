@@ -0,0 +1,217 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"context-doctor/rules"
+)
+
+// cmdHistory is the subcommand that scores every revision of a context file
+const cmdHistory = "history"
+
+// formatCSV is the history command's spreadsheet output
+const formatCSV = "csv"
+
+var regressionThreshold int
+
+// historyEntry is the analysis of one revision of a context file
+type historyEntry struct {
+	Revision     rules.Revision
+	Lines        int
+	Instructions int
+	Score        int
+	Dimensions   map[rules.Dimension]int
+	Regressions  []string // what dropped since the previous revision, e.g. "score -14"
+}
+
+// runHistory analyses every committed revision of the context file at target,
+// read from git objects, and prints the scores as a table or CSV with large
+// drops flagged
+func runHistory(target string) int {
+	root := rules.GetGitRoot(filepath.Dir(absPath(target)))
+	if root == "" {
+		fmt.Fprintf(os.Stderr, "Error: %s is not in a git repository\n", target)
+		return exitError
+	}
+	rel, ok := rules.RepoPath(root, target)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Error: %s is outside the repository at %s\n", target, root)
+		return exitError
+	}
+	if outputFormat != formatText && outputFormat != formatCSV {
+		fmt.Fprintf(os.Stderr, "Error: the history command supports text and csv output, not %s\n", outputFormat)
+		return exitError
+	}
+
+	revs, err := rules.FileRevisions(root, rel)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitError
+	}
+	if len(revs) == 0 {
+		fmt.Fprintf(os.Stderr, "No commits change %s\n", rel)
+		return exitError
+	}
+
+	var entries []historyEntry
+	for _, rev := range revs {
+		fa, err := analyzeRevision(root, rev.Commit, rev.Path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: skipping %s: %v\n", shortCommit(rev.Commit), err)
+			continue
+		}
+		entries = append(entries, newHistoryEntry(rev, fa))
+	}
+	flagRegressions(entries, regressionThreshold)
+
+	if outputFormat == formatCSV {
+		if err := writeHistoryCSV(os.Stdout, entries); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return exitError
+		}
+	} else {
+		printHistory(rel, entries)
+	}
+	return exitOK
+}
+
+// analyzeRevision runs the full analysis on the file at rel as of commit,
+// reading it, its references and the repo files from git objects
+func analyzeRevision(root, commit, rel string) (*fileAnalysis, error) {
+	src, err := rules.NewGitSource(root, commit)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+	return analyzeFrom(src, filepath.Join(root, filepath.FromSlash(rel)))
+}
+
+// analyzeFrom runs the full analysis on the file at path reading from src
+func analyzeFrom(src rules.Source, path string) (*fileAnalysis, error) {
+	prev := rules.ActiveSource()
+	rules.SetSource(src)
+	defer rules.SetSource(prev)
+	return buildAnalysis(path)
+}
+
+func newHistoryEntry(rev rules.Revision, fa *fileAnalysis) historyEntry {
+	e := historyEntry{
+		Revision:     rev,
+		Lines:        fa.Ctx.LineCount,
+		Instructions: fa.Ctx.InstructionCount,
+		Score:        fa.Score,
+		Dimensions:   map[rules.Dimension]int{},
+	}
+	if fa.DimensionScores != nil {
+		for dim, entry := range fa.DimensionScores.Scores {
+			e.Dimensions[dim] = entry.Score
+		}
+	}
+	return e
+}
+
+// flagRegressions marks the revisions where the score or a dimension score
+// dropped by at least threshold points from the previous revision
+func flagRegressions(entries []historyEntry, threshold int) {
+	if threshold <= 0 {
+		return
+	}
+	for i := 1; i < len(entries); i++ {
+		prev, cur := entries[i-1], &entries[i]
+		if drop := prev.Score - cur.Score; drop >= threshold {
+			cur.Regressions = append(cur.Regressions, fmt.Sprintf("score -%d", drop))
+		}
+		for _, dim := range rules.AllDimensions() {
+			if drop := prev.Dimensions[dim] - cur.Dimensions[dim]; drop >= threshold {
+				cur.Regressions = append(cur.Regressions, fmt.Sprintf("%s -%d", dim, drop))
+			}
+		}
+	}
+}
+
+func printHistory(rel string, entries []historyEntry) {
+	fmt.Println("=" + strings.Repeat("=", 59))
+	fmt.Println("  Context File History")
+	fmt.Println("=" + strings.Repeat("=", 59))
+	fmt.Println()
+	fmt.Printf("File:      %s\n", rel)
+	fmt.Printf("Revisions: %d\n", len(entries))
+	fmt.Println()
+
+	fmt.Printf("  %-10s  %-7s  %-16s %6s %6s %6s", "DATE", "COMMIT", "AUTHOR", "LINES", "INSTR", "SCORE")
+	for _, dim := range rules.AllDimensions() {
+		fmt.Printf(" %6s", strings.ToUpper(truncateRunes(string(dim), 5)))
+	}
+	fmt.Println()
+
+	regressions := 0
+	for _, e := range entries {
+		fmt.Printf("  %-10s  %-7s  %-16s %6d %6d %6d", e.Revision.Time.Format("2006-01-02"), shortCommit(e.Revision.Commit),
+			truncateRunes(e.Revision.Author, 16), e.Lines, e.Instructions, e.Score)
+		for _, dim := range rules.AllDimensions() {
+			fmt.Printf(" %6d", e.Dimensions[dim])
+		}
+		if len(e.Regressions) > 0 {
+			regressions++
+			fmt.Printf("  ⚠ %s", strings.Join(e.Regressions, ", "))
+		}
+		fmt.Println()
+	}
+	fmt.Println()
+
+	if regressions > 0 {
+		fmt.Printf("  ⚠ %d regression(s): a score dropped by %d or more points from the previous revision\n", regressions, regressionThreshold)
+		fmt.Println()
+	}
+}
+
+func writeHistoryCSV(w io.Writer, entries []historyEntry) error {
+	cw := csv.NewWriter(w)
+	header := []string{"date", "commit", "author", "lines", "instructions", "score"}
+	for _, dim := range rules.AllDimensions() {
+		header = append(header, string(dim))
+	}
+	header = append(header, "regression")
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		row := []string{
+			e.Revision.Time.UTC().Format("2006-01-02T15:04:05Z"),
+			e.Revision.Commit,
+			e.Revision.Author,
+			strconv.Itoa(e.Lines),
+			strconv.Itoa(e.Instructions),
+			strconv.Itoa(e.Score),
+		}
+		for _, dim := range rules.AllDimensions() {
+			row = append(row, strconv.Itoa(e.Dimensions[dim]))
+		}
+		row = append(row, strings.Join(e.Regressions, "; "))
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func shortCommit(commit string) string {
+	return commit[:min(7, len(commit))]
+}
+
+// truncateRunes cuts s to n characters for fixed-width columns
+func truncateRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n])
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"context-doctor/rules"
+)
+
+func historySample(commit string, score, style int) historyEntry {
+	return historyEntry{
+		Revision: rules.Revision{Commit: commit, Time: time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC), Author: "Jane, Doe"},
+		Lines:    40,
+		Score:    score,
+		Dimensions: map[rules.Dimension]int{
+			rules.DimensionCorrectness: 100,
+			rules.DimensionStyle:       style,
+			rules.DimensionCompliance:  100,
+			rules.DimensionFreshness:   100,
+		},
+	}
+}
+
+func TestFlagRegressions(t *testing.T) {
+	entries := []historyEntry{
+		historySample("a", 95, 100),
+		historySample("b", 80, 70), // bloated
+		historySample("c", 75, 65), // small drop
+		historySample("d", 90, 90),
+	}
+	flagRegressions(entries, 10)
+
+	if len(entries[0].Regressions) != 0 || len(entries[2].Regressions) != 0 || len(entries[3].Regressions) != 0 {
+		t.Errorf("expected only the second revision flagged, got %+v", entries)
+	}
+	if got := strings.Join(entries[1].Regressions, ", "); got != "score -15, style -30" {
+		t.Errorf("unexpected regressions %q", got)
+	}
+
+	disabled := []historyEntry{historySample("a", 95, 100), historySample("b", 10, 10)}
+	flagRegressions(disabled, 0)
+	if len(disabled[1].Regressions) != 0 {
+		t.Errorf("expected a zero threshold to disable flagging, got %v", disabled[1].Regressions)
+	}
+}
+
+func TestWriteHistoryCSV(t *testing.T) {
+	entries := []historyEntry{historySample("abc123", 95, 100)}
+	entries[0].Regressions = []string{"score -12"}
+
+	var buf bytes.Buffer
+	if err := writeHistoryCSV(&buf, entries); err != nil {
+		t.Fatal(err)
+	}
+	want := "date,commit,author,lines,instructions,score,correctness,style,compliance,freshness,regression\n" +
+		`2025-03-01T12:00:00Z,abc123,"Jane, Doe",40,0,95,100,100,100,100,score -12` + "\n"
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
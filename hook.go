@@ -0,0 +1,136 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"context-doctor/rules"
+)
+
+// cmdInstallHook is the subcommand that writes a git pre-commit hook
+const cmdInstallHook = "install-hook"
+
+var forceHook bool
+
+// hookMarker marks hooks written by install-hook, which it may replace
+const hookMarker = "# Installed by context-doctor install-hook"
+
+// runInstallHook writes a pre-commit hook for the repository at dir that
+// runs context-doctor -staged on dir with the options install-hook was given.
+// Without a gate option, the hook fails on errors.
+func runInstallHook(dir string) int {
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		fmt.Fprintf(os.Stderr, "Error: install-hook takes a directory, not %s\n", dir)
+		return exitError
+	}
+	root := rules.GetGitRoot(dir)
+	if root == "" {
+		fmt.Fprintf(os.Stderr, "Error: %s is not in a git repository\n", dir)
+		return exitError
+	}
+	hooksDir, err := gitHooksDir(root)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitError
+	}
+
+	path := filepath.Join(hooksDir, "pre-commit")
+	if existing, err := os.ReadFile(path); err == nil && !strings.Contains(string(existing), hookMarker) && !forceHook {
+		fmt.Fprintf(os.Stderr, "Error: %s already exists; pass -force to replace it\n", path)
+		return exitError
+	}
+
+	// Hooks run from the top of the working tree
+	target := "."
+	if rel, ok := rules.RepoPath(root, dir); ok {
+		target = rel
+	}
+	script := hookScript(hookExecutable(), hookArgs(flag.CommandLine), target)
+
+	if err := os.MkdirAll(hooksDir, 0o755); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitError
+	}
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitError
+	}
+	fmt.Printf("Installed pre-commit hook at %s\n", displayPath(path))
+	return exitOK
+}
+
+// gitHooksDir returns the directory git runs hooks from, honouring
+// core.hooksPath and linked worktrees
+func gitHooksDir(root string) (string, error) {
+	cmd := exec.Command("git", "rev-parse", "--git-path", "hooks")
+	cmd.Dir = root
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("locating the hooks directory: %w", err)
+	}
+	dir := strings.TrimSpace(string(output))
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(root, dir)
+	}
+	return dir, nil
+}
+
+// hookExecutable is how the hook calls context-doctor: by name when it is
+// on the PATH, otherwise by the path of the running binary
+func hookExecutable() string {
+	if _, err := exec.LookPath("context-doctor"); err == nil {
+		return "context-doctor"
+	}
+	if exe, err := os.Executable(); err == nil {
+		return exe
+	}
+	return "context-doctor"
+}
+
+// hookArgs returns the options set on the command line as hook arguments,
+// adding -fail-on error when no gate flag was given
+func hookArgs(fs *flag.FlagSet) []string {
+	var args []string
+	gated := false
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "force", "staged":
+			return
+		case "fail-on", "min-score", "min-dimension":
+			gated = true
+		}
+		args = append(args, fmt.Sprintf("-%s=%s", f.Name, f.Value))
+	})
+	if !gated {
+		args = append([]string{"-fail-on=error"}, args...)
+	}
+	return args
+}
+
+func hookScript(exe string, args []string, target string) string {
+	words := []string{shellQuote(exe), "-staged"}
+	for _, a := range args {
+		words = append(words, shellQuote(a))
+	}
+	words = append(words, shellQuote(target))
+
+	return "#!/bin/sh\n" +
+		hookMarker + "\n" +
+		"# Checks the staged version of changed context files and the docs they reference.\n" +
+		"exec " + strings.Join(words, " ") + "\n"
+}
+
+// shellSafe matches words that need no quoting in sh
+var shellSafe = regexp.MustCompile(`^[A-Za-z0-9_./=,:@%+-]+$`)
+
+func shellQuote(s string) string {
+	if shellSafe.MatchString(s) {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"flag"
+	"strings"
+	"testing"
+)
+
+func TestHookArgs(t *testing.T) {
+	newFlags := func(args ...string) *flag.FlagSet {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.String("fail-on", "", "")
+		fs.Int("min-score", 0, "")
+		fs.String("config", "", "")
+		fs.Bool("force", false, "")
+		if err := fs.Parse(args); err != nil {
+			t.Fatal(err)
+		}
+		return fs
+	}
+
+	if got := strings.Join(hookArgs(newFlags("-force", "-config", "ci/config.yaml")), " "); got != "-fail-on=error -config=ci/config.yaml" {
+		t.Errorf("expected the default gate and the config, got %q", got)
+	}
+	if got := strings.Join(hookArgs(newFlags("-min-score", "80")), " "); got != "-min-score=80" {
+		t.Errorf("expected the given gate only, got %q", got)
+	}
+}
+
+func TestHookScript(t *testing.T) {
+	script := hookScript("/opt/my tools/context-doctor", []string{"-fail-on=warning", "-config=it's.yaml"}, "docs")
+	want := "#!/bin/sh\n" +
+		hookMarker + "\n" +
+		"# Checks the staged version of changed context files and the docs they reference.\n" +
+		`exec '/opt/my tools/context-doctor' -staged -fail-on=warning '-config=it'\''s.yaml' docs` + "\n"
+	if script != want {
+		t.Errorf("got:\n%s\nwant:\n%s", script, want)
+	}
+}
@@ -19,14 +19,25 @@ var (
 )
 
 var (
-	customRulesDir  string
-	noBuiltin       bool
-	verbose         bool
-	showScore       bool
-	categoriesFlag  string
-	severitiesFlag  string
-	showVersion     bool
-	staleThreshold  int
+	customRulesDir      string
+	noBuiltin           bool
+	verbose             bool
+	showScore           bool
+	categoriesFlag      string
+	severitiesFlag      string
+	showVersion         bool
+	staleThreshold      int
+	outputFormat        string
+	failOnFlag          string
+	minScore            int
+	minDimensionFlag    string
+	baselinePath        string
+	updateBaseline      bool
+	configPath          string
+	similarityThreshold float64
+	tokenizerName       string
+	tokenizerVocab      string
+	profileName         string
 )
 
 func init() {
@@ -38,46 +49,179 @@ func init() {
 	flag.StringVar(&severitiesFlag, "severities", "", "Filter by severities (comma-separated: error,warning,info)")
 	flag.BoolVar(&showVersion, "version", false, "Show version information")
 	flag.IntVar(&staleThreshold, "stale-threshold", 90, "Days before a referenced doc is considered stale")
+	flag.StringVar(&outputFormat, "format", formatText, "Output format: text, json or sarif")
+	flag.StringVar(&failOnFlag, "fail-on", "", "Exit 1 when a finding has this severity or higher: error, warning, info or none")
+	flag.IntVar(&minScore, "min-score", 0, "Exit 1 when the overall score is below this value (0 disables)")
+	flag.StringVar(&minDimensionFlag, "min-dimension", "", "Exit 1 when a dimension score is below its minimum (e.g. correctness=90,style=70)")
+	flag.StringVar(&baselinePath, "baseline", "", "Baseline file of known findings to hide (e.g. .context-doctor/baseline.json)")
+	flag.BoolVar(&updateBaseline, "update-baseline", false, "Record all current findings in the baseline file instead of filtering them")
+	flag.Float64Var(&similarityThreshold, "similarity-threshold", rules.DefaultSimilarityThreshold, "Word overlap (0-1] at which instructions count as duplicates (1 = same words only)")
+	flag.StringVar(&tokenizerName, "tokenizer", rules.DefaultTokenizer, "Tokenizer for token counts: "+strings.Join(rules.TokenizerNames(), ", "))
+	flag.StringVar(&tokenizerVocab, "tokenizer-vocab", "", "Vocabulary file for the bpe tokenizer (tiktoken format, e.g. cl100k_base.tiktoken)")
+	flag.StringVar(&profileName, "profile", "", "Agent profile for baselines and instruction limits: "+strings.Join(rules.ProfileNames(), ", ")+" (default: from the file type)")
+	flag.StringVar(&configPath, "config", "", "Config file (default: nearest .context-doctor/config.yaml up to the repo root)")
+	flag.BoolVar(&staged, "staged", false, "Analyse the staged version of changed context files and their referenced docs, read from the git index")
 }
 
 func main() {
-	flag.Parse()
+	args := os.Args[1:]
+	command := ""
+	if len(args) > 0 {
+		switch args[0] {
+		case cmdFix:
+			command, args = cmdFix, args[1:]
+			flag.BoolVar(&dryRun, "dry-run", false, "Print the fixes as a unified diff instead of writing them")
+		case cmdEffective:
+			command, args = cmdEffective, args[1:]
+			flag.StringVar(&agentName, "agent", rules.DefaultAgent, "Agent whose loading rules to simulate: "+strings.Join(rules.AgentNames(), ", "))
+		case cmdHistory:
+			command, args = cmdHistory, args[1:]
+			flag.IntVar(&regressionThreshold, "regression", 10, "Score drop (points) from the previous revision flagged as a regression (0 disables)")
+		case cmdInstallHook:
+			command, args = cmdInstallHook, args[1:]
+			flag.BoolVar(&forceHook, "force", false, "Replace an existing pre-commit hook that context-doctor didn't install")
+		case cmdDiff:
+			command, args = cmdDiff, args[1:]
+			flag.StringVar(&diffBase, "base", "origin/main", "Revision to compare the working tree with")
+			flag.IntVar(&regressionThreshold, "regression", 0, "Exit 1 when the score or a dimension score drops by at least this many points (0 disables)")
+		}
+	}
+	flag.CommandLine.Parse(args)
 
 	if showVersion {
 		fmt.Printf("context-doctor %s (built %s)\n", Version, BuildTime)
-		os.Exit(0)
+		os.Exit(exitOK)
 	}
 
-	if flag.NArg() < 1 {
+	if flag.NArg() < 1 && command != cmdInstallHook {
 		fmt.Println("Usage: context-doctor [options] <path-to-context-file | directory>")
+		fmt.Println("       context-doctor fix [-dry-run] [options] <path-to-context-file | directory>")
+		fmt.Println("       context-doctor effective [-agent claude|codex] [options] <directory>")
+		fmt.Println("       context-doctor history [-regression 10] [-format text|csv] [options] <path-to-context-file>")
+		fmt.Println("       context-doctor diff [-base origin/main] [-regression 5] [options] <path-to-context-file | directory>")
+		fmt.Println("       context-doctor install-hook [-force] [options] [directory]")
 		fmt.Println("\nOptions:")
 		flag.PrintDefaults()
-		os.Exit(1)
+		os.Exit(exitError)
 	}
 
 	target := flag.Arg(0)
+	if target == "" {
+		target = "." // install-hook defaults to the current repository
+	}
+
+	if err := setupOptions(flag.CommandLine, target); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	if !isValidFormat(outputFormat) && !(command == cmdHistory && outputFormat == formatCSV) {
+		fmt.Fprintf(os.Stderr, "Error: unknown output format %q\n", outputFormat)
+		os.Exit(exitError)
+	}
+
+	if profileName != "" {
+		if _, err := activeConfig.Profile(profileName); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitError)
+		}
+	}
+
+	if similarityThreshold <= 0 || similarityThreshold > 1 {
+		fmt.Fprintf(os.Stderr, "Error: invalid -similarity-threshold %v (expected a value above 0 and at most 1)\n", similarityThreshold)
+		os.Exit(exitError)
+	}
+
+	gates, err := buildGateOptions()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	if err := setupTokenizer(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	if err := setupBaseline(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	if staged && command != "" {
+		fmt.Fprintf(os.Stderr, "Error: -staged doesn't apply to the %s command\n", command)
+		os.Exit(exitError)
+	}
+	if staged && updateBaseline {
+		fmt.Fprintln(os.Stderr, "Error: -update-baseline can't be combined with -staged")
+		os.Exit(exitError)
+	}
+
+	// The file may be gone from the working tree but not from its history
+	// or the index
+	switch {
+	case command == cmdHistory:
+		os.Exit(runHistory(target))
+	case staged:
+		os.Exit(runStaged(target, gates))
+	}
 
 	// Check if target is a directory
 	info, err := os.Stat(target)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitError)
 	}
 
+	switch command {
+	case cmdFix:
+		os.Exit(runFix(target, info.IsDir()))
+	case cmdEffective:
+		os.Exit(runEffective(target))
+	case cmdDiff:
+		os.Exit(runDiff(target, info.IsDir(), gates))
+	case cmdInstallHook:
+		os.Exit(runInstallHook(target))
+	}
+
+	var analyses []*fileAnalysis
+	var failures []string
 	if info.IsDir() {
 		files := findContextFiles(target)
 		if len(files) == 0 {
-			fmt.Fprintf(os.Stderr, "No context files found (CLAUDE.md, AGENTS.md) in %s\n", target)
+			fmt.Fprintf(os.Stderr, "No context files found in %s\n", target)
 			printTemplateSuggestion(target)
-			os.Exit(1)
+			os.Exit(exitError)
+		}
+		ra := analyzeRepo(target, files)
+		if len(ra.Analyses) == 0 {
+			os.Exit(exitError)
 		}
-		printRepoReport(target, files)
+		analyses = ra.Analyses
+		failures = gates.checkRepo(ra)
 	} else {
-		analyzeFile(target)
+		fa, err := analyzeFile(target)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitError)
+		}
+		analyses = []*fileAnalysis{fa}
+		failures = gates.checkFile(fa, target)
+	}
+
+	// Recording a baseline accepts the current findings, so gates don't apply
+	if updateBaseline {
+		if err := writeBaseline(analyses); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitError)
+		}
+		return
 	}
+
+	os.Exit(reportGateFailures(failures))
 }
 
-// findContextFiles finds all context files (CLAUDE.md, AGENTS.md) in a directory, respecting .gitignore
+// findContextFiles finds all context files of the registered formats in a directory, respecting .gitignore
 func findContextFiles(dir string) []string {
 	// Try git ls-files first — respects .gitignore automatically
 	if files := findContextFilesGit(dir); files != nil {
@@ -89,8 +233,8 @@ func findContextFiles(dir string) []string {
 
 func findContextFilesGit(dir string) []string {
 	// --cached: tracked files, --others: untracked, --exclude-standard: respect .gitignore
-	cmd := exec.Command("git", "ls-files", "--cached", "--others", "--exclude-standard",
-		"CLAUDE.md", "*/CLAUDE.md", "AGENTS.md", "*/AGENTS.md")
+	args := append([]string{"ls-files", "--cached", "--others", "--exclude-standard"}, rules.ContextFilePathspecs()...)
+	cmd := exec.Command("git", args...)
 	cmd.Dir = dir
 	output, err := cmd.Output()
 	if err != nil {
@@ -102,10 +246,10 @@ func findContextFilesGit(dir string) []string {
 		return []string{}
 	}
 
-	var files []string
+	files := []string{}
 	for _, line := range strings.Split(trimmed, "\n") {
 		line = strings.TrimSpace(line)
-		if line != "" {
+		if line != "" && rules.ContextFileFormat(line) != nil {
 			files = append(files, filepath.Join(dir, line))
 		}
 	}
@@ -209,7 +353,7 @@ func findContextFilesWalk(dir string) []string {
 		}
 		if info.IsDir() {
 			base := filepath.Base(path)
-			if base != "." && strings.HasPrefix(base, ".") {
+			if base != "." && strings.HasPrefix(base, ".") && !rules.IsContextFormatDir(base) {
 				return filepath.SkipDir
 			}
 			if base == "node_modules" || base == "vendor" {
@@ -217,8 +361,8 @@ func findContextFilesWalk(dir string) []string {
 			}
 			return nil
 		}
-		name := filepath.Base(path)
-		if name == "CLAUDE.md" || name == "AGENTS.md" {
+		rel, err := filepath.Rel(dir, path)
+		if err == nil && rules.ContextFileFormat(rel) != nil {
 			files = append(files, path)
 		}
 		return nil
@@ -230,10 +374,14 @@ func findContextFilesWalk(dir string) []string {
 type fileAnalysis struct {
 	FilePath        string
 	Ctx             *rules.AnalysisContext
+	Profile         rules.AgentProfile
+	Engine          *rules.Engine
 	Results         []rules.RuleResult
 	Refs            []rules.RefInfo
 	RefResults      map[string][]rules.RuleResult
 	AggMetrics      rules.AggregateMetrics
+	Positions       *rules.PositionAnalysis
+	Drift           *rules.DriftReport
 	DimensionScores *rules.DimensionScores
 	FreshnessDays   int
 	Score           int
@@ -242,7 +390,7 @@ type fileAnalysis struct {
 }
 
 func buildAnalysis(filePath string) (*fileAnalysis, error) {
-	content, err := os.ReadFile(filePath)
+	content, err := rules.ActiveSource().ReadFile(filePath)
 	if err != nil {
 		return nil, err
 	}
@@ -256,13 +404,24 @@ func buildAnalysis(filePath string) (*fileAnalysis, error) {
 	if err != nil {
 		return nil, err
 	}
-
 	ctx := rules.BuildContext(filePath, string(content))
+	profile, err := activeConfig.ProfileFor(ctx, profileName)
+	if err != nil {
+		return nil, err
+	}
+	allRules = rules.FormatOf(ctx).ApplicableRules(profile.ApplyRules(allRules))
+	allRules, err = activeConfig.ApplyRules(allRules)
+	if err != nil {
+		return nil, err
+	}
 
 	baseDir := filepath.Dir(filePath)
 
 	// Detect technology stacks from repo root
 	repoRoot := rules.GetGitRoot(baseDir)
+	if src, ok := rules.ActiveSource().(*rules.GitSource); ok {
+		repoRoot = src.Root // baseDir may not exist in the working tree
+	}
 	if repoRoot == "" {
 		repoRoot = baseDir
 	}
@@ -271,16 +430,26 @@ func buildAnalysis(filePath string) (*fileAnalysis, error) {
 		ctx.Metrics["detected_stacks"] = detectedStacks
 	}
 
+	rules.EnrichContextWithCommands(ctx, rules.ValidateCommands(ctx, baseDir, repoRoot))
+	rules.EnrichContextWithMentions(ctx, rules.ValidateMentions(ctx, repoRoot))
+
 	refs := rules.ResolveReferences(ctx, baseDir, staleThreshold)
 	rules.EnrichContextWithRefMetrics(ctx, refs)
 
-	aggMetrics := rules.ComputeAggregateMetrics(ctx, refs)
+	aggMetrics := rules.ComputeAggregateMetrics(ctx, refs, similarityThreshold)
 	ctx.Metrics["total_instruction_count"] = aggMetrics.TotalInstructionCount
 	ctx.Metrics["duplicate_instruction_count"] = len(aggMetrics.Duplicates)
+	ctx.Metrics["conflicting_instruction_count"] = len(aggMetrics.Conflicts)
+	ctx.Metrics["total_token_count"] = aggMetrics.TotalTokenCount
+
+	profile.ApplyMetrics(ctx)
 
-	scopeCommits, claudeMdDays := rules.ScopeActivitySinceUpdate(filePath)
-	ctx.Metrics["scope_commits_since_update"] = scopeCommits
-	ctx.Metrics["claude_md_days_since_update"] = claudeMdDays
+	positions := rules.AnalyzePositions(ctx, baseDir)
+	ctx.Metrics["attention_risk"] = positions.AttentionRisk
+	ctx.Metrics["buried_critical_instruction_count"] = len(positions.Buried)
+
+	drift := rules.AnalyzeDrift(ctx, repoRoot)
+	rules.EnrichContextWithDrift(ctx, drift)
 
 	engine := rules.NewEngine(allRules)
 	results := engine.Evaluate(ctx)
@@ -295,8 +464,10 @@ func buildAnalysis(filePath string) (*fileAnalysis, error) {
 		}
 	}
 
+	results = applyBaseline(filePath, results, refs, refResults)
+
 	freshnessScore, freshnessDays := rules.CalculateFreshnessScore(filePath)
-	dimScores := rules.CalculateDimensionScores(results, freshnessScore)
+	dimScores := rules.CalculateWeightedDimensionScores(results, freshnessScore, activeConfig.DimensionWeights())
 	score := dimScores.Overall
 
 	errors := 0
@@ -316,10 +487,14 @@ func buildAnalysis(filePath string) (*fileAnalysis, error) {
 	return &fileAnalysis{
 		FilePath:        filePath,
 		Ctx:             ctx,
+		Profile:         profile,
+		Engine:          engine,
 		Results:         results,
 		Refs:            refs,
 		RefResults:      refResults,
 		AggMetrics:      aggMetrics,
+		Positions:       positions,
+		Drift:           drift,
 		DimensionScores: dimScores,
 		FreshnessDays:   freshnessDays,
 		Score:           score,
@@ -328,73 +503,250 @@ func buildAnalysis(filePath string) (*fileAnalysis, error) {
 	}, nil
 }
 
-func analyzeFile(filePath string) {
+func analyzeFile(filePath string) (*fileAnalysis, error) {
 	fa, err := buildAnalysis(filePath)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		return
+		return nil, err
 	}
 
 	filterOpts := buildFilterOpts()
-	printReport(fa, filterOpts)
+	switch outputFormat {
+	case formatJSON:
+		writeJSON(os.Stdout, newJSONFileReport(fa, filterOpts))
+	case formatSARIF:
+		writeJSON(os.Stdout, newSARIFFileReport(fa, filterOpts))
+	default:
+		printReport(fa, filterOpts)
+	}
+	return fa, nil
 }
 
-func printRepoReport(dir string, files []string) {
-	fmt.Println("=" + strings.Repeat("=", 59))
-	fmt.Println("  Repository Context Report")
-	fmt.Println("=" + strings.Repeat("=", 59))
-	fmt.Println()
+func analyzeRepo(dir string, files []string) *repoAnalysis {
+	ra := buildRepoAnalysis(dir, files)
+
+	switch outputFormat {
+	case formatJSON:
+		writeJSON(os.Stdout, newJSONRepoReport(ra, buildFilterOpts()))
+	case formatSARIF:
+		writeJSON(os.Stdout, newSARIFRepoReport(ra, buildFilterOpts()))
+	default:
+		printRepoReport(ra)
+	}
+	return ra
+}
+
+// repoAnalysis holds the combined results of analyzing every context file in a directory
+type repoAnalysis struct {
+	Dir               string
+	Analyses          []*fileAnalysis
+	Hierarchy         *rules.HierarchyAnalysis
+	Findings          []repoFinding
+	Orphans           []string
+	TotalLines        int
+	TotalInstructions int
+	TotalTokens       int
+	TotalErrors       int
+	TotalWarnings     int
+	AvgScore          int
+}
+
+// repoFinding is a repository-level violation that isn't tied to a single file
+type repoFinding struct {
+	Code     string
+	Severity rules.Severity
+	Message  string
+	Files    []string
+	Details  []string // instruction-level evidence, e.g. "pkg/CLAUDE.md:4 repeats CLAUDE.md:9: ..."
+	Penalty  int
+}
+
+// Penalties subtracted from the repo's average score per hierarchy finding
+const (
+	penaltyScopeOverlap  = 5
+	penaltyScopeConflict = 15
+	penaltyScopeLoad     = 10
+)
+
+// repoFindingRules describe the repo-level checks, whose finding messages
+// vary with the files involved
+var repoFindingRules = map[string]rules.Rule{
+	"CD060": {
+		Description:  "Nested context file repeats an ancestor",
+		Severity:     rules.SeverityWarning,
+		ErrorMessage: "A nested context file repeats instructions of an ancestor, which is already loaded",
+		Suggestion:   "Keep shared instructions in the ancestor only",
+	},
+	"CD061": {
+		Description:  "Nested context file contradicts an ancestor",
+		Severity:     rules.SeverityError,
+		ErrorMessage: "A nested context file contradicts an instruction of an ancestor loaded with it",
+		Suggestion:   "State the exception explicitly in the ancestor or scope the instruction",
+	},
+	"CD062": {
+		Description:  "Too many instructions loaded in a directory",
+		Severity:     rules.SeverityWarning,
+		ErrorMessage: "Working in a directory loads more instructions across a nested file and its ancestors than the profile allows",
+		Suggestion:   "Move package-specific detail to referenced docs",
+	},
+}
+
+func buildRepoAnalysis(dir string, files []string) *repoAnalysis {
+	ra := &repoAnalysis{Dir: dir}
 
-	var analyses []*fileAnalysis
 	for _, f := range files {
 		fa, err := buildAnalysis(f)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "  Error analyzing %s: %v\n", f, err)
 			continue
 		}
-		analyses = append(analyses, fa)
+		ra.Analyses = append(ra.Analyses, fa)
+	}
+
+	if len(ra.Analyses) == 0 {
+		return ra
+	}
+
+	contexts := make([]*rules.AnalysisContext, len(ra.Analyses))
+	for i, fa := range ra.Analyses {
+		contexts[i] = fa.Ctx
+	}
+	ra.Hierarchy = rules.AnalyzeHierarchy(dir, contexts, similarityThreshold)
+	profiles := make(map[string]rules.AgentProfile, len(ra.Analyses))
+	for _, fa := range ra.Analyses {
+		profiles[relPath(dir, fa.Ctx.FilePath)] = fa.Profile
+	}
+	ra.Findings = append(ra.Findings, hierarchyFindings(ra.Hierarchy, profiles)...)
+
+	totalScore := 0
+	for _, fa := range ra.Analyses {
+		totalScore += fa.Score
+		ra.TotalErrors += fa.Errors
+		ra.TotalWarnings += fa.Warnings
+		ra.TotalInstructions += fa.AggMetrics.TotalInstructionCount
+		ra.TotalLines += fa.AggMetrics.TotalLineCount
+		ra.TotalTokens += fa.AggMetrics.TotalTokenCount
+	}
+
+	// Only a scan of every context file can tell which docs none reference
+	if !staged {
+		ra.Orphans = findOrphanMDFiles(dir, ra.Analyses)
+	}
+
+	ra.AvgScore = totalScore / len(ra.Analyses)
+	for _, f := range ra.Findings {
+		ra.AvgScore = max(0, ra.AvgScore-f.Penalty)
+		switch f.Severity {
+		case rules.SeverityError:
+			ra.TotalErrors++
+		case rules.SeverityWarning:
+			ra.TotalWarnings++
+		}
+	}
+
+	return ra
+}
+
+// hierarchyFindings turns the checks between nested context files and their
+// ancestors into repo findings. A scope's instruction budget is the
+// maxInstructions of its nearest file's profile, which also adds its baseline
+// to the load as in CD003; profiles are keyed by path relative to the repo.
+func hierarchyFindings(ha *rules.HierarchyAnalysis, profiles map[string]rules.AgentProfile) []repoFinding {
+	var findings []repoFinding
+
+	for _, o := range ha.Overlaps {
+		f := repoFinding{
+			Code:     "CD060",
+			Severity: rules.SeverityWarning,
+			Message:  fmt.Sprintf("%s repeats %d instruction(s) from %s, which is already loaded", o.File, len(o.Pairs), o.Ancestor),
+			Files:    []string{o.File, o.Ancestor},
+			Penalty:  penaltyScopeOverlap,
+		}
+		for _, p := range o.Pairs {
+			f.Details = append(f.Details, fmt.Sprintf("%s:%d repeats %s:%d: %s",
+				p.Nested.File, p.Nested.Line, p.Ancestor.File, p.Ancestor.Line, strings.TrimSpace(p.Nested.Text)))
+		}
+		findings = append(findings, f)
+	}
+
+	for _, c := range ha.Conflicts {
+		f := repoFinding{
+			Code:     "CD061",
+			Severity: rules.SeverityError,
+			Message:  fmt.Sprintf("%s contradicts %d instruction(s) of %s", c.File, len(c.Conflicts), c.Ancestor),
+			Files:    []string{c.File, c.Ancestor},
+			Penalty:  penaltyScopeConflict,
+		}
+		for _, conflict := range c.Conflicts {
+			f.Details = append(f.Details, fmt.Sprintf("%s:%d (%s) vs %s:%d (%s)",
+				conflict.Second.File, conflict.Second.Line, conflict.Second.Text,
+				conflict.First.File, conflict.First.Line, conflict.First.Text))
+		}
+		findings = append(findings, f)
+	}
+
+	for _, l := range ha.Loads {
+		profile, ok := profiles[l.File]
+		if !ok {
+			profile = rules.AgentProfile{Agent: "the agent", MaxInstructions: rules.DefaultMaxInstructions}
+		}
+		total := l.Instructions + profile.BaselineInstructions
+		if len(l.Chain) < 2 || total <= profile.MaxInstructions {
+			continue
+		}
+		findings = append(findings, repoFinding{
+			Code:     "CD062",
+			Severity: rules.SeverityWarning,
+			Message: fmt.Sprintf("Working in %s loads ~%d instructions from %d context files and ~%d from %s (budget %d)",
+				filepath.Dir(l.File), l.Instructions, len(l.Chain), profile.BaselineInstructions, profile.Agent, profile.MaxInstructions),
+			Files:   l.Chain,
+			Penalty: penaltyScopeLoad,
+		})
 	}
 
-	if len(analyses) == 0 {
+	return findings
+}
+
+// relPath returns path relative to dir, falling back to path itself
+func relPath(dir, path string) string {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return path
+	}
+	return rel
+}
+
+func printRepoReport(ra *repoAnalysis) {
+	dir := ra.Dir
+
+	fmt.Println("=" + strings.Repeat("=", 59))
+	fmt.Println("  Repository Context Report")
+	fmt.Println("=" + strings.Repeat("=", 59))
+	fmt.Println()
+
+	if len(ra.Analyses) == 0 {
 		fmt.Println("  No files could be analyzed.")
 		return
 	}
 
-	// Multiple context files violation
-	if len(analyses) > 1 {
-		fmt.Println("✗ [CD060] MULTIPLE CONTEXT FILES DETECTED")
+	printContextHierarchy(dir, ra.Hierarchy)
+
+	if len(ra.Findings) > 0 {
+		fmt.Println("REPO FINDINGS")
 		fmt.Println(strings.Repeat("-", 40))
-		fmt.Println("  A repository should have exactly one context file at the root.")
-		fmt.Println("  Multiple files fragment context and confuse the LLM.")
-		fmt.Println("  Consolidate into a single root context file and use progressive")
-		fmt.Println("  disclosure to reference supporting docs.")
-		fmt.Println()
-		for _, fa := range analyses {
-			relPath, err := filepath.Rel(dir, fa.FilePath)
-			if err != nil {
-				relPath = fa.FilePath
+		for _, f := range ra.Findings {
+			fmt.Printf("  %s [%s] %s (-%d)\n", getSeverityIcon(f.Severity), f.Code, f.Message, f.Penalty)
+			for _, d := range f.Details {
+				fmt.Printf("       %s\n", truncate(d, 100))
 			}
-			fmt.Printf("  ✗ %s\n", relPath)
 		}
 		fmt.Println()
 	}
 
 	// Summary table
-	fmt.Printf("FILES (%d context files found)\n", len(analyses))
+	fmt.Printf("FILES (%d context files found)\n", len(ra.Analyses))
 	fmt.Println(strings.Repeat("-", 40))
 
-	totalScore := 0
-	totalErrors := 0
-	totalWarnings := 0
-	totalInstructions := 0
-	totalLines := 0
-
-	for _, fa := range analyses {
-		relPath, err := filepath.Rel(dir, fa.FilePath)
-		if err != nil {
-			relPath = fa.FilePath
-		}
-
+	for _, fa := range ra.Analyses {
 		icon := "✓"
 		if fa.Errors > 0 {
 			icon = "✗"
@@ -402,43 +754,32 @@ func printRepoReport(dir string, files []string) {
 			icon = "⚠"
 		}
 
-		fmt.Printf("  %s %s\n", icon, relPath)
+		fmt.Printf("  %s %s\n", icon, relPath(dir, fa.FilePath))
 		dimCompact := formatDimensionCompact(fa.DimensionScores)
-		fmt.Printf("      Score: %d/100 %s  Lines: %d  Instructions: ~%d  Errors: %d  Warnings: %d\n",
-			fa.Score, dimCompact, fa.Ctx.LineCount, fa.Ctx.InstructionCount, fa.Errors, fa.Warnings)
+		fmt.Printf("      Score: %d/100 %s  Lines: %d  Instructions: ~%d  Tokens: %d  Errors: %d  Warnings: %d\n",
+			fa.Score, dimCompact, fa.Ctx.LineCount, fa.Ctx.InstructionCount, fa.Ctx.TokenCount, fa.Errors, fa.Warnings)
 
 		// Show referenced docs inline (full tree)
 		if len(fa.Refs) > 0 {
 			printRepoRefTree(fa.Refs, "      ")
 		}
-
-		totalScore += fa.Score
-		totalErrors += fa.Errors
-		totalWarnings += fa.Warnings
-		totalInstructions += fa.AggMetrics.TotalInstructionCount
-		totalLines += fa.AggMetrics.TotalLineCount
 	}
 	fmt.Println()
 
 	// Issues section — only show files that have problems
 	hasIssues := false
-	for _, fa := range analyses {
+	for _, fa := range ra.Analyses {
 		if fa.Errors == 0 && fa.Warnings == 0 {
 			continue
 		}
 
-		relPath, err := filepath.Rel(dir, fa.FilePath)
-		if err != nil {
-			relPath = fa.FilePath
-		}
-
 		if !hasIssues {
 			fmt.Println("ISSUES")
 			fmt.Println(strings.Repeat("-", 40))
 			hasIssues = true
 		}
 
-		fmt.Printf("  %s\n", relPath)
+		fmt.Printf("  %s\n", relPath(dir, fa.FilePath))
 		for _, r := range fa.Results {
 			if r.Rule.Category == "good-practice" || !r.Passed {
 				continue
@@ -447,7 +788,11 @@ func printRepoReport(dir string, files []string) {
 				continue
 			}
 			icon := getSeverityIcon(r.Rule.Severity)
-			fmt.Printf("    %s [%s] %s\n", icon, r.Rule.Code, r.Rule.ErrorMessage)
+			location := ""
+			if len(r.Spans) > 0 {
+				location = fmt.Sprintf(" (%s:%d)", relPath(dir, fa.FilePath), r.Spans[0].Line)
+			}
+			fmt.Printf("    %s [%s] %s%s\n", icon, r.Rule.Code, r.Rule.ErrorMessage, location)
 		}
 	}
 	if hasIssues {
@@ -455,34 +800,72 @@ func printRepoReport(dir string, files []string) {
 	}
 
 	// Orphan docs section
-	orphans := findOrphanMDFiles(dir, analyses)
-	if len(orphans) > 0 {
+	if len(ra.Orphans) > 0 {
 		fmt.Println("ORPHAN DOCS (not referenced by any context file)")
 		fmt.Println(strings.Repeat("-", 40))
-		for _, o := range orphans {
+		for _, o := range ra.Orphans {
 			fmt.Printf("  ? %s\n", o)
 		}
 		fmt.Println()
 	}
 
 	// Repo totals
-	avgScore := totalScore / len(analyses)
-	if len(analyses) > 1 {
-		// Heavy penalty for multiple context files
-		avgScore = max(0, avgScore-30)
-		totalErrors++
-	}
 	fmt.Println("REPO SUMMARY")
 	fmt.Println(strings.Repeat("-", 40))
-	fmt.Printf("  Files:        %d\n", len(analyses))
-	fmt.Printf("  Total lines:  %d\n", totalLines)
-	fmt.Printf("  Total instr:  ~%d\n", totalInstructions)
-	fmt.Printf("  Errors:       %d\n", totalErrors)
-	fmt.Printf("  Warnings:     %d\n", totalWarnings)
-	fmt.Printf("  Avg score:    %d/100\n", avgScore)
+	fmt.Printf("  Files:        %d\n", len(ra.Analyses))
+	fmt.Printf("  Total lines:  %d\n", ra.TotalLines)
+	fmt.Printf("  Total instr:  ~%d\n", ra.TotalInstructions)
+	fmt.Printf("  Total tokens: %d\n", ra.TotalTokens)
+	fmt.Printf("  Errors:       %d\n", ra.TotalErrors)
+	fmt.Printf("  Warnings:     %d\n", ra.TotalWarnings)
+	fmt.Printf("  Avg score:    %d/100\n", ra.AvgScore)
+	fmt.Println()
+}
+
+// printContextHierarchy shows the directory scopes of the context files and
+// how many instructions an agent working in each one loads
+func printContextHierarchy(dir string, ha *rules.HierarchyAnalysis) {
+	if ha == nil || len(ha.Loads) < 2 {
+		return
+	}
+	loads := make(map[string]rules.ScopeLoad, len(ha.Loads))
+	for _, l := range ha.Loads {
+		loads[l.File] = l
+	}
+
+	fmt.Println("CONTEXT HIERARCHY")
+	fmt.Println(strings.Repeat("-", 40))
+	var walk func(n *rules.ScopeNode, indent string)
+	walk = func(n *rules.ScopeNode, indent string) {
+		if len(n.Files) > 0 || n.Parent == nil {
+			fmt.Printf("%s%s/\n", indent, n.Dir)
+		}
+		for _, f := range n.Files {
+			l := loads[relPath(dir, f.FilePath)]
+			loaded := ""
+			if len(l.Chain) > 1 {
+				loaded = fmt.Sprintf(", ~%d loaded with %d ancestor(s)", l.Instructions, len(l.Chain)-1)
+			}
+			fmt.Printf("%s  %s (~%d instructions%s)\n", indent, filepath.Base(f.FilePath), f.InstructionCount, loaded)
+		}
+		for _, c := range n.Children {
+			walk(c, indent+"  ")
+		}
+	}
+	walk(ha.Root, "  ")
 	fmt.Println()
 }
 
+// setupTokenizer selects the tokenizer used for token metrics
+func setupTokenizer() error {
+	t, err := rules.NewTokenizer(tokenizerName, tokenizerVocab)
+	if err != nil {
+		return err
+	}
+	rules.SetTokenizer(t)
+	return nil
+}
+
 func buildFilterOpts() rules.FilterOptions {
 	filterOpts := rules.FilterOptions{
 		FailuresOnly:     !verbose,
@@ -511,28 +894,43 @@ func printReport(fa *fileAnalysis, filterOpts rules.FilterOptions) {
 	fmt.Println("=" + strings.Repeat("=", 59))
 	fmt.Println()
 
-	fmt.Printf("File: %s\n\n", ctx.FilePath)
+	format := rules.FormatOf(ctx)
+	profile := fa.Profile
+	fmt.Printf("File:    %s\n", ctx.FilePath)
+	fmt.Printf("Format:  %s (%s)\n", format.Name, format.Agent)
+	if fm := ctx.Frontmatter; fm != nil {
+		switch {
+		case fm.Err != nil:
+			fmt.Printf("         %v\n", fm.Err)
+		case len(fm.Globs) > 0:
+			fmt.Printf("         applies to %s\n", strings.Join(fm.Globs, ", "))
+		}
+	}
+	fmt.Printf("Profile: %s (~%d instructions, ~%d tokens of system prompt)\n",
+		profile.Name, profile.BaselineInstructions, profile.BaselineTokens)
+	fmt.Println()
 
 	// Metrics section
 	fmt.Println("METRICS")
 	fmt.Println(strings.Repeat("-", 40))
 
-	lineStatus := "OK"
-	if ctx.LineCount > 300 {
-		lineStatus = "HIGH"
-	} else if ctx.LineCount > 100 {
-		lineStatus = "MODERATE"
-	}
-	fmt.Printf("  Lines:        %d (%s)\n", ctx.LineCount, lineStatus)
+	fmt.Printf("  Lines:        %d (%s)\n", ctx.LineCount, lineStatus(fa))
 
-	effective := ctx.InstructionCount + 50
+	effective := ctx.InstructionCount + profile.BaselineInstructions
 	instrStatus := "OK"
-	if effective > 150 {
+	if effective > profile.MaxInstructions {
 		instrStatus = "HIGH"
-	} else if effective > 100 {
+	} else if effective > profile.WarnInstructions {
 		instrStatus = "MODERATE"
 	}
-	fmt.Printf("  Instructions: ~%d (+50 Claude = ~%d) (%s)\n", ctx.InstructionCount, effective, instrStatus)
+	fmt.Printf("  Instructions: ~%d (+%d %s = ~%d) (%s)\n",
+		ctx.InstructionCount, profile.BaselineInstructions, profile.Agent, effective, instrStatus)
+	fmt.Printf("  Tokens:       %d (+%d %s = ~%d) (%s)\n",
+		ctx.TokenCount, profile.BaselineTokens, profile.Agent, ctx.TokenCount+profile.BaselineTokens, rules.ActiveTokenizer().Name())
+	if imp := ctx.Imported; imp.Files > 0 {
+		fmt.Printf("  Imported:     %d lines, ~%d instructions, %d tokens from %d @import file(s), included above\n",
+			imp.Lines, imp.Instructions, imp.Tokens, imp.Files)
+	}
 
 	hasProgDisc := ctx.Metrics["hasProgressiveDisclosure"].(bool)
 	pdStatus := "NO"
@@ -541,16 +939,18 @@ func printReport(fa *fileAnalysis, filterOpts rules.FilterOptions) {
 	}
 	fmt.Printf("  Progressive Disclosure: %s\n", pdStatus)
 
-	if scopeCommits, ok := ctx.Metrics["scope_commits_since_update"].(int); ok {
-		claudeDays, _ := ctx.Metrics["claude_md_days_since_update"].(int)
-		if claudeDays >= 0 {
-			fmt.Printf("  Scope Activity:  %d commits since last context file update (%d days ago)\n", scopeCommits, claudeDays)
-		}
+	if d := fa.Drift; d != nil {
+		fmt.Printf("  Scope Activity:  %d commits since last context file update (%d days ago)\n", d.ScopeCommits, d.DaysSinceUpdate)
+		fmt.Printf("  Drift:        %d commits touched mentioned code since last update\n", d.Commits)
 	}
 
 	if stacks, ok := ctx.Metrics["detected_stacks"].([]string); ok && len(stacks) > 0 {
 		fmt.Printf("  Detected Stacks: %s\n", formatStackNames(stacks))
 	}
+
+	if n := countBaselined(fa); n > 0 {
+		fmt.Printf("  Baseline:     %d known findings hidden\n", n)
+	}
 	fmt.Println()
 
 	// Group results by category
@@ -578,15 +978,15 @@ func printReport(fa *fileAnalysis, filterOpts rules.FilterOptions) {
 	// Print problems by category
 	categoryOrder := []string{"length", "instructions", "linter-abuse", "auto-generated", "progressive-disclosure", "referenced-docs", "cross-file-consistency", "staleness", "stack-suggestions"}
 	categoryNames := map[string]string{
-		"length":                  "LENGTH ISSUES",
-		"instructions":            "INSTRUCTION COUNT ISSUES",
-		"linter-abuse":            "LINTER ABUSE DETECTED",
-		"auto-generated":          "AUTO-GENERATED CONTENT",
-		"progressive-disclosure":  "PROGRESSIVE DISCLOSURE",
-		"referenced-docs":         "REFERENCED DOCS",
-		"cross-file-consistency":  "CROSS-FILE CONSISTENCY",
-		"staleness":               "STALENESS CHECK",
-		"stack-suggestions":       "STACK-SPECIFIC SUGGESTIONS",
+		"length":                 "LENGTH ISSUES",
+		"instructions":           "INSTRUCTION COUNT ISSUES",
+		"linter-abuse":           "LINTER ABUSE DETECTED",
+		"auto-generated":         "AUTO-GENERATED CONTENT",
+		"progressive-disclosure": "PROGRESSIVE DISCLOSURE",
+		"referenced-docs":        "REFERENCED DOCS",
+		"cross-file-consistency": "CROSS-FILE CONSISTENCY",
+		"staleness":              "STALENESS CHECK",
+		"stack-suggestions":      "STACK-SPECIFIC SUGGESTIONS",
 	}
 
 	// Add any custom categories found in results
@@ -652,6 +1052,7 @@ func printReport(fa *fileAnalysis, filterOpts rules.FilterOptions) {
 
 			severityIcon := getSeverityIcon(p.Rule.Severity)
 			fmt.Printf("  %s [%s] %s\n", severityIcon, p.Rule.Code, p.Rule.ErrorMessage)
+			printSpans(ctx.FilePath, p.Spans, "     ")
 			if p.Rule.Suggestion != "" {
 				fmt.Printf("     → %s\n", p.Rule.Suggestion)
 			}
@@ -669,6 +1070,14 @@ func printReport(fa *fileAnalysis, filterOpts rules.FilterOptions) {
 		fmt.Println()
 	}
 
+	printTokenHeatmap(ctx)
+	printAttentionRisk(fa.Positions)
+	printCodeDrift(ctx.FilePath, fa.Drift)
+
+	if verbose {
+		printSuppressedFindings(fa)
+	}
+
 	// Print referenced docs section
 	if len(refs) > 0 {
 		printReferencedDocs(refs)
@@ -686,7 +1095,7 @@ func printReport(fa *fileAnalysis, filterOpts rules.FilterOptions) {
 	}
 
 	// Print cross-file analysis section
-	if len(refs) > 0 {
+	if len(refs) > 0 || len(aggMetrics.Conflicts) > 0 {
 		printCrossFileAnalysis(aggMetrics)
 	}
 
@@ -701,6 +1110,175 @@ func printReport(fa *fileAnalysis, filterOpts rules.FilterOptions) {
 	}
 }
 
+// heatmapMinShare is the token share (percent) a section needs to appear in the
+// heatmap without -verbose
+const heatmapMinShare = 5
+
+// printTokenHeatmap shows how the file's tokens are spread across its sections
+func printTokenHeatmap(ctx *rules.AnalysisContext) {
+	if ctx.Sections == nil || len(ctx.Sections.Children) == 0 || ctx.TokenCount == 0 {
+		return
+	}
+
+	var sections []*rules.Section
+	ctx.Sections.Walk(func(s *rules.Section) {
+		if s.Level == 0 && s.TokenCount == 0 {
+			return
+		}
+		if !verbose && s.TokenCount*100 < heatmapMinShare*ctx.TokenCount {
+			return
+		}
+		sections = append(sections, s)
+	})
+	if len(sections) < 2 {
+		return
+	}
+
+	// Bars are relative to the largest section so hot spots stand out
+	largest := 0
+	for _, s := range sections {
+		largest = max(largest, s.TokenCount)
+	}
+
+	fmt.Println("TOKEN HEATMAP")
+	fmt.Println(strings.Repeat("-", 40))
+	for _, s := range sections {
+		title := s.Title
+		indent := ""
+		if s.Level == 0 {
+			title = "(before first heading)"
+		} else {
+			indent = strings.Repeat("  ", s.Level-1)
+		}
+		share := s.TokenCount * 100 / ctx.TokenCount
+		bar := renderProgressBar(s.TokenCount*100/max(largest, 1), 20)
+		fmt.Printf("  %s %3d%% %6d  %s%s\n", bar, share, s.TokenCount, indent, truncate(title, 40))
+	}
+	fmt.Println()
+}
+
+// printAttentionRisk lists critical instructions buried in the middle of the
+// loaded context and the order they should open the file in
+func printAttentionRisk(pa *rules.PositionAnalysis) {
+	if pa == nil || len(pa.Buried) == 0 {
+		return
+	}
+
+	fmt.Println("ATTENTION RISK")
+	fmt.Println(strings.Repeat("-", 40))
+	fmt.Printf("  ⚠ %d of %d critical instructions (%d%%) sit in the middle third of the context\n",
+		len(pa.Buried), len(pa.Critical), pa.AttentionRisk)
+	for _, b := range pa.Buried {
+		fmt.Printf("       %s:%d (at %d%%): %s\n", b.File, b.Line, int(b.Position*100), truncate(b.Text, 60))
+	}
+	start, end := pa.SuggestedOrder()
+	fmt.Println("     → Suggested reordering, strongest wording nearest the edges:")
+	if len(start) > 0 {
+		fmt.Println("       Open the file with:")
+		for i, b := range start {
+			fmt.Printf("         %d. %s\n", i+1, truncate(b.Text, 60))
+		}
+	}
+	if len(end) > 0 {
+		fmt.Println("       End the file with:")
+		for i, b := range end {
+			fmt.Printf("         %d. %s\n", i+1, truncate(b.Text, 60))
+		}
+	}
+	fmt.Println()
+}
+
+// lineStatus rates the file's length against the thresholds of CD001 (HIGH)
+// and CD002 (MODERATE) as configured for it
+func lineStatus(fa *fileAnalysis) string {
+	lines := fa.Ctx.LineCount
+	if limit, ok := lineLimit(fa, "CD001"); ok && lines > limit {
+		return "HIGH"
+	}
+	if limit, ok := lineLimit(fa, "CD002"); ok && lines > limit {
+		return "MODERATE"
+	}
+	return "OK"
+}
+
+// lineLimit returns the line count above which the rule fires, or false when
+// the rule is disabled or doesn't compare the line count
+func lineLimit(fa *fileAnalysis, code string) (int, bool) {
+	if fa.Engine == nil {
+		return 0, false
+	}
+	for _, r := range fa.Engine.Rules {
+		spec := r.MatchSpec
+		if r.Code != code || spec.Metric != rules.MetricLineCount || spec.Action != rules.ActionGreaterThan || spec.Section != "" {
+			continue
+		}
+		if limit, ok := spec.Value.(int); ok {
+			return limit, true
+		}
+	}
+	return 0, false
+}
+
+// printCodeDrift lists the mentioned code that changed since the context
+// file's last commit
+func printCodeDrift(path string, d *rules.DriftReport) {
+	if d == nil || len(d.Drifted) == 0 {
+		return
+	}
+
+	fmt.Println("CODE DRIFT")
+	fmt.Println(strings.Repeat("-", 40))
+	fmt.Printf("  Since %s (%d days ago):\n", shortCommit(d.Since), d.DaysSinceUpdate)
+	for _, drift := range d.Drifted {
+		status := string(drift.Status)
+		if drift.RenamedTo != "" {
+			status += " to " + drift.RenamedTo
+		}
+		fmt.Printf("  ⚠ %s %s (%d commits)\n", drift.Path, status, drift.Commits)
+		for _, m := range drift.Mentions {
+			fmt.Printf("       %s:%d: %s\n", path, m.Line, m.Text)
+		}
+	}
+	fmt.Println()
+}
+
+// printSuppressedFindings lists findings disabled by inline directives so they stay visible
+func printSuppressedFindings(fa *fileAnalysis) {
+	type suppressed struct {
+		path   string
+		result rules.RuleResult
+	}
+
+	var entries []suppressed
+	collect := func(path string, results []rules.RuleResult) {
+		for _, r := range results {
+			if r.Suppressed || len(r.SuppressedSpans) > 0 {
+				entries = append(entries, suppressed{path, r})
+			}
+		}
+	}
+	collect(fa.FilePath, fa.Results)
+	for _, ref := range rules.FlattenRefs(fa.Refs) {
+		collect(ref.ResolvedPath, fa.RefResults[ref.Path])
+	}
+
+	if len(entries) == 0 {
+		return
+	}
+
+	fmt.Println("SUPPRESSED FINDINGS")
+	fmt.Println(strings.Repeat("-", 40))
+	for _, e := range entries {
+		fmt.Printf("  - [%s] %s\n", e.result.Rule.Code, e.result.Rule.ErrorMessage)
+		if len(e.result.SuppressedSpans) == 0 {
+			fmt.Printf("       %s (entire file)\n", e.path)
+			continue
+		}
+		printSpans(e.path, e.result.SuppressedSpans, "     ")
+	}
+	fmt.Println()
+}
+
 func printReferencedDocs(refs []rules.RefInfo) {
 	fmt.Println("REFERENCED DOCS")
 	fmt.Println(strings.Repeat("-", 40))
@@ -710,12 +1288,13 @@ func printReferencedDocs(refs []rules.RefInfo) {
 
 func printRefTree(refs []rules.RefInfo, indent string) {
 	for _, ref := range refs {
+		path := refDisplayPath(ref)
 		if !ref.Exists {
-			fmt.Printf("%s✗ %s (file not found!)\n", indent, ref.Path)
+			fmt.Printf("%s✗ %s (file not found!)\n", indent, path)
 		} else if ref.IsStale {
-			fmt.Printf("%s⚠ %s (last updated %d days ago — stale)\n", indent, ref.Path, ref.DaysSinceUpdate)
+			fmt.Printf("%s⚠ %s (last updated %d days ago — stale)\n", indent, path, ref.DaysSinceUpdate)
 		} else {
-			fmt.Printf("%s✓ %s (last updated %d days ago)\n", indent, ref.Path, ref.DaysSinceUpdate)
+			fmt.Printf("%s✓ %s (last updated %d days ago)\n", indent, path, ref.DaysSinceUpdate)
 		}
 		if len(ref.Children) > 0 {
 			printRefTree(ref.Children, indent+"  ")
@@ -723,6 +1302,17 @@ func printRefTree(refs []rules.RefInfo, indent string) {
 	}
 }
 
+// refDisplayPath shows imports with their @ and marks the ones loaded at session start
+func refDisplayPath(ref rules.RefInfo) string {
+	if ref.Kind != rules.RefImport {
+		return ref.Path
+	}
+	if ref.Eager {
+		return "@" + ref.Path + " [loaded at start]"
+	}
+	return "@" + ref.Path
+}
+
 func printReferencedDocIssues(refs []rules.RefInfo, refResults map[string][]rules.RuleResult, filterOpts rules.FilterOptions) {
 	if refResults == nil {
 		return
@@ -768,6 +1358,7 @@ func printReferencedDocIssues(refs []rules.RefInfo, refResults map[string][]rule
 
 			severityIcon := getSeverityIcon(p.Rule.Severity)
 			fmt.Printf("  %s [%s] %s\n", severityIcon, p.Rule.Code, p.Rule.ErrorMessage)
+			printSpans(ref.ResolvedPath, p.Spans, "     ")
 			if p.Rule.Suggestion != "" {
 				fmt.Printf("     → %s\n", p.Rule.Suggestion)
 			}
@@ -781,11 +1372,27 @@ func printCrossFileAnalysis(agg rules.AggregateMetrics) {
 	fmt.Println(strings.Repeat("-", 40))
 	fmt.Printf("  Total instructions across %d files: %d\n", agg.FileCount, agg.TotalInstructionCount)
 	fmt.Printf("  Total lines across %d files: %d\n", agg.FileCount, agg.TotalLineCount)
+	fmt.Printf("  Total tokens across %d files: %d\n", agg.FileCount, agg.TotalTokenCount)
 
 	if len(agg.Duplicates) > 0 {
 		fmt.Printf("  ⚠ %d duplicated instructions found across files\n", len(agg.Duplicates))
 		for _, dup := range agg.Duplicates {
 			fmt.Printf("     → \"%s\" in %s\n", truncate(dup.Instruction, 60), strings.Join(dup.Files, ", "))
+			if len(dup.Instructions) > 1 {
+				fmt.Printf("       %d wordings, %d%% similar:\n", len(dup.Instructions), int(dup.Similarity*100))
+				for _, w := range dup.Instructions[1:] {
+					fmt.Printf("         \"%s\"\n", truncate(w, 60))
+				}
+			}
+		}
+	}
+
+	if len(agg.Conflicts) > 0 {
+		fmt.Printf("  ✗ %d conflicting instruction pairs found\n", len(agg.Conflicts))
+		for _, c := range agg.Conflicts {
+			fmt.Printf("     → %s:\n", c.Subject)
+			fmt.Printf("         %s:%d: %s\n", c.First.File, c.First.Line, truncate(c.First.Text, 60))
+			fmt.Printf("         %s:%d: %s\n", c.Second.File, c.Second.Line, truncate(c.Second.Text, 60))
 		}
 	}
 	fmt.Println()
@@ -847,12 +1454,13 @@ func formatDimensionCompact(ds *rules.DimensionScores) string {
 
 func printRepoRefTree(refs []rules.RefInfo, indent string) {
 	for _, ref := range refs {
+		path := refDisplayPath(ref)
 		if !ref.Exists {
-			fmt.Printf("%s✗ ref: %s (not found!)\n", indent, ref.Path)
+			fmt.Printf("%s✗ ref: %s (not found!)\n", indent, path)
 		} else if ref.IsStale {
-			fmt.Printf("%s⚠ ref: %s (stale — %d days)\n", indent, ref.Path, ref.DaysSinceUpdate)
+			fmt.Printf("%s⚠ ref: %s (stale — %d days)\n", indent, path, ref.DaysSinceUpdate)
 		} else {
-			fmt.Printf("%s✓ ref: %s (%d days ago)\n", indent, ref.Path, ref.DaysSinceUpdate)
+			fmt.Printf("%s✓ ref: %s (%d days ago)\n", indent, path, ref.DaysSinceUpdate)
 		}
 		if len(ref.Children) > 0 {
 			printRepoRefTree(ref.Children, indent+"  ")
@@ -860,6 +1468,20 @@ func printRepoRefTree(refs []rules.RefInfo, indent string) {
 	}
 }
 
+// maxSpansShown caps how many locations are printed per finding
+const maxSpansShown = 5
+
+// printSpans prints file:line locations with the offending line as a snippet
+func printSpans(path string, spans []rules.Span, indent string) {
+	for i, span := range spans {
+		if i == maxSpansShown {
+			fmt.Printf("%s  ... and %d more\n", indent, len(spans)-maxSpansShown)
+			break
+		}
+		fmt.Printf("%s  %s:%d: %s\n", indent, path, span.Line, truncate(span.Snippet, 70))
+	}
+}
+
 func truncate(s string, maxLen int) string {
 	if len(s) <= maxLen {
 		return s
@@ -916,6 +1538,8 @@ func stackDisplayName(stack string) string {
 		"typescript":     "TypeScript",
 		"rust":           "Rust",
 		"make":           "Make",
+		"task":           "Task",
+		"just":           "just",
 		"docker":         "Docker",
 		"github-actions": "GitHub Actions",
 	}
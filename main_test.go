@@ -1,11 +1,56 @@
 package main
 
 import (
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
 	"testing"
 
 	"context-doctor/rules"
 )
 
+// gitRepo creates a git repository in a temp dir and returns its path, with
+// symlinks resolved like the paths git reports, and a function running git in it
+func gitRepo(t *testing.T) (dir string, run func(args ...string)) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	run = func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %s", args, out)
+		}
+	}
+	run("init", "-q")
+	run("config", "user.email", "test@test.com")
+	run("config", "user.name", "Test")
+	return dir, run
+}
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
 // =============================================================================
 // calculateScore
 // =============================================================================
@@ -233,3 +278,246 @@ func TestFormatDimensionCompact(t *testing.T) {
 		}
 	})
 }
+
+// =============================================================================
+// printSpans
+// =============================================================================
+
+func TestPrintSpans_CapsOutput(t *testing.T) {
+	var spans []rules.Span
+	for i := 1; i <= maxSpansShown+2; i++ {
+		spans = append(spans, rules.Span{Line: i, Snippet: "use tabs"})
+	}
+
+	out := captureStdout(t, func() { printSpans("CLAUDE.md", spans, "") })
+	if !strings.Contains(out, "CLAUDE.md:1: use tabs") {
+		t.Errorf("expected file:line with snippet, got %q", out)
+	}
+	if !strings.Contains(out, "... and 2 more") {
+		t.Errorf("expected overflow marker, got %q", out)
+	}
+}
+
+// =============================================================================
+// lineStatus
+// =============================================================================
+
+func TestLineStatus(t *testing.T) {
+	builtin, err := rules.LoadBuiltinRules()
+	if err != nil {
+		t.Fatal(err)
+	}
+	high, moderate := 200, 50
+	cfg := &rules.Config{Rules: map[string]rules.RuleOverride{
+		"CD001": {Threshold: &high},
+		"CD002": {Threshold: &moderate},
+	}}
+	configured, err := cfg.ApplyRules(builtin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		rules []rules.Rule
+		lines int
+		want  string
+	}{
+		{"default high", builtin, 301, "HIGH"},
+		{"default moderate", builtin, 150, "MODERATE"},
+		{"default ok", builtin, 100, "OK"},
+		{"configured high", configured, 250, "HIGH"},
+		{"configured moderate", configured, 60, "MODERATE"},
+		{"rules disabled", nil, 1000, "OK"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fa := &fileAnalysis{
+				Ctx:    &rules.AnalysisContext{LineCount: tt.lines},
+				Engine: rules.NewEngine(tt.rules),
+			}
+			if got := lineStatus(fa); got != tt.want {
+				t.Errorf("lineStatus with %d lines = %s, want %s", tt.lines, got, tt.want)
+			}
+		})
+	}
+}
+
+// =============================================================================
+// hierarchyFindings
+// =============================================================================
+
+func TestHierarchyFindings(t *testing.T) {
+	ha := &rules.HierarchyAnalysis{
+		Overlaps: []rules.ScopeOverlap{{File: "pkg/CLAUDE.md", Ancestor: "CLAUDE.md", Pairs: []rules.InstructionPair{{
+			Nested:   rules.InstructionLocation{File: "pkg/CLAUDE.md", Line: 4, Text: "- Run make test"},
+			Ancestor: rules.InstructionLocation{File: "CLAUDE.md", Line: 9, Text: "- Run make test"},
+		}}}},
+		Conflicts: []rules.ScopeConflict{{File: "pkg/CLAUDE.md", Ancestor: "CLAUDE.md", Conflicts: []rules.ConflictInfo{{Subject: "pnpm"}}}},
+		Loads: []rules.ScopeLoad{
+			{File: "CLAUDE.md", Chain: []string{"CLAUDE.md"}, Instructions: 200},
+			{File: "pkg/CLAUDE.md", Chain: []string{"pkg/CLAUDE.md", "CLAUDE.md"}, Instructions: 220},
+			{File: "web/CLAUDE.md", Chain: []string{"web/CLAUDE.md", "CLAUDE.md"}, Instructions: 90},
+		},
+	}
+	claude, err := (*rules.Config)(nil).Profile("claude-code")
+	if err != nil {
+		t.Fatal(err)
+	}
+	profiles := map[string]rules.AgentProfile{"CLAUDE.md": claude, "pkg/CLAUDE.md": claude, "web/CLAUDE.md": claude}
+
+	got := hierarchyFindings(ha, profiles)
+	var codes []string
+	for _, f := range got {
+		codes = append(codes, f.Code)
+	}
+	// A lone oversized file is left to the per-file rules; only pkg/ exceeds the budget through nesting
+	if strings.Join(codes, ",") != "CD060,CD061,CD062" {
+		t.Fatalf("unexpected findings %v", codes)
+	}
+	if got[0].Details[0] != "pkg/CLAUDE.md:4 repeats CLAUDE.md:9: - Run make test" {
+		t.Errorf("unexpected detail %q", got[0].Details[0])
+	}
+	if got[1].Severity != rules.SeverityError || got[1].Penalty != penaltyScopeConflict {
+		t.Errorf("unexpected conflict finding %+v", got[1])
+	}
+	if !strings.Contains(got[2].Message, "pkg") || !strings.Contains(got[2].Message, "~50 from Claude Code (budget 150)") || len(got[2].Files) != 2 {
+		t.Errorf("unexpected scope finding %+v", got[2])
+	}
+
+	// web/ fits the default budget with Claude Code's baseline, but not a
+	// stricter profile's
+	strict := claude
+	strict.MaxInstructions = 120
+	profiles["web/CLAUDE.md"] = strict
+	got = hierarchyFindings(ha, profiles)
+	if last := got[len(got)-1]; last.Code != "CD062" || !strings.Contains(last.Message, "web") || !strings.Contains(last.Message, "budget 120") {
+		t.Errorf("expected web/ over the strict profile's budget, got %+v", last)
+	}
+}
+
+// captureStdout returns everything fn prints to stdout.
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	orig := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = orig }()
+
+	fn()
+	w.Close()
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out)
+}
+
+// =============================================================================
+// findContextFiles
+// =============================================================================
+
+func TestFindContextFilesWalk_Formats(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		"CLAUDE.md",
+		"GEMINI.md",
+		".github/copilot-instructions.md",
+		".cursor/rules/go.mdc",
+		".windsurfrules",
+		"CONVENTIONS.md",
+		"pkg/AGENTS.md",
+		"pkg/CONVENTIONS.md", // aider only reads the root file
+		".git/CLAUDE.md",     // hidden directories stay skipped
+		"node_modules/x/GEMINI.md",
+		"README.md",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("- Run tests\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var got []string
+	for _, f := range findContextFilesWalk(dir) {
+		got = append(got, relPath(dir, f))
+	}
+	sort.Strings(got)
+	want := []string{
+		".cursor/rules/go.mdc",
+		".github/copilot-instructions.md",
+		".windsurfrules",
+		"CLAUDE.md",
+		"CONVENTIONS.md",
+		"GEMINI.md",
+		"pkg/AGENTS.md",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("found %v, want %v", got, want)
+	}
+}
+
+// =============================================================================
+// runFix
+// =============================================================================
+
+func TestRunFix(t *testing.T) {
+	defer func(prev bool) { dryRun = prev }(dryRun)
+	defer func(prev float64) { similarityThreshold = prev }(similarityThreshold)
+	similarityThreshold = rules.DefaultSimilarityThreshold
+
+	root := t.TempDir()
+	original := "# Project\n\n- Run make test\n- Max line length 100\n- Keep PRs small\n"
+	writeFiles(t, root, map[string]string{"CLAUDE.md": original})
+	path := filepath.Join(root, "CLAUDE.md")
+	name := strings.TrimPrefix(displayPath(path), "/")
+
+	read := func() string {
+		t.Helper()
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	// A dry run prints the diff and leaves the file alone
+	dryRun = true
+	var code int
+	out := captureStdout(t, func() { code = runFix(path, false) })
+	if code != exitOK {
+		t.Errorf("dry run exit code = %d, want %d", code, exitOK)
+	}
+	want := "--- a/" + name + "\n+++ b/" + name + "\n" +
+		"@@ -1,5 +1,4 @@\n # Project\n \n - Run make test\n-- Max line length 100\n - Keep PRs small\n"
+	if out != want {
+		t.Errorf("dry run diff = %q, want %q", out, want)
+	}
+	if read() != original {
+		t.Error("dry run must not write the file")
+	}
+
+	// Writing applies the same change, after which nothing is left to fix
+	dryRun = false
+	out = captureStdout(t, func() { code = runFix(path, false) })
+	if code != exitOK || !strings.Contains(out, "Fixed "+displayPath(path)+" (1 changes)") {
+		t.Errorf("unexpected write run (exit %d): %q", code, out)
+	}
+	if got := read(); got != "# Project\n\n- Run make test\n- Keep PRs small\n" {
+		t.Errorf("fixed file = %q", got)
+	}
+	out = captureStdout(t, func() { code = runFix(path, false) })
+	if code != exitOK || out != "Nothing to fix\n" {
+		t.Errorf("expected nothing left to fix (exit %d): %q", code, out)
+	}
+
+	if code := runFix(t.TempDir(), true); code != exitError {
+		t.Errorf("directory without context files exit code = %d, want %d", code, exitError)
+	}
+}
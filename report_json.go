@@ -0,0 +1,661 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"context-doctor/rules"
+)
+
+// Output formats accepted by -format
+const (
+	formatText  = "text"
+	formatJSON  = "json"
+	formatSARIF = "sarif"
+)
+
+// jsonSchemaVersion is bumped whenever a field is removed or changes meaning.
+// Adding new fields does not bump the version.
+const jsonSchemaVersion = 1
+
+func isValidFormat(format string) bool {
+	switch format {
+	case formatText, formatJSON, formatSARIF:
+		return true
+	default:
+		return false
+	}
+}
+
+// jsonReport is the top-level document emitted by -format json
+type jsonReport struct {
+	SchemaVersion int            `json:"schemaVersion"`
+	Tool          jsonTool       `json:"tool"`
+	Mode          string         `json:"mode"`
+	Files         []jsonFile     `json:"files"`
+	Repo          *jsonRepo      `json:"repo,omitempty"`
+	Effective     *jsonEffective `json:"effective,omitempty"`
+	Diff          *jsonDiff      `json:"diff,omitempty"`
+}
+
+type jsonTool struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type jsonFile struct {
+	Path          string                   `json:"path"`
+	Format        jsonFormat               `json:"format"`
+	Profile       jsonProfile              `json:"profile"`
+	Score         int                      `json:"score"`
+	Errors        int                      `json:"errors"`
+	Warnings      int                      `json:"warnings"`
+	FreshnessDays int                      `json:"freshnessDays"`
+	Metrics       jsonMetrics              `json:"metrics"`
+	Dimensions    map[string]jsonDimension `json:"dimensions"`
+	Results       []jsonResult             `json:"results"`
+	Refs          []jsonRef                `json:"refs"`
+	Drift         jsonDrift                `json:"drift"`
+	Aggregate     jsonAggregate            `json:"aggregate"`
+	Attention     jsonAttention            `json:"attention"`
+}
+
+type jsonFormat struct {
+	Name             string   `json:"name"`
+	Agent            string   `json:"agent"`
+	Globs            []string `json:"globs"`                      // frontmatter globs the file applies to
+	FrontmatterError string   `json:"frontmatterError,omitempty"` // why the frontmatter didn't parse
+}
+
+type jsonProfile struct {
+	Name                 string `json:"name"`
+	Agent                string `json:"agent"`
+	BaselineInstructions int    `json:"baselineInstructions"`
+	BaselineTokens       int    `json:"baselineTokens"`
+	MaxInstructions      int    `json:"maxInstructions"`
+	WarnInstructions     int    `json:"warnInstructions"`
+}
+
+type jsonMetrics struct {
+	Lines                   int           `json:"lines"`
+	Instructions            int           `json:"instructions"`
+	EffectiveInstructions   int           `json:"effectiveInstructions"` // instructions plus the profile's baseline
+	Tokens                  int           `json:"tokens"`
+	EffectiveTokens         int           `json:"effectiveTokens"` // tokens plus the profile's baseline
+	Tokenizer               string        `json:"tokenizer"`
+	Imported                jsonImported  `json:"imported"`
+	ProgressiveDisclosure   bool          `json:"progressiveDisclosure"`
+	DetectedStacks          []string      `json:"detectedStacks"`
+	ScopeCommitsSinceUpdate int           `json:"scopeCommitsSinceUpdate"`
+	DaysSinceUpdate         int           `json:"daysSinceUpdate"`
+	Sections                []jsonSection `json:"sections"`
+}
+
+// jsonImported is the part of the metrics that comes from @imports
+type jsonImported struct {
+	Files        int `json:"files"`
+	Lines        int `json:"lines"`
+	Instructions int `json:"instructions"`
+	Tokens       int `json:"tokens"`
+}
+
+type jsonSection struct {
+	Title        string `json:"title"`
+	Level        int    `json:"level"`
+	Line         int    `json:"line"`
+	Lines        int    `json:"lines"`
+	Instructions int    `json:"instructions"`
+	Tokens       int    `json:"tokens"`
+}
+
+type jsonAttention struct {
+	Risk     int                     `json:"risk"`
+	Critical int                     `json:"critical"`
+	Buried   []jsonBuriedInstruction `json:"buried"`
+}
+
+type jsonBuriedInstruction struct {
+	File     string  `json:"file"`
+	Line     int     `json:"line"`
+	Text     string  `json:"text"`
+	Position float64 `json:"position"`
+}
+
+// jsonDrift is the mentioned code that changed since the file's last commit
+type jsonDrift struct {
+	Since   string            `json:"since"` // the file's last commit, empty outside git
+	Commits int               `json:"commits"`
+	Paths   []jsonDriftedPath `json:"paths"`
+}
+
+type jsonDriftedPath struct {
+	Path      string         `json:"path"`
+	Status    string         `json:"status"`
+	Commits   int            `json:"commits"`
+	RenamedTo string         `json:"renamedTo,omitempty"`
+	Locations []jsonLocation `json:"locations"`
+}
+
+type jsonDimension struct {
+	Score      int `json:"score"`
+	Violations int `json:"violations"`
+	Bonuses    int `json:"bonuses"`
+}
+
+type jsonResult struct {
+	Code        string         `json:"code"`
+	Description string         `json:"description"`
+	Severity    string         `json:"severity"`
+	Category    string         `json:"category"`
+	Dimension   string         `json:"dimension"`
+	Detected    bool           `json:"detected"`
+	Message     string         `json:"message"`
+	Suggestion  string         `json:"suggestion,omitempty"`
+	Links       []string       `json:"links,omitempty"`
+	Locations   []jsonLocation `json:"locations"`
+
+	Suppressed          bool           `json:"suppressed"`
+	SuppressedLocations []jsonLocation `json:"suppressedLocations"`
+	Baselined           bool           `json:"baselined"`
+	BaselinedLocations  []jsonLocation `json:"baselinedLocations"`
+}
+
+type jsonLocation struct {
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Text    string `json:"text"`
+	Snippet string `json:"snippet"`
+}
+
+type jsonRef struct {
+	Path            string       `json:"path"`
+	Kind            string       `json:"kind"`
+	Eager           bool         `json:"eager"`
+	ReferencedBy    string       `json:"referencedBy"`
+	Depth           int          `json:"depth"`
+	Exists          bool         `json:"exists"`
+	Stale           bool         `json:"stale"`
+	DaysSinceUpdate int          `json:"daysSinceUpdate"`
+	Results         []jsonResult `json:"results"`
+	Children        []jsonRef    `json:"children"`
+}
+
+type jsonAggregate struct {
+	FileCount         int             `json:"fileCount"`
+	TotalLines        int             `json:"totalLines"`
+	TotalInstructions int             `json:"totalInstructions"`
+	TotalTokens       int             `json:"totalTokens"`
+	Duplicates        []jsonDuplicate `json:"duplicates"`
+	Conflicts         []jsonConflict  `json:"conflicts"`
+}
+
+type jsonDuplicate struct {
+	Instruction  string   `json:"instruction"`
+	Files        []string `json:"files"`
+	Instructions []string `json:"instructions"`
+	Similarity   float64  `json:"similarity"`
+}
+
+type jsonConflict struct {
+	Subject string                  `json:"subject"`
+	First   jsonInstructionLocation `json:"first"`
+	Second  jsonInstructionLocation `json:"second"`
+}
+
+type jsonInstructionLocation struct {
+	File string `json:"file"`
+	Line int    `json:"line"`
+	Text string `json:"text"`
+}
+
+type jsonRepo struct {
+	Dir      string            `json:"dir"`
+	Findings []jsonRepoFinding `json:"findings"`
+	Scopes   []jsonScopeLoad   `json:"scopes"`
+	Orphans  []string          `json:"orphans"`
+	Totals   jsonRepoTotals    `json:"totals"`
+	AvgScore int               `json:"avgScore"`
+}
+
+type jsonRepoFinding struct {
+	Code     string   `json:"code"`
+	Severity string   `json:"severity"`
+	Message  string   `json:"message"`
+	Files    []string `json:"files"`
+	Details  []string `json:"details"`
+	Penalty  int      `json:"penalty"`
+}
+
+// jsonScopeLoad is what an agent working in a context file's directory loads
+type jsonScopeLoad struct {
+	File         string   `json:"file"`
+	Chain        []string `json:"chain"`
+	Instructions int      `json:"instructions"`
+	Tokens       int      `json:"tokens"`
+}
+
+// jsonEffective is what an agent loads in a directory (effective command)
+type jsonEffective struct {
+	Dir       string           `json:"dir"`
+	Agent     string           `json:"agent"`
+	Files     []jsonLoadedFile `json:"files"`
+	Aggregate jsonAggregate    `json:"aggregate"`
+}
+
+type jsonLoadedFile struct {
+	Path         string `json:"path"`
+	Source       string `json:"source"`
+	ImportedBy   string `json:"importedBy,omitempty"`
+	Lines        int    `json:"lines"`
+	Instructions int    `json:"instructions"`
+	Tokens       int    `json:"tokens"`
+}
+
+// jsonDiff compares the files with a base revision (diff command); files
+// holds their working tree reports
+type jsonDiff struct {
+	Base   string         `json:"base"`
+	Commit string         `json:"commit"`
+	Files  []jsonFileDiff `json:"files"`
+}
+
+type jsonFileDiff struct {
+	Path              string                    `json:"path"`
+	Added             bool                      `json:"added"` // the file doesn't exist at the base revision
+	Score             jsonScoreDelta            `json:"score"`
+	Dimensions        map[string]jsonScoreDelta `json:"dimensions"`
+	New               []jsonDiffFinding         `json:"new"`
+	Fixed             []jsonDiffFinding         `json:"fixed"` // located in the base revision
+	Unchanged         []jsonDiffFinding         `json:"unchanged"`
+	AddedInstructions []jsonInstructionLocation `json:"addedInstructions"`
+}
+
+type jsonScoreDelta struct {
+	Base  int `json:"base"`
+	Head  int `json:"head"`
+	Delta int `json:"delta"`
+}
+
+type jsonDiffFinding struct {
+	Code      string        `json:"code"`
+	Severity  string        `json:"severity"`
+	Dimension string        `json:"dimension"`
+	Message   string        `json:"message"`
+	File      string        `json:"file"`
+	Location  *jsonLocation `json:"location"` // null for file-level findings
+}
+
+type jsonRepoTotals struct {
+	Files        int `json:"files"`
+	Lines        int `json:"lines"`
+	Instructions int `json:"instructions"`
+	Tokens       int `json:"tokens"`
+	Errors       int `json:"errors"`
+	Warnings     int `json:"warnings"`
+}
+
+func newJSONReport(mode string) *jsonReport {
+	return &jsonReport{
+		SchemaVersion: jsonSchemaVersion,
+		Tool:          jsonTool{Name: "context-doctor", Version: Version},
+		Mode:          mode,
+		Files:         []jsonFile{},
+	}
+}
+
+// newJSONFileReport builds the JSON document for single-file mode
+func newJSONFileReport(fa *fileAnalysis, filterOpts rules.FilterOptions) *jsonReport {
+	report := newJSONReport("file")
+	report.Files = append(report.Files, toJSONFile(fa, fa.FilePath, filterOpts))
+	return report
+}
+
+// newJSONEffectiveReport builds the JSON document for the effective command
+func newJSONEffectiveReport(ec *rules.EffectiveContext, home string) *jsonReport {
+	report := newJSONReport("effective")
+	eff := &jsonEffective{
+		Dir:       ec.Dir,
+		Agent:     ec.Agent,
+		Files:     []jsonLoadedFile{},
+		Aggregate: toJSONAggregate(ec.Aggregate),
+	}
+	for _, f := range ec.Files {
+		lf := jsonLoadedFile{
+			Path:         effectivePath(ec.Dir, home, f.Path),
+			Source:       string(f.Source),
+			Lines:        f.Context.LineCount,
+			Instructions: f.Context.InstructionCount,
+			Tokens:       f.Context.TokenCount,
+		}
+		if f.ImportedBy != "" {
+			lf.ImportedBy = effectivePath(ec.Dir, home, f.ImportedBy)
+		}
+		eff.Files = append(eff.Files, lf)
+	}
+	report.Effective = eff
+	return report
+}
+
+// newJSONDiffReport builds the JSON document for the diff command
+func newJSONDiffReport(report *diffReport, filterOpts rules.FilterOptions) *jsonReport {
+	out := newJSONReport("diff")
+	diff := &jsonDiff{Base: report.Base, Commit: report.Commit, Files: []jsonFileDiff{}}
+	for _, fd := range report.Files {
+		out.Files = append(out.Files, toJSONFile(fd.Head, fd.Path, filterOpts))
+
+		base, head := fd.scoreDelta()
+		jd := jsonFileDiff{
+			Path:              fd.Path,
+			Added:             fd.Base == nil,
+			Score:             jsonScoreDelta{Base: base, Head: head, Delta: head - base},
+			Dimensions:        map[string]jsonScoreDelta{},
+			New:               toJSONDiffFindings(fd.New),
+			Fixed:             toJSONDiffFindings(fd.Fixed),
+			Unchanged:         toJSONDiffFindings(fd.Unchanged),
+			AddedInstructions: []jsonInstructionLocation{},
+		}
+		for _, dim := range rules.AllDimensions() {
+			base, head := fd.dimensionDelta(dim)
+			jd.Dimensions[string(dim)] = jsonScoreDelta{Base: base, Head: head, Delta: head - base}
+		}
+		for _, in := range fd.AddedInstructions {
+			jd.AddedInstructions = append(jd.AddedInstructions, jsonInstructionLocation{File: in.File, Line: in.Span.Line, Text: in.Span.Text})
+		}
+		diff.Files = append(diff.Files, jd)
+	}
+	out.Diff = diff
+	return out
+}
+
+func toJSONDiffFindings(findings []diffFinding) []jsonDiffFinding {
+	out := []jsonDiffFinding{}
+	for _, f := range findings {
+		jf := jsonDiffFinding{
+			Code:      f.Rule.Code,
+			Severity:  string(f.Rule.Severity),
+			Dimension: string(rules.ResolveDimension(f.Rule)),
+			Message:   f.Rule.ErrorMessage,
+			File:      f.File,
+		}
+		if s := f.Span; s != nil {
+			jf.Location = &jsonLocation{Line: s.Line, Column: s.Column, Text: s.Text, Snippet: s.Snippet}
+		}
+		out = append(out, jf)
+	}
+	return out
+}
+
+// newJSONRepoReport builds the JSON document for repository mode
+func newJSONRepoReport(ra *repoAnalysis, filterOpts rules.FilterOptions) *jsonReport {
+	report := newJSONReport("repo")
+	for _, fa := range ra.Analyses {
+		report.Files = append(report.Files, toJSONFile(fa, relPath(ra.Dir, fa.FilePath), filterOpts))
+	}
+
+	repo := &jsonRepo{
+		Dir:      ra.Dir,
+		Findings: []jsonRepoFinding{},
+		Scopes:   []jsonScopeLoad{},
+		Orphans:  nonNilStrings(ra.Orphans),
+		Totals: jsonRepoTotals{
+			Files:        len(ra.Analyses),
+			Lines:        ra.TotalLines,
+			Instructions: ra.TotalInstructions,
+			Tokens:       ra.TotalTokens,
+			Errors:       ra.TotalErrors,
+			Warnings:     ra.TotalWarnings,
+		},
+		AvgScore: ra.AvgScore,
+	}
+	for _, f := range ra.Findings {
+		repo.Findings = append(repo.Findings, jsonRepoFinding{
+			Code:     f.Code,
+			Severity: string(f.Severity),
+			Message:  f.Message,
+			Files:    nonNilStrings(f.Files),
+			Details:  nonNilStrings(f.Details),
+			Penalty:  f.Penalty,
+		})
+	}
+	if ra.Hierarchy != nil {
+		for _, l := range ra.Hierarchy.Loads {
+			repo.Scopes = append(repo.Scopes, jsonScopeLoad{
+				File:         l.File,
+				Chain:        l.Chain,
+				Instructions: l.Instructions,
+				Tokens:       l.Tokens,
+			})
+		}
+	}
+	report.Repo = repo
+
+	return report
+}
+
+func toJSONFormat(ctx *rules.AnalysisContext) jsonFormat {
+	format := rules.FormatOf(ctx)
+	jf := jsonFormat{
+		Name:  format.Name,
+		Agent: format.Agent,
+		Globs: []string{},
+	}
+	if fm := ctx.Frontmatter; fm != nil {
+		if fm.Err != nil {
+			jf.FrontmatterError = fm.Err.Error()
+		}
+		jf.Globs = append(jf.Globs, fm.Globs...)
+	}
+	return jf
+}
+
+func toJSONFile(fa *fileAnalysis, path string, filterOpts rules.FilterOptions) jsonFile {
+	ctx := fa.Ctx
+
+	jf := jsonFile{
+		Path:          path,
+		Format:        toJSONFormat(ctx),
+		Profile:       jsonProfile(fa.Profile),
+		Score:         fa.Score,
+		Errors:        fa.Errors,
+		Warnings:      fa.Warnings,
+		FreshnessDays: fa.FreshnessDays,
+		Metrics: jsonMetrics{
+			Lines:                 ctx.LineCount,
+			Instructions:          ctx.InstructionCount,
+			EffectiveInstructions: ctx.InstructionCount + fa.Profile.BaselineInstructions,
+			Tokens:                ctx.TokenCount,
+			EffectiveTokens:       ctx.TokenCount + fa.Profile.BaselineTokens,
+			Tokenizer:             rules.ActiveTokenizer().Name(),
+			Imported:              jsonImported(ctx.Imported),
+			DetectedStacks:        []string{},
+			DaysSinceUpdate:       -1,
+			Sections:              toJSONSections(ctx.Sections),
+		},
+		Dimensions: make(map[string]jsonDimension),
+		Results:    toJSONResults(fa.Results, filterOpts),
+		Refs:       toJSONRefs(fa.Refs, fa.RefResults, filterOpts),
+		Aggregate:  toJSONAggregate(fa.AggMetrics),
+		Drift:      toJSONDrift(fa.Drift, ctx),
+	}
+
+	if pd, ok := ctx.Metrics["hasProgressiveDisclosure"].(bool); ok {
+		jf.Metrics.ProgressiveDisclosure = pd
+	}
+	if stacks, ok := ctx.Metrics["detected_stacks"].([]string); ok {
+		jf.Metrics.DetectedStacks = stacks
+	}
+	if commits, ok := ctx.Metrics["scope_commits_since_update"].(int); ok {
+		jf.Metrics.ScopeCommitsSinceUpdate = commits
+	}
+	if days, ok := ctx.Metrics["claude_md_days_since_update"].(int); ok {
+		jf.Metrics.DaysSinceUpdate = days
+	}
+
+	if fa.DimensionScores != nil {
+		for dim, entry := range fa.DimensionScores.Scores {
+			jf.Dimensions[string(dim)] = jsonDimension{
+				Score:      entry.Score,
+				Violations: entry.Violations,
+				Bonuses:    entry.Bonuses,
+			}
+		}
+	}
+
+	jf.Attention.Buried = []jsonBuriedInstruction{}
+	if pa := fa.Positions; pa != nil {
+		jf.Attention.Risk = pa.AttentionRisk
+		jf.Attention.Critical = len(pa.Critical)
+		for _, b := range pa.Buried {
+			jf.Attention.Buried = append(jf.Attention.Buried, jsonBuriedInstruction{
+				File:     b.File,
+				Line:     b.Line,
+				Text:     b.Text,
+				Position: b.Position,
+			})
+		}
+	}
+
+	return jf
+}
+
+func toJSONAggregate(agg rules.AggregateMetrics) jsonAggregate {
+	out := jsonAggregate{
+		FileCount:         agg.FileCount,
+		TotalLines:        agg.TotalLineCount,
+		TotalInstructions: agg.TotalInstructionCount,
+		TotalTokens:       agg.TotalTokenCount,
+		Duplicates:        []jsonDuplicate{},
+		Conflicts:         []jsonConflict{},
+	}
+	for _, dup := range agg.Duplicates {
+		out.Duplicates = append(out.Duplicates, jsonDuplicate{
+			Instruction:  dup.Instruction,
+			Files:        nonNilStrings(dup.Files),
+			Instructions: nonNilStrings(dup.Instructions),
+			Similarity:   dup.Similarity,
+		})
+	}
+	for _, c := range agg.Conflicts {
+		out.Conflicts = append(out.Conflicts, jsonConflict{
+			Subject: c.Subject,
+			First:   toJSONInstructionLocation(c.First),
+			Second:  toJSONInstructionLocation(c.Second),
+		})
+	}
+	return out
+}
+
+func toJSONInstructionLocation(loc rules.InstructionLocation) jsonInstructionLocation {
+	return jsonInstructionLocation{File: loc.File, Line: loc.Line, Text: loc.Text}
+}
+
+func toJSONResults(results []rules.RuleResult, filterOpts rules.FilterOptions) []jsonResult {
+	out := []jsonResult{}
+	for _, r := range rules.FilterResults(results, filterOpts) {
+		out = append(out, jsonResult{
+			Code:        r.Rule.Code,
+			Description: r.Rule.Description,
+			Severity:    string(r.Rule.Severity),
+			Category:    r.Rule.Category,
+			Dimension:   string(rules.ResolveDimension(r.Rule)),
+			Detected:    r.Passed,
+			Message:     r.Rule.ErrorMessage,
+			Suggestion:  r.Rule.Suggestion,
+			Links:       r.Rule.Links,
+			Locations:   toJSONLocations(r.Spans),
+
+			Suppressed:          r.Suppressed,
+			SuppressedLocations: toJSONLocations(r.SuppressedSpans),
+			Baselined:           r.Baselined,
+			BaselinedLocations:  toJSONLocations(r.BaselinedSpans),
+		})
+	}
+	return out
+}
+
+// toJSONSections flattens the heading tree in document order
+func toJSONSections(root *rules.Section) []jsonSection {
+	sections := []jsonSection{}
+	if root == nil {
+		return sections
+	}
+	root.Walk(func(s *rules.Section) {
+		if s.Level == 0 {
+			return
+		}
+		sections = append(sections, jsonSection{
+			Title:        s.Title,
+			Level:        s.Level,
+			Line:         s.Line,
+			Lines:        s.LineCount,
+			Instructions: s.InstructionCount,
+			Tokens:       s.TokenCount,
+		})
+	})
+	return sections
+}
+
+func toJSONLocations(spans []rules.Span) []jsonLocation {
+	out := []jsonLocation{}
+	for _, s := range spans {
+		out = append(out, jsonLocation{Line: s.Line, Column: s.Column, Text: s.Text, Snippet: s.Snippet})
+	}
+	return out
+}
+
+func toJSONRefs(refs []rules.RefInfo, refResults map[string][]rules.RuleResult, filterOpts rules.FilterOptions) []jsonRef {
+	out := []jsonRef{}
+	for _, ref := range refs {
+		out = append(out, jsonRef{
+			Path:            ref.Path,
+			Kind:            string(ref.Kind),
+			Eager:           ref.Eager,
+			ReferencedBy:    ref.ReferencedBy,
+			Depth:           ref.Depth,
+			Exists:          ref.Exists,
+			Stale:           ref.IsStale,
+			DaysSinceUpdate: ref.DaysSinceUpdate,
+			Results:         toJSONResults(refResults[ref.Path], filterOpts),
+			Children:        toJSONRefs(ref.Children, refResults, filterOpts),
+		})
+	}
+	return out
+}
+
+func toJSONDrift(d *rules.DriftReport, ctx *rules.AnalysisContext) jsonDrift {
+	out := jsonDrift{Paths: []jsonDriftedPath{}}
+	if d == nil {
+		return out
+	}
+	out.Since = d.Since
+	out.Commits = d.Commits
+	for _, drift := range d.Drifted {
+		out.Paths = append(out.Paths, jsonDriftedPath{
+			Path:      drift.Path,
+			Status:    string(drift.Status),
+			Commits:   drift.Commits,
+			RenamedTo: drift.RenamedTo,
+			Locations: toJSONLocations(rules.MentionSpans(ctx, drift.Mentions)),
+		})
+	}
+	return out
+}
+
+// nonNilStrings ensures empty lists encode as [] rather than null
+func nonNilStrings(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
+
+// writeJSON encodes v as indented JSON
+func writeJSON(w io.Writer, v any) {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to encode JSON: %v\n", err)
+	}
+}
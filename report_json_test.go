@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"context-doctor/rules"
+)
+
+// sampleAnalysis builds a fileAnalysis without touching disk or git.
+func sampleAnalysis(path string) *fileAnalysis {
+	ctx := rules.BuildContext(path, "# Project\n\n- Always run make test\n")
+	results := []rules.RuleResult{
+		{Rule: rules.Rule{Code: "CD011", Severity: rules.SeverityWarning, Category: "linter-abuse",
+			ErrorMessage: "Line length rules found"}, Passed: true},
+		{Rule: rules.Rule{Code: "CD001", Severity: rules.SeverityError, Category: "length"}, Passed: false},
+	}
+	return &fileAnalysis{
+		FilePath:        path,
+		Ctx:             ctx,
+		Results:         results,
+		Refs:            []rules.RefInfo{{Path: "docs/missing.md", ReferencedBy: path}},
+		AggMetrics:      rules.AggregateMetrics{FileCount: 1, TotalLineCount: ctx.LineCount},
+		DimensionScores: rules.CalculateDimensionScores(results, 100),
+		FreshnessDays:   -1,
+		Score:           95,
+		Warnings:        1,
+	}
+}
+
+func TestIsValidFormat(t *testing.T) {
+	for _, f := range []string{"text", "json"} {
+		if !isValidFormat(f) {
+			t.Errorf("expected %q to be valid", f)
+		}
+	}
+	if isValidFormat("xml") {
+		t.Error("expected xml to be invalid")
+	}
+}
+
+func TestNewJSONFileReport(t *testing.T) {
+	fa := sampleAnalysis("CLAUDE.md")
+	report := newJSONFileReport(fa, rules.FilterOptions{FailuresOnly: true, HideGoodPractice: true})
+
+	if report.SchemaVersion != jsonSchemaVersion || report.Mode != "file" || report.Repo != nil {
+		t.Fatalf("unexpected envelope: %+v", report)
+	}
+	if len(report.Files) != 1 {
+		t.Fatalf("expected 1 file, got %d", len(report.Files))
+	}
+	jf := report.Files[0]
+	if jf.Score != 95 || jf.Warnings != 1 {
+		t.Errorf("unexpected score/warnings: %d/%d", jf.Score, jf.Warnings)
+	}
+	if len(jf.Results) != 1 || jf.Results[0].Code != "CD011" || !jf.Results[0].Detected {
+		t.Errorf("expected only detected CD011, got %+v", jf.Results)
+	}
+	if jf.Results[0].Dimension != "style" {
+		t.Errorf("expected resolved dimension, got %q", jf.Results[0].Dimension)
+	}
+	if jf.Dimensions["style"].Score != 95 || jf.Dimensions["style"].Violations != 1 {
+		t.Errorf("unexpected dimensions: %+v", jf.Dimensions)
+	}
+	if jf.Results[0].Locations == nil {
+		t.Error("expected locations to encode as [] not null")
+	}
+	if len(jf.Refs) != 1 || jf.Refs[0].Exists {
+		t.Errorf("unexpected refs: %+v", jf.Refs)
+	}
+	if s := jf.Metrics.Sections; len(s) != 1 || s[0].Title != "Project" || s[0].Instructions != 1 {
+		t.Errorf("unexpected sections: %+v", s)
+	}
+}
+
+func TestNewJSONRepoReport(t *testing.T) {
+	ra := &repoAnalysis{
+		Dir:      "/repo",
+		Analyses: []*fileAnalysis{sampleAnalysis("/repo/CLAUDE.md"), sampleAnalysis("/repo/pkg/CLAUDE.md")},
+		Findings: []repoFinding{{Code: "CD061", Severity: rules.SeverityError, Files: []string{"pkg/CLAUDE.md", "CLAUDE.md"}, Penalty: 15}},
+		AvgScore: 65,
+	}
+	report := newJSONRepoReport(ra, rules.FilterOptions{})
+
+	if report.Mode != "repo" || report.Repo == nil {
+		t.Fatalf("expected repo mode, got %+v", report)
+	}
+	if report.Files[1].Path != "pkg/CLAUDE.md" {
+		t.Errorf("expected repo-relative path, got %q", report.Files[1].Path)
+	}
+	if len(report.Repo.Findings) != 1 || report.Repo.Findings[0].Penalty != 15 {
+		t.Errorf("unexpected findings: %+v", report.Repo.Findings)
+	}
+	if report.Repo.Orphans == nil {
+		t.Error("expected orphans to encode as [] not null")
+	}
+}
+
+func TestWriteJSON_RoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	writeJSON(&buf, newJSONFileReport(sampleAnalysis("CLAUDE.md"), rules.FilterOptions{}))
+
+	var decoded map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if decoded["schemaVersion"].(float64) != jsonSchemaVersion {
+		t.Errorf("unexpected schemaVersion: %v", decoded["schemaVersion"])
+	}
+	file := decoded["files"].([]any)[0].(map[string]any)
+	if _, ok := file["aggregate"].(map[string]any)["duplicates"].([]any); !ok {
+		t.Error("expected duplicates to encode as an array")
+	}
+}
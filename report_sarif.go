@@ -0,0 +1,271 @@
+package main
+
+import (
+	"path/filepath"
+	"unicode/utf8"
+
+	"context-doctor/rules"
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	toolInfoURI  = "https://github.com/michal-franc/context-doctor"
+)
+
+// sarifLog is the root object of a SARIF 2.1.0 document
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool       sarifTool     `json:"tool"`
+	ColumnKind string        `json:"columnKind"`
+	Results    []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string             `json:"id"`
+	ShortDescription     sarifMessage       `json:"shortDescription"`
+	FullDescription      *sarifMessage      `json:"fullDescription,omitempty"`
+	Help                 *sarifMessage      `json:"help,omitempty"`
+	HelpURI              string             `json:"helpUri,omitempty"`
+	DefaultConfiguration sarifConfiguration `json:"defaultConfiguration"`
+	Properties           sarifProperties    `json:"properties"`
+}
+
+type sarifConfiguration struct {
+	Level string `json:"level"`
+}
+
+type sarifProperties struct {
+	Category  string   `json:"category,omitempty"`
+	Dimension string   `json:"dimension,omitempty"`
+	Links     []string `json:"links,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	RuleIndex int             `json:"ruleIndex"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifRegion struct {
+	StartLine   int           `json:"startLine"`
+	StartColumn int           `json:"startColumn"`
+	EndColumn   int           `json:"endColumn"`
+	Snippet     *sarifMessage `json:"snippet,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+// sarifBuilder accumulates rules and results for a single SARIF run
+type sarifBuilder struct {
+	filterOpts rules.FilterOptions
+	ruleIndex  map[string]int
+	rules      []sarifRule
+	results    []sarifResult
+}
+
+func newSARIFBuilder(filterOpts rules.FilterOptions) *sarifBuilder {
+	// SARIF only carries problems, so good practices and undetected rules are always dropped
+	filterOpts.FailuresOnly = true
+	filterOpts.HideGoodPractice = true
+	return &sarifBuilder{
+		filterOpts: filterOpts,
+		ruleIndex:  make(map[string]int),
+	}
+}
+
+// newSARIFFileReport builds the SARIF log for single-file mode
+func newSARIFFileReport(fa *fileAnalysis, filterOpts rules.FilterOptions) *sarifLog {
+	b := newSARIFBuilder(filterOpts)
+	b.addAnalysis(fa)
+	return b.log()
+}
+
+// newSARIFRepoReport builds the SARIF log for repository mode, including repo-level findings
+func newSARIFRepoReport(ra *repoAnalysis, filterOpts rules.FilterOptions) *sarifLog {
+	b := newSARIFBuilder(filterOpts)
+	for _, fa := range ra.Analyses {
+		b.addAnalysis(fa)
+	}
+	for _, f := range ra.Findings {
+		rule, ok := repoFindingRules[f.Code]
+		if !ok {
+			rule = rules.Rule{Description: f.Code, Severity: f.Severity}
+		}
+		rule.Code, rule.Category = f.Code, "repository"
+		idx := b.addRule(rule)
+		result := sarifResult{
+			RuleID:    f.Code,
+			RuleIndex: idx,
+			Level:     sarifLevel(f.Severity),
+			Message:   sarifMessage{Text: f.Message},
+			Locations: []sarifLocation{},
+		}
+		for _, path := range f.Files {
+			result.Locations = append(result.Locations, sarifFileLocation(filepath.Join(ra.Dir, path)))
+		}
+		b.results = append(b.results, result)
+	}
+	return b.log()
+}
+
+func (b *sarifBuilder) addAnalysis(fa *fileAnalysis) {
+	// Register every evaluated rule so the driver describes the full rule set
+	for _, r := range fa.Results {
+		b.addRule(r.Rule)
+	}
+	b.addResults(fa.FilePath, fa.Results)
+
+	for _, ref := range rules.FlattenRefs(fa.Refs) {
+		results, ok := fa.RefResults[ref.Path]
+		if !ok {
+			continue
+		}
+		b.addResults(ref.ResolvedPath, results)
+	}
+}
+
+func (b *sarifBuilder) addResults(path string, results []rules.RuleResult) {
+	for _, r := range rules.FilterResults(results, b.filterOpts) {
+		idx := b.addRule(r.Rule)
+		result := sarifResult{
+			RuleID:    r.Rule.Code,
+			RuleIndex: idx,
+			Level:     sarifLevel(r.Rule.Severity),
+			Message:   sarifMessage{Text: r.Rule.ErrorMessage},
+		}
+
+		// File-level findings (e.g. line count) point at the file itself;
+		// content matches become one result per location
+		if len(r.Spans) == 0 {
+			result.Locations = []sarifLocation{sarifFileLocation(path)}
+			b.results = append(b.results, result)
+			continue
+		}
+		for _, span := range r.Spans {
+			located := result
+			located.Locations = []sarifLocation{sarifSpanLocation(path, span)}
+			b.results = append(b.results, located)
+		}
+	}
+}
+
+// addRule registers rule metadata once and returns its index in the driver's rules array
+func (b *sarifBuilder) addRule(r rules.Rule) int {
+	if idx, ok := b.ruleIndex[r.Code]; ok {
+		return idx
+	}
+
+	sr := sarifRule{
+		ID:                   r.Code,
+		ShortDescription:     sarifMessage{Text: r.Description},
+		DefaultConfiguration: sarifConfiguration{Level: sarifLevel(r.Severity)},
+		Properties: sarifProperties{
+			Category:  r.Category,
+			Dimension: string(rules.ResolveDimension(r)),
+			Links:     r.Links,
+		},
+	}
+	if r.ErrorMessage != "" {
+		sr.FullDescription = &sarifMessage{Text: r.ErrorMessage}
+	}
+	if r.Suggestion != "" {
+		sr.Help = &sarifMessage{Text: r.Suggestion}
+	}
+	if len(r.Links) > 0 {
+		sr.HelpURI = r.Links[0]
+	}
+
+	idx := len(b.rules)
+	b.rules = append(b.rules, sr)
+	b.ruleIndex[r.Code] = idx
+	return idx
+}
+
+func (b *sarifBuilder) log() *sarifLog {
+	results := b.results
+	if results == nil {
+		results = []sarifResult{}
+	}
+	driverRules := b.rules
+	if driverRules == nil {
+		driverRules = []sarifRule{}
+	}
+
+	return &sarifLog{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs: []sarifRun{{
+			Tool: sarifTool{Driver: sarifDriver{
+				Name:           "context-doctor",
+				Version:        Version,
+				InformationURI: toolInfoURI,
+				Rules:          driverRules,
+			}},
+			ColumnKind: "unicodeCodePoints",
+			Results:    results,
+		}},
+	}
+}
+
+func sarifFileLocation(path string) sarifLocation {
+	return sarifLocation{PhysicalLocation: sarifPhysicalLocation{
+		ArtifactLocation: sarifArtifactLocation{URI: filepath.ToSlash(path)},
+	}}
+}
+
+func sarifSpanLocation(path string, span rules.Span) sarifLocation {
+	loc := sarifFileLocation(path)
+	loc.PhysicalLocation.Region = &sarifRegion{
+		StartLine:   span.Line,
+		StartColumn: span.Column,
+		EndColumn:   span.Column + utf8.RuneCountInString(span.Text),
+		Snippet:     &sarifMessage{Text: span.Snippet},
+	}
+	return loc
+}
+
+// sarifLevel maps rule severities to SARIF result levels
+func sarifLevel(severity rules.Severity) string {
+	switch severity {
+	case rules.SeverityError:
+		return "error"
+	case rules.SeverityWarning:
+		return "warning"
+	default:
+		return "note"
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"context-doctor/rules"
+)
+
+func TestSarifLevel(t *testing.T) {
+	tests := []struct {
+		severity rules.Severity
+		want     string
+	}{
+		{rules.SeverityError, "error"},
+		{rules.SeverityWarning, "warning"},
+		{rules.SeverityInfo, "note"},
+	}
+	for _, tc := range tests {
+		if got := sarifLevel(tc.severity); got != tc.want {
+			t.Errorf("sarifLevel(%s) = %q, want %q", tc.severity, got, tc.want)
+		}
+	}
+}
+
+func TestNewSARIFFileReport(t *testing.T) {
+	fa := sampleAnalysis("docs/CLAUDE.md")
+	fa.Refs = []rules.RefInfo{{Path: "guide.md", ResolvedPath: "docs/guide.md", Exists: true}}
+	fa.RefResults = map[string][]rules.RuleResult{
+		"guide.md": {{Rule: rules.Rule{Code: "CD012", Severity: rules.SeverityWarning, ErrorMessage: "Quote style rules found"}, Passed: true}},
+	}
+
+	log := newSARIFFileReport(fa, rules.FilterOptions{})
+	if log.Version != "2.1.0" || len(log.Runs) != 1 {
+		t.Fatalf("unexpected envelope: %+v", log)
+	}
+	run := log.Runs[0]
+
+	// CD011 and CD001 from the primary file, CD012 from the referenced doc
+	if len(run.Tool.Driver.Rules) != 3 {
+		t.Fatalf("expected 3 driver rules, got %d", len(run.Tool.Driver.Rules))
+	}
+	if len(run.Results) != 2 {
+		t.Fatalf("expected 2 failing results, got %d", len(run.Results))
+	}
+
+	primary := run.Results[0]
+	if primary.RuleID != "CD011" || primary.Level != "warning" {
+		t.Errorf("unexpected primary result: %+v", primary)
+	}
+	if run.Tool.Driver.Rules[primary.RuleIndex].ID != "CD011" {
+		t.Error("ruleIndex does not point at the matching rule")
+	}
+	if uri := primary.Locations[0].PhysicalLocation.ArtifactLocation.URI; uri != "docs/CLAUDE.md" {
+		t.Errorf("expected primary file location, got %q", uri)
+	}
+	if uri := run.Results[1].Locations[0].PhysicalLocation.ArtifactLocation.URI; uri != "docs/guide.md" {
+		t.Errorf("expected referenced doc location, got %q", uri)
+	}
+}
+
+func TestNewSARIFRepoReport_IncludesRepoFindings(t *testing.T) {
+	ra := &repoAnalysis{
+		Dir:      "repo",
+		Analyses: []*fileAnalysis{sampleAnalysis("repo/CLAUDE.md")},
+		Findings: []repoFinding{
+			{Code: "CD061", Severity: rules.SeverityError, Message: "web/CLAUDE.md contradicts 2 instruction(s) of CLAUDE.md",
+				Files: []string{"CLAUDE.md", "web/CLAUDE.md"}},
+			{Code: "CD061", Severity: rules.SeverityError, Message: "pkg/CLAUDE.md contradicts 1 instruction(s) of CLAUDE.md",
+				Files: []string{"CLAUDE.md", "pkg/AGENTS.md"}},
+		},
+	}
+
+	run := newSARIFRepoReport(ra, rules.FilterOptions{}).Runs[0]
+	last := run.Results[len(run.Results)-1]
+	if last.RuleID != "CD061" || last.Level != "error" {
+		t.Fatalf("expected CD061 repo finding, got %+v", last)
+	}
+	if len(last.Locations) != 2 || last.Locations[1].PhysicalLocation.ArtifactLocation.URI != "repo/pkg/AGENTS.md" {
+		t.Errorf("unexpected locations: %+v", last.Locations)
+	}
+
+	// The rule is described the same way whichever finding comes first
+	rule := run.Tool.Driver.Rules[last.RuleIndex]
+	if rule.ShortDescription.Text != "Nested context file contradicts an ancestor" || strings.Contains(rule.FullDescription.Text, "CLAUDE.md") {
+		t.Errorf("expected a fixed description for CD061, got %+v", rule)
+	}
+}
+
+func TestNewSARIFFileReport_OneResultPerSpan(t *testing.T) {
+	fa := sampleAnalysis("CLAUDE.md")
+	fa.Results[0].Spans = []rules.Span{
+		{Line: 3, Column: 3, Text: "max line length", Snippet: "- max line length 80"},
+		{Line: 7, Column: 1, Text: "maximum width", Snippet: "maximum width 100"},
+	}
+
+	run := newSARIFFileReport(fa, rules.FilterOptions{}).Runs[0]
+	if len(run.Results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(run.Results))
+	}
+	region := run.Results[0].Locations[0].PhysicalLocation.Region
+	if region == nil || region.StartLine != 3 || region.StartColumn != 3 || region.EndColumn != 18 {
+		t.Errorf("unexpected region: %+v", region)
+	}
+	if run.Results[1].Locations[0].PhysicalLocation.Region.StartLine != 7 {
+		t.Error("expected second result at line 7")
+	}
+}
@@ -36,6 +36,8 @@ func getMetricValue(ctx *AnalysisContext, metric MetricType) any {
 		return ctx.LineCount
 	case MetricInstructionCount:
 		return ctx.InstructionCount
+	case MetricTokenCount:
+		return ctx.TokenCount
 	case MetricContent:
 		return ctx.Content
 	default:
@@ -46,6 +48,40 @@ func getMetricValue(ctx *AnalysisContext, metric MetricType) any {
 	}
 }
 
+// absenceActions check that something is missing. Without nodes they see every
+// line, so a command that only appears in a code block still counts as mentioned.
+var absenceActions = map[CheckAction]bool{
+	ActionNotContains:   true,
+	ActionRegexNotMatch: true,
+	ActionNotPresent:    true,
+}
+
+// specContent returns the content a spec's patterns run against: the lines of
+// the markdown nodes it targets, or by default everything outside code and
+// HTML blocks for presence checks and every line for absence checks
+func specContent(ctx *AnalysisContext, spec *MatchSpec) string {
+	if ctx.Content == "" {
+		return ""
+	}
+	nodes := spec.Nodes
+	if len(nodes) == 0 && absenceActions[spec.Action] {
+		nodes = []NodeType{NodeAll}
+	}
+	content := markdownOf(ctx).Select(nodes)
+	if spec.Section != "" {
+		content = restrictToSections(ctx, content, spec.Section)
+	}
+	return content
+}
+
+// markdownOf returns ctx's parsed markdown, parsing it for contexts not made by BuildContext
+func markdownOf(ctx *AnalysisContext) *MarkdownDoc {
+	if ctx.Markdown == nil {
+		ctx.Markdown = ParseMarkdown(strings.Split(ctx.Content, "\n"))
+	}
+	return ctx.Markdown
+}
+
 // toInt converts any numeric value to int
 func toInt(v any) (int, bool) {
 	switch val := v.(type) {
@@ -71,6 +107,9 @@ func toString(v any) string {
 }
 
 func checkLessThan(ctx *AnalysisContext, spec *MatchSpec) bool {
+	if spec.Section != "" {
+		return len(matchingSections(ctx, spec, lessThan)) > 0
+	}
 	metricVal := getMetricValue(ctx, spec.Metric)
 	actual, ok := toInt(metricVal)
 	if !ok {
@@ -84,6 +123,9 @@ func checkLessThan(ctx *AnalysisContext, spec *MatchSpec) bool {
 }
 
 func checkGreaterThan(ctx *AnalysisContext, spec *MatchSpec) bool {
+	if spec.Section != "" {
+		return len(matchingSections(ctx, spec, greaterThan)) > 0
+	}
 	metricVal := getMetricValue(ctx, spec.Metric)
 	actual, ok := toInt(metricVal)
 	if !ok {
@@ -107,7 +149,7 @@ func checkNotEquals(ctx *AnalysisContext, spec *MatchSpec) bool {
 }
 
 func checkContains(ctx *AnalysisContext, spec *MatchSpec) bool {
-	content := ctx.Content
+	content := specContent(ctx, spec)
 	if spec.Metric != "" && spec.Metric != MetricContent {
 		content = toString(getMetricValue(ctx, spec.Metric))
 	}
@@ -131,7 +173,7 @@ func checkContains(ctx *AnalysisContext, spec *MatchSpec) bool {
 }
 
 func checkNotContains(ctx *AnalysisContext, spec *MatchSpec) bool {
-	content := ctx.Content
+	content := specContent(ctx, spec)
 	if spec.Metric != "" && spec.Metric != MetricContent {
 		content = toString(getMetricValue(ctx, spec.Metric))
 	}
@@ -155,7 +197,7 @@ func checkNotContains(ctx *AnalysisContext, spec *MatchSpec) bool {
 }
 
 func checkRegexMatch(ctx *AnalysisContext, spec *MatchSpec) bool {
-	content := ctx.Content
+	content := specContent(ctx, spec)
 	if spec.Metric != "" && spec.Metric != MetricContent {
 		content = toString(getMetricValue(ctx, spec.Metric))
 	}
@@ -191,17 +233,19 @@ func checkRegexNotMatch(ctx *AnalysisContext, spec *MatchSpec) bool {
 }
 
 func checkIsPresent(ctx *AnalysisContext, spec *MatchSpec) bool {
+	content := specContent(ctx, spec)
+
 	// Check if patterns exist in content
 	if len(spec.Patterns) > 0 {
 		for _, pattern := range spec.Patterns {
 			re, err := regexp.Compile("(?i)" + pattern)
 			if err != nil {
-				if strings.Contains(strings.ToLower(ctx.Content), strings.ToLower(pattern)) {
+				if strings.Contains(strings.ToLower(content), strings.ToLower(pattern)) {
 					return true
 				}
 				continue
 			}
-			if re.MatchString(ctx.Content) {
+			if re.MatchString(content) {
 				return true
 			}
 		}
@@ -210,7 +254,7 @@ func checkIsPresent(ctx *AnalysisContext, spec *MatchSpec) bool {
 
 	// Check single value
 	if spec.Value != nil {
-		return strings.Contains(ctx.Content, toString(spec.Value))
+		return strings.Contains(content, toString(spec.Value))
 	}
 
 	return false
@@ -0,0 +1,159 @@
+package rules
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// BaselineVersion is the on-disk format version of baseline files
+const BaselineVersion = 1
+
+// BaselineEntry identifies one accepted finding by rule, file and content
+type BaselineEntry struct {
+	Code        string `json:"code"`
+	File        string `json:"file"`
+	Fingerprint string `json:"fingerprint"` // empty for file-level findings
+}
+
+// Baseline is a recorded set of known findings that later runs hide
+type Baseline struct {
+	Version  int             `json:"version"`
+	Findings []BaselineEntry `json:"findings"`
+}
+
+// LoadBaseline reads a baseline file written by Save
+func LoadBaseline(path string) (*Baseline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read baseline: %w", err)
+	}
+
+	var b Baseline
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("failed to parse baseline %s: %w", path, err)
+	}
+	if b.Version != BaselineVersion {
+		return nil, fmt.Errorf("unsupported baseline version %d in %s (expected %d)", b.Version, path, BaselineVersion)
+	}
+	return &b, nil
+}
+
+// Save writes the baseline as indented JSON, sorted so diffs stay reviewable
+func (b *Baseline) Save(path string) error {
+	b.Version = BaselineVersion
+	sort.SliceStable(b.Findings, func(i, j int) bool {
+		x, y := b.Findings[i], b.Findings[j]
+		if x.File != y.File {
+			return x.File < y.File
+		}
+		if x.Code != y.Code {
+			return x.Code < y.Code
+		}
+		return x.Fingerprint < y.Fingerprint
+	})
+	if b.Findings == nil {
+		b.Findings = []BaselineEntry{}
+	}
+
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create baseline directory: %w", err)
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// Fingerprint returns a stable hash of a matched span. It ignores case,
+// surrounding whitespace and line position, so moving a line doesn't turn it
+// into a new finding but editing it does.
+func Fingerprint(span Span) string {
+	normalize := func(s string) string {
+		return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+	}
+	sum := sha256.Sum256([]byte(normalize(span.Text) + "\x00" + normalize(span.Snippet)))
+	return hex.EncodeToString(sum[:8])
+}
+
+// BaselineEntries returns an entry for every detected problem in results:
+// one per location, or a single file-level entry when the rule has none
+func BaselineEntries(file string, results []RuleResult) []BaselineEntry {
+	var entries []BaselineEntry
+	for _, r := range results {
+		if !r.Passed || r.Rule.Category == "good-practice" {
+			continue
+		}
+		if len(r.Spans) == 0 {
+			entries = append(entries, BaselineEntry{Code: r.Rule.Code, File: file})
+			continue
+		}
+		for _, span := range r.Spans {
+			entries = append(entries, BaselineEntry{Code: r.Rule.Code, File: file, Fingerprint: Fingerprint(span)})
+		}
+	}
+	return entries
+}
+
+// Filter hides findings of file that are recorded in the baseline. Each entry
+// hides at most one location, so a second copy of a known line is still new.
+// Results with no remaining locations no longer count as detected.
+func (b *Baseline) Filter(file string, results []RuleResult) []RuleResult {
+	if b == nil {
+		return results
+	}
+
+	known := make(map[BaselineEntry]int)
+	for _, e := range b.Findings {
+		if e.File == file {
+			known[e]++
+		}
+	}
+	consume := func(e BaselineEntry) bool {
+		if known[e] > 0 {
+			known[e]--
+			return true
+		}
+		return false
+	}
+
+	filtered := make([]RuleResult, len(results))
+	for i, r := range results {
+		filtered[i] = r
+		if !r.Passed || r.Rule.Category == "good-practice" {
+			continue
+		}
+
+		if len(r.Spans) == 0 {
+			if consume(BaselineEntry{Code: r.Rule.Code, File: file}) {
+				filtered[i].Passed = false
+				filtered[i].Baselined = true
+				filtered[i].Message = ""
+			}
+			continue
+		}
+
+		var kept, hidden []Span
+		for _, span := range r.Spans {
+			if consume(BaselineEntry{Code: r.Rule.Code, File: file, Fingerprint: Fingerprint(span)}) {
+				hidden = append(hidden, span)
+				continue
+			}
+			kept = append(kept, span)
+		}
+		filtered[i].Spans = kept
+		filtered[i].BaselinedSpans = hidden
+		if len(kept) == 0 {
+			filtered[i].Passed = false
+			filtered[i].Baselined = true
+			filtered[i].Message = ""
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,126 @@
+package rules
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func baselineResult(code string, spans ...Span) RuleResult {
+	return RuleResult{
+		Rule:   Rule{Code: code, Severity: SeverityWarning, Category: "linter-abuse"},
+		Passed: true,
+		Spans:  spans,
+	}
+}
+
+func TestFingerprint_IgnoresPositionAndWhitespace(t *testing.T) {
+	a := Fingerprint(Span{Line: 3, Column: 1, Text: "camelCase", Snippet: "- Use camelCase for variables"})
+	b := Fingerprint(Span{Line: 40, Column: 5, Text: "camelCase", Snippet: "  - use  camelCase for variables "})
+	if a != b {
+		t.Errorf("expected equal fingerprints, got %s and %s", a, b)
+	}
+
+	c := Fingerprint(Span{Text: "camelCase", Snippet: "- Use camelCase for functions"})
+	if a == c {
+		t.Error("expected an edited line to get a new fingerprint")
+	}
+}
+
+func TestBaseline_SaveLoadRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "baseline.json")
+	span := Span{Line: 1, Text: "80 characters", Snippet: "- max 80 characters"}
+
+	b := &Baseline{Findings: BaselineEntries("CLAUDE.md", []RuleResult{
+		baselineResult("CD011", span),
+		baselineResult("CD001"),
+		{Rule: Rule{Code: "CD099", Category: "good-practice"}, Passed: true},
+		{Rule: Rule{Code: "CD012"}, Passed: false},
+	})}
+	if err := b.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadBaseline(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Version != BaselineVersion {
+		t.Errorf("got version %d", loaded.Version)
+	}
+	if len(loaded.Findings) != 2 {
+		t.Fatalf("expected 2 entries (good-practice and undetected skipped), got %+v", loaded.Findings)
+	}
+	if loaded.Findings[0].Code != "CD001" || loaded.Findings[0].Fingerprint != "" {
+		t.Errorf("expected sorted file-level CD001 entry first, got %+v", loaded.Findings[0])
+	}
+	if loaded.Findings[1].Fingerprint != Fingerprint(span) {
+		t.Errorf("expected CD011 fingerprint, got %+v", loaded.Findings[1])
+	}
+}
+
+func TestLoadBaseline_RejectsUnknownVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "baseline.json")
+	b := &Baseline{}
+	if err := b.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadBaseline(path); err != nil {
+		t.Fatalf("expected current version to load, got %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"version": 99, "findings": []}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadBaseline(path); err == nil {
+		t.Error("expected an error for an unknown version")
+	}
+}
+
+func TestBaseline_Filter(t *testing.T) {
+	known := Span{Line: 8, Text: "80 characters", Snippet: "- max 80 characters"}
+	b := &Baseline{Findings: []BaselineEntry{
+		{Code: "CD011", File: "CLAUDE.md", Fingerprint: Fingerprint(known)},
+		{Code: "CD001", File: "CLAUDE.md"},
+	}}
+
+	t.Run("hides known locations and file-level findings", func(t *testing.T) {
+		moved := known
+		moved.Line = 20
+		got := b.Filter("CLAUDE.md", []RuleResult{baselineResult("CD011", moved), baselineResult("CD001")})
+
+		for _, r := range got {
+			if r.Passed || !r.Baselined {
+				t.Errorf("%s: expected baselined, got %+v", r.Rule.Code, r)
+			}
+		}
+		if len(got[0].BaselinedSpans) != 1 {
+			t.Errorf("expected the moved span to be hidden, got %+v", got[0])
+		}
+	})
+
+	t.Run("each entry hides one copy", func(t *testing.T) {
+		dup := known
+		dup.Line = 30
+		got := b.Filter("CLAUDE.md", []RuleResult{baselineResult("CD011", known, dup)})
+
+		if !got[0].Passed || len(got[0].Spans) != 1 || got[0].Spans[0].Line != 30 {
+			t.Errorf("expected the second copy to stay reported, got %+v", got[0])
+		}
+	})
+
+	t.Run("other files are unaffected", func(t *testing.T) {
+		got := b.Filter("docs/README.md", []RuleResult{baselineResult("CD001")})
+		if !got[0].Passed || got[0].Baselined {
+			t.Errorf("expected finding in another file to stay reported, got %+v", got[0])
+		}
+	})
+
+	t.Run("nil baseline is a no-op", func(t *testing.T) {
+		var none *Baseline
+		in := []RuleResult{baselineResult("CD001")}
+		if got := none.Filter("CLAUDE.md", in); !got[0].Passed {
+			t.Error("expected results unchanged")
+		}
+	})
+}
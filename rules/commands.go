@@ -0,0 +1,612 @@
+package rules
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DocumentedCommand is a command a context file tells the agent to run
+type DocumentedCommand struct {
+	Line   int    // 1-based line number
+	Column int    // 1-based column, counted in characters
+	Text   string // the command, e.g. "make lint"
+	Tool   string // the runner: make, npm, cargo, task, just or script
+	Name   string // what the runner must find: a target, script, alias, recipe or path
+}
+
+// DeadCommand is a documented command the repo can't run
+type DeadCommand struct {
+	DocumentedCommand
+	Reason string // e.g. `no Makefile target "lint"`
+}
+
+// commandSource lists the commands a runner can find in a directory
+type commandSource struct {
+	File string // display name of what is missing, e.g. "Makefile target"
+	// Load returns the names defined in dir. found is false when dir has no
+	// file for the runner; open is true when the names can't be listed
+	// exhaustively (e.g. Makefile pattern rules or Taskfile includes).
+	Load func(dir string) (names map[string]bool, found, open bool)
+}
+
+// commandSources are keyed by the stack (see DefaultStackMarkers) whose
+// marker files define the runner's commands
+var commandSources = map[string]commandSource{
+	"make":   {File: "Makefile target", Load: loadMakeTargets},
+	"nodejs": {File: "package.json script", Load: loadPackageScripts},
+	"rust":   {File: "Cargo alias", Load: loadCargoAliases},
+	"task":   {File: "Taskfile task", Load: loadTaskfileTasks},
+	"just":   {File: "justfile recipe", Load: loadJustRecipes},
+}
+
+// toolStacks maps each runner to the stack holding its commands
+var toolStacks = map[string]string{
+	"make":  "make",
+	"npm":   "nodejs",
+	"cargo": "rust",
+	"task":  "task",
+	"just":  "just",
+}
+
+// shellInfos are the fenced code info strings holding commands
+var shellInfos = map[string]bool{
+	"": true, "sh": true, "bash": true, "shell": true, "zsh": true,
+	"console": true, "shell-session": true, "terminal": true,
+}
+
+// inlineCodePattern matches `inline code`
+var inlineCodePattern = regexp.MustCompile("`([^`\n]+)`")
+
+// ExtractCommands finds the commands in ctx's shell code blocks and inline
+// code. Only invocations of a known runner or a repo script are returned.
+func ExtractCommands(ctx *AnalysisContext) []DocumentedCommand {
+	doc := markdownOf(ctx)
+	shellLines := make(map[int]bool)
+	doc.Walk(func(n *MarkdownNode) bool {
+		if n.Type == NodeCodeBlock && shellInfos[strings.ToLower(n.Info)] {
+			for l := n.Line; l <= n.EndLine; l++ {
+				shellLines[l] = true
+			}
+		}
+		return true
+	})
+
+	var cmds []DocumentedCommand
+	for i, line := range ctx.Lines {
+		lineNo := i + 1
+		switch {
+		case shellLines[lineNo]:
+			trimmed := strings.TrimSpace(line)
+			if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") || strings.HasPrefix(trimmed, "#") {
+				continue
+			}
+			start := strings.Index(line, trimmed)
+			if strings.HasPrefix(trimmed, "$ ") {
+				trimmed = trimmed[2:]
+				start += 2
+			}
+			cmds = append(cmds, parseCommandLine(line, lineNo, start, trimmed)...)
+		case doc.InNode(lineNo, NodeCodeBlock) || doc.InNode(lineNo, NodeHTMLBlock) || doc.InNode(lineNo, NodeFrontmatter):
+			continue
+		default:
+			for _, m := range inlineCodePattern.FindAllStringSubmatchIndex(line, -1) {
+				cmds = append(cmds, parseCommandLine(line, lineNo, m[2], line[m[2]:m[3]])...)
+			}
+		}
+	}
+	return cmds
+}
+
+// parseCommandLine splits a shell line at &&, ||, ; and | and returns the
+// segments that invoke a runner. start is the byte offset of text in line.
+func parseCommandLine(line string, lineNo, start int, text string) []DocumentedCommand {
+	var cmds []DocumentedCommand
+	offset := 0
+	for _, seg := range splitShell(text) {
+		idx := strings.Index(text[offset:], seg)
+		if idx < 0 {
+			continue
+		}
+		pos := offset + idx
+		offset = pos + len(seg)
+
+		tool, name, ok := parseCommand(strings.Fields(seg))
+		if !ok {
+			continue
+		}
+		cmds = append(cmds, DocumentedCommand{
+			Line:   lineNo,
+			Column: utf8.RuneCountInString(line[:start+pos]) + 1,
+			Text:   seg,
+			Tool:   tool,
+			Name:   name,
+		})
+	}
+	return cmds
+}
+
+// shellSeparators split a command line into single commands
+var shellSeparators = regexp.MustCompile(`\s*(?:&&|\|\||;|\|)\s*`)
+
+func splitShell(text string) []string {
+	var segs []string
+	for _, s := range shellSeparators.Split(text, -1) {
+		if s = strings.TrimSpace(s); s != "" {
+			segs = append(segs, s)
+		}
+	}
+	return segs
+}
+
+// parseCommand returns the runner and the name it must find, or false for
+// commands that aren't checked
+func parseCommand(args []string) (tool, name string, ok bool) {
+	// Skip leading VAR=value assignments
+	for len(args) > 0 && strings.Contains(args[0], "=") && !strings.HasPrefix(args[0], "-") {
+		args = args[1:]
+	}
+	if len(args) == 0 || strings.ContainsAny(strings.Join(args, " "), "$<>{}*") {
+		return "", "", false
+	}
+
+	switch args[0] {
+	case "make", "gmake":
+		return parseMake(args[1:])
+	case "npm":
+		return parseNPM(args[1:])
+	case "yarn", "pnpm":
+		return parseYarn(args[1:])
+	case "bun":
+		if len(args) > 2 && args[1] == "run" && !strings.ContainsAny(args[2], "./") {
+			return "npm", args[2], true
+		}
+	case "cargo":
+		if len(args) > 1 && !strings.HasPrefix(args[1], "-") && !strings.HasPrefix(args[1], "+") &&
+			!cargoSubcommands[args[1]] {
+			return "cargo", args[1], true
+		}
+	case "task", "just":
+		for _, a := range args[1:] {
+			if strings.HasPrefix(a, "-") {
+				return "", "", false // flags may pick another file or list recipes
+			}
+			if !strings.Contains(a, "=") {
+				return args[0], a, true
+			}
+		}
+	case "bash", "sh", "zsh", "python", "python3", "node", "ruby", "perl":
+		if len(args) > 1 && isScriptPath(args[1]) {
+			return "script", args[1], true
+		}
+	default:
+		if isScriptPath(args[0]) {
+			return "script", args[0], true
+		}
+	}
+	return "", "", false
+}
+
+func parseMake(args []string) (string, string, bool) {
+	for _, a := range args {
+		switch {
+		case a == "-C" || a == "-f" || strings.HasPrefix(a, "--directory") || strings.HasPrefix(a, "--file") || strings.HasPrefix(a, "-C") || strings.HasPrefix(a, "-f"):
+			return "", "", false // another Makefile
+		case strings.HasPrefix(a, "-") || strings.Contains(a, "="):
+			continue
+		default:
+			return "make", a, true
+		}
+	}
+	return "", "", false // the default goal
+}
+
+// npmScriptCommands are npm commands that run the script of the same name
+var npmScriptCommands = map[string]bool{"test": true, "t": true, "start": true, "stop": true, "restart": true}
+
+func parseNPM(args []string) (string, string, bool) {
+	for _, a := range args {
+		if a == "-w" || a == "--prefix" || strings.HasPrefix(a, "--workspace") {
+			return "", "", false
+		}
+	}
+	if len(args) == 0 {
+		return "", "", false
+	}
+	switch {
+	case args[0] == "run" || args[0] == "run-script" || args[0] == "rum" || args[0] == "urn":
+		if len(args) > 1 && !strings.HasPrefix(args[1], "-") {
+			return "npm", args[1], true
+		}
+	case npmScriptCommands[args[0]]:
+		name := args[0]
+		if name == "t" {
+			name = "test"
+		}
+		return "npm", name, true
+	}
+	return "", "", false
+}
+
+// yarnBuiltins are yarn and pnpm commands that aren't package scripts
+var yarnBuiltins = map[string]bool{
+	"add": true, "audit": true, "autoclean": true, "bin": true, "cache": true, "config": true,
+	"create": true, "dedupe": true, "dlx": true, "exec": true, "fetch": true, "global": true,
+	"help": true, "i": true, "import": true, "info": true, "init": true, "install": true,
+	"licenses": true, "link": true, "list": true, "ls": true, "login": true, "logout": true,
+	"node": true, "outdated": true, "owner": true, "pack": true, "patch": true, "prune": true,
+	"publish": true, "rebuild": true, "remove": true, "rm": true, "set": true, "setup": true,
+	"store": true, "tag": true, "team": true, "unlink": true, "up": true, "update": true,
+	"upgrade": true, "upgrade-interactive": true, "version": true, "versions": true, "why": true,
+	"workspace": true, "workspaces": true, "env": true, "plugin": true,
+}
+
+func parseYarn(args []string) (string, string, bool) {
+	for _, a := range args {
+		if a == "-w" || a == "-r" || a == "-C" || a == "--recursive" || strings.HasPrefix(a, "--filter") ||
+			strings.HasPrefix(a, "--cwd") || strings.HasPrefix(a, "--dir") || strings.HasPrefix(a, "--workspace") {
+			return "", "", false
+		}
+	}
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return "", "", false
+	}
+	if args[0] == "run" {
+		if len(args) > 1 && !strings.HasPrefix(args[1], "-") {
+			return "npm", args[1], true
+		}
+		return "", "", false
+	}
+	if yarnBuiltins[args[0]] || strings.ContainsAny(args[0], "./@") {
+		return "", "", false
+	}
+	return "npm", args[0], true
+}
+
+// cargoSubcommands are cargo's own commands and widely used extensions,
+// which don't need an alias
+var cargoSubcommands = map[string]bool{
+	"add": true, "b": true, "bench": true, "build": true, "c": true, "check": true, "clean": true,
+	"clippy": true, "config": true, "d": true, "doc": true, "fetch": true, "fix": true, "fmt": true,
+	"generate-lockfile": true, "help": true, "info": true, "init": true, "install": true,
+	"locate-project": true, "login": true, "logout": true, "metadata": true, "miri": true,
+	"new": true, "owner": true, "package": true, "pkgid": true, "publish": true, "r": true,
+	"remove": true, "report": true, "rm": true, "run": true, "rustc": true, "rustdoc": true,
+	"search": true, "t": true, "test": true, "tree": true, "uninstall": true, "update": true,
+	"vendor": true, "verify-project": true, "version": true, "yank": true,
+	// Extensions installed with cargo install
+	"audit": true, "binstall": true, "bloat": true, "criterion": true, "deny": true, "dist": true,
+	"edit": true, "expand": true, "flamegraph": true, "fuzz": true, "hack": true, "insta": true,
+	"llvm-cov": true, "machete": true, "make": true, "mutants": true, "nextest": true,
+	"outdated": true, "release": true, "semver-checks": true, "sort": true, "tarpaulin": true,
+	"udeps": true, "upgrade": true, "watch": true, "zigbuild": true,
+}
+
+// scriptExtensions mark a path argument as a script
+var scriptExtensions = map[string]bool{
+	".sh": true, ".bash": true, ".py": true, ".js": true, ".mjs": true, ".cjs": true,
+	".ts": true, ".rb": true, ".pl": true, ".ps1": true,
+}
+
+// isScriptPath reports whether arg names a script file in the repo
+func isScriptPath(arg string) bool {
+	if strings.HasPrefix(arg, "/") || strings.HasPrefix(arg, "~") || strings.HasPrefix(arg, "-") {
+		return false
+	}
+	if strings.HasPrefix(arg, "./") || strings.HasPrefix(arg, "../") {
+		return true
+	}
+	return strings.Contains(arg, "/") && scriptExtensions[filepath.Ext(arg)]
+}
+
+// ValidateCommands checks the commands ctx documents against the repo. dirs
+// are searched in order, e.g. the context file's directory and the repo
+// root; a command is alive when any of them defines it. Only the sources of
+// stacks detected in dirs are parsed.
+func ValidateCommands(ctx *AnalysisContext, dirs ...string) []DeadCommand {
+	cmds := ExtractCommands(ctx)
+	if len(cmds) == 0 {
+		return nil
+	}
+	dirs = uniqueDirs(dirs)
+
+	type loaded struct {
+		names       map[string]bool
+		found, open bool
+	}
+	cache := map[string]*loaded{}
+	lookup := func(stack string) *loaded {
+		if l, ok := cache[stack]; ok {
+			return l
+		}
+		l := &loaded{names: map[string]bool{}}
+		src := commandSources[stack]
+		for _, dir := range dirs {
+			if !hasStack(dir, stack) {
+				continue
+			}
+			names, found, open := src.Load(dir)
+			for n := range names {
+				l.names[n] = true
+			}
+			l.found = l.found || found
+			l.open = l.open || open
+		}
+		cache[stack] = l
+		return l
+	}
+
+	var dead []DeadCommand
+	for _, c := range cmds {
+		if c.Tool == "script" {
+			if !scriptExists(dirs, c.Name) {
+				dead = append(dead, DeadCommand{c, "no script " + c.Name})
+			}
+			continue
+		}
+
+		stack := toolStacks[c.Tool]
+		src := commandSources[stack]
+		l := lookup(stack)
+		switch {
+		case l.names[c.Name] || l.open:
+		case c.Tool == "cargo":
+			// Cargo needs no config file for its own commands; anything
+			// else must be an alias
+			dead = append(dead, DeadCommand{c, `no cargo command or alias "` + c.Name + `"`})
+		case !l.found:
+			dead = append(dead, DeadCommand{c, "no " + strings.Fields(src.File)[0] + " found"})
+		default:
+			dead = append(dead, DeadCommand{c, "no " + src.File + ` "` + c.Name + `"`})
+		}
+	}
+	return dead
+}
+
+// EnrichContextWithCommands records the dead commands as the
+// dead_command_count metric, located at each command
+func EnrichContextWithCommands(ctx *AnalysisContext, dead []DeadCommand) {
+	ctx.Metrics["dead_command_count"] = len(dead)
+	spans := make([]Span, 0, len(dead))
+	for _, d := range dead {
+		snippet := ""
+		if d.Line <= len(ctx.Lines) {
+			snippet = strings.TrimSpace(ctx.Lines[d.Line-1])
+		}
+		spans = append(spans, Span{Line: d.Line, Column: d.Column, Text: d.Text, Snippet: snippet})
+	}
+	if ctx.MetricSpans == nil {
+		ctx.MetricSpans = make(map[MetricType][]Span)
+	}
+	ctx.MetricSpans["dead_command_count"] = spans
+}
+
+func uniqueDirs(dirs []string) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, d := range dirs {
+		if d == "" {
+			continue
+		}
+		abs, err := filepath.Abs(d)
+		if err != nil {
+			abs = d
+		}
+		if !seen[abs] {
+			seen[abs] = true
+			out = append(out, abs)
+		}
+	}
+	return out
+}
+
+func hasStack(dir, stack string) bool {
+	for _, s := range DetectStacks(dir) {
+		if s == stack {
+			return true
+		}
+	}
+	return false
+}
+
+func scriptExists(dirs []string, path string) bool {
+	for _, dir := range dirs {
+		if _, err := activeSource.Stat(filepath.Join(dir, path)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// stackFile returns the first of a stack's marker files present in dir
+func stackFile(dir, stack string) string {
+	for _, sm := range DefaultStackMarkers() {
+		if sm.Name != stack || sm.IsDir {
+			continue
+		}
+		for _, m := range sm.Markers {
+			path := filepath.Join(dir, m)
+			if info, err := activeSource.Stat(path); err == nil && !info.IsDir() {
+				return path
+			}
+		}
+	}
+	return ""
+}
+
+// makeRulePattern matches a rule line's targets, but not variable
+// assignments like "X := y" or "X ::= y"
+var makeRulePattern = regexp.MustCompile(`^([^\s:#=][^:#=]*?)\s*::?(?:[^=]|$)`)
+
+// makeIncludePattern matches include directives with literal paths
+var makeIncludePattern = regexp.MustCompile(`^-?include\s+(.+)$`)
+
+func loadMakeTargets(dir string) (map[string]bool, bool, bool) {
+	path := stackFile(dir, "make")
+	if path == "" {
+		return nil, false, false
+	}
+	names := map[string]bool{}
+	open := false
+	seen := map[string]bool{}
+
+	var parse func(path string)
+	parse = func(path string) {
+		if seen[path] {
+			return
+		}
+		seen[path] = true
+		data, err := activeSource.ReadFile(path)
+		if err != nil {
+			return
+		}
+
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		for scanner.Scan() {
+			line := scanner.Text()
+			if strings.HasPrefix(line, "\t") {
+				continue // recipe
+			}
+			if m := makeIncludePattern.FindStringSubmatch(line); m != nil {
+				for _, inc := range strings.Fields(m[1]) {
+					if strings.ContainsAny(inc, "$*") {
+						open = true
+						continue
+					}
+					parse(filepath.Join(filepath.Dir(path), inc))
+				}
+				continue
+			}
+			m := makeRulePattern.FindStringSubmatch(line)
+			if m == nil {
+				continue
+			}
+			for _, target := range strings.Fields(m[1]) {
+				if strings.ContainsAny(target, "%$") {
+					open = true // pattern rules and computed targets match names we can't list
+					continue
+				}
+				names[target] = true
+			}
+		}
+	}
+	parse(path)
+	return names, true, open
+}
+
+func loadPackageScripts(dir string) (map[string]bool, bool, bool) {
+	data, err := activeSource.ReadFile(filepath.Join(dir, "package.json"))
+	if err != nil {
+		return nil, false, false
+	}
+	var pkg struct {
+		Scripts map[string]any `json:"scripts"`
+	}
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return nil, true, true // unreadable, so nothing can be ruled out
+	}
+	names := map[string]bool{}
+	for name := range pkg.Scripts {
+		names[name] = true
+	}
+	return names, true, false
+}
+
+func loadCargoAliases(dir string) (map[string]bool, bool, bool) {
+	names := map[string]bool{}
+	found := false
+	for _, name := range []string{".cargo/config.toml", ".cargo/config"} {
+		data, err := activeSource.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		found = true
+		inAlias := false
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if strings.HasPrefix(line, "[") {
+				inAlias = line == "[alias]"
+				continue
+			}
+			if key, _, ok := strings.Cut(line, "="); ok && inAlias {
+				names[strings.Trim(strings.TrimSpace(key), `"'`)] = true
+			}
+		}
+	}
+	return names, found, false
+}
+
+func loadTaskfileTasks(dir string) (map[string]bool, bool, bool) {
+	path := stackFile(dir, "task")
+	if path == "" {
+		return nil, false, false
+	}
+	data, err := activeSource.ReadFile(path)
+	if err != nil {
+		return nil, false, false
+	}
+	var tf struct {
+		Includes map[string]any `yaml:"includes"`
+		Tasks    map[string]struct {
+			Aliases []string `yaml:"aliases"`
+		} `yaml:"tasks"`
+	}
+	if err := yaml.Unmarshal(data, &tf); err != nil {
+		return nil, true, true
+	}
+	names := map[string]bool{}
+	for name, t := range tf.Tasks {
+		names[name] = true
+		for _, a := range t.Aliases {
+			names[a] = true
+		}
+	}
+	return names, true, len(tf.Includes) > 0
+}
+
+// justRecipePattern matches a recipe header, e.g. "test *args:" or "@build:",
+// and justAliasPattern an alias, e.g. "alias t := test"
+var (
+	justRecipePattern = regexp.MustCompile(`^@?([A-Za-z_][\w-]*)[^:=]*:(?:[^=]|$)`)
+	justAliasPattern  = regexp.MustCompile(`^alias\s+([A-Za-z_][\w-]*)\s*:=`)
+)
+
+func loadJustRecipes(dir string) (map[string]bool, bool, bool) {
+	path := stackFile(dir, "just")
+	if path == "" {
+		return nil, false, false
+	}
+	data, err := activeSource.ReadFile(path)
+	if err != nil {
+		return nil, false, false
+	}
+
+	names := map[string]bool{}
+	open := false
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t"):
+			continue // recipe body
+		case strings.HasPrefix(line, "import ") || strings.HasPrefix(line, "mod "):
+			open = true
+		case justAliasPattern.MatchString(line):
+			names[justAliasPattern.FindStringSubmatch(line)[1]] = true
+		case strings.HasPrefix(line, "set ") || strings.HasPrefix(line, "export "):
+			continue
+		default:
+			if m := justRecipePattern.FindStringSubmatch(line); m != nil {
+				names[m[1]] = true
+			}
+		}
+	}
+	return names, true, open
+}
@@ -0,0 +1,198 @@
+package rules
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// =============================================================================
+// ExtractCommands
+// =============================================================================
+
+func TestExtractCommands(t *testing.T) {
+	content := strings.Join([]string{
+		"# Project",
+		"",
+		"Run `make test` before pushing, and `npm run lint && npm test` for the UI.",
+		"",
+		"```bash",
+		"# build everything",
+		"$ make build",
+		"CGO_ENABLED=0 cargo xtask dist | tee out",
+		"./scripts/release.sh v1",
+		"```",
+		"",
+		"```go",
+		"make(chan int)",
+		"```",
+		"",
+		"- `go test ./...` and `make` are fine",
+	}, "\n")
+
+	var got []string
+	for _, c := range ExtractCommands(BuildContext("CLAUDE.md", content)) {
+		got = append(got, c.Tool+":"+c.Name)
+	}
+	want := []string{
+		"make:test", "npm:lint", "npm:test",
+		"make:build", "cargo:xtask", "script:./scripts/release.sh",
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestExtractCommands_Location(t *testing.T) {
+	cmds := ExtractCommands(BuildContext("CLAUDE.md", "Then run `npm run dev && make up`."))
+	if len(cmds) != 2 {
+		t.Fatalf("expected 2 commands, got %+v", cmds)
+	}
+	if cmds[1].Line != 1 || cmds[1].Column != 26 || cmds[1].Text != "make up" {
+		t.Errorf("unexpected location %+v", cmds[1])
+	}
+}
+
+func TestParseCommand(t *testing.T) {
+	tests := []struct {
+		cmd  string
+		want string // tool:name, or "" when unchecked
+	}{
+		{"make -j4 lint", "make:lint"},
+		{"make -C web build", ""},
+		{"make VERBOSE=1", ""},
+		{"npm run-script build", "npm:build"},
+		{"npm t", "npm:test"},
+		{"npm install", ""},
+		{"npm run build -w api", ""},
+		{"yarn typecheck", "npm:typecheck"},
+		{"pnpm add -D vitest", ""},
+		{"pnpm --filter web build", ""},
+		{"bun run dev", "npm:dev"},
+		{"cargo build --release", ""},
+		{"cargo nextest run", ""},
+		{"cargo xtask ci", "cargo:xtask"},
+		{"task test", "task:test"},
+		{"task --list", ""},
+		{"just fmt", "just:fmt"},
+		{"bash scripts/setup.sh", "script:scripts/setup.sh"},
+		{"python tools/gen.py", "script:tools/gen.py"},
+		{"go test ./...", ""},
+		{"make $(TARGET)", ""},
+	}
+	for _, tt := range tests {
+		tool, name, ok := parseCommand(strings.Fields(tt.cmd))
+		got := ""
+		if ok {
+			got = tool + ":" + name
+		}
+		if got != tt.want {
+			t.Errorf("parseCommand(%q) = %q, want %q", tt.cmd, got, tt.want)
+		}
+	}
+}
+
+// =============================================================================
+// ValidateCommands
+// =============================================================================
+
+func TestValidateCommands(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"Makefile":           ".PHONY: build test\nVERSION := 1.0\nbuild test: deps\n\tgo build ./...\ninclude tools.mk\n",
+		"tools.mk":           "lint:\n\tgolangci-lint run\n",
+		"package.json":       `{"scripts": {"dev": "vite", "test": "vitest"}}`,
+		"Cargo.toml":         "[package]\nname = \"x\"\n",
+		".cargo/config.toml": "[alias]\nxtask = \"run --package xtask --\"\n",
+		"Taskfile.yml":       "version: '3'\ntasks:\n  gen:\n    aliases: [g]\n    cmds: [echo]\n",
+		"justfile":           "set shell := [\"bash\", \"-c\"]\nalias f := fmt\n\n@fmt *args:\n    gofmt -w .\n",
+		"scripts/setup.sh":   "#!/bin/sh\n",
+	})
+
+	content := strings.Join([]string{
+		"```sh",
+		"make build && make lint && make release",
+		"npm run dev && npm run typecheck && npm test",
+		"cargo xtask && cargo bench && cargo deploy",
+		"task gen && task g && task clean",
+		"just f && just fmt && just ship",
+		"./scripts/setup.sh && ./scripts/teardown.sh",
+		"```",
+	}, "\n")
+
+	var dead []string
+	for _, d := range ValidateCommands(BuildContext(filepath.Join(dir, "CLAUDE.md"), content), dir) {
+		dead = append(dead, d.Text+" => "+d.Reason)
+	}
+	sort.Strings(dead)
+	want := []string{
+		`./scripts/teardown.sh => no script ./scripts/teardown.sh`,
+		`cargo deploy => no cargo command or alias "deploy"`,
+		`just ship => no justfile recipe "ship"`,
+		`make release => no Makefile target "release"`,
+		`npm run typecheck => no package.json script "typecheck"`,
+		`task clean => no Taskfile task "clean"`,
+	}
+	if strings.Join(dead, "\n") != strings.Join(want, "\n") {
+		t.Errorf("dead commands:\n%s\nwant:\n%s", strings.Join(dead, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestValidateCommands_MissingSource(t *testing.T) {
+	dir := t.TempDir()
+	ctx := BuildContext(filepath.Join(dir, "CLAUDE.md"), "Run `make lint` and `npm test`.")
+
+	dead := ValidateCommands(ctx, dir)
+	if len(dead) != 2 || dead[0].Reason != "no Makefile found" || dead[1].Reason != "no package.json found" {
+		t.Errorf("unexpected dead commands %+v", dead)
+	}
+}
+
+func TestValidateCommands_SearchesEveryDir(t *testing.T) {
+	root := t.TempDir()
+	pkg := filepath.Join(root, "web")
+	writeFiles(t, root, map[string]string{
+		"Makefile":         "ci:\n\ttrue\n",
+		"web/package.json": `{"scripts": {"build": "vite build"}}`,
+	})
+	ctx := BuildContext(filepath.Join(pkg, "CLAUDE.md"), "Run `npm run build`, then `make ci`.")
+
+	if dead := ValidateCommands(ctx, pkg, root); len(dead) != 0 {
+		t.Errorf("expected every command to be found, got %+v", dead)
+	}
+}
+
+func TestValidateCommands_PatternRulesAreOpen(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"Makefile": "build-%:\n\tgo build ./cmd/$*\n"})
+	ctx := BuildContext(filepath.Join(dir, "CLAUDE.md"), "Run `make build-api`.")
+
+	if dead := ValidateCommands(ctx, dir); len(dead) != 0 {
+		t.Errorf("expected a pattern rule to cover the target, got %+v", dead)
+	}
+}
+
+func TestDeadCommandRule_LocatesEachCommand(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"Makefile": "test:\n\tgo test ./...\n"})
+	content := "# Commands\n\n- `make test`\n- `make lint`\n- `make vet`\n"
+	ctx := BuildContext(filepath.Join(dir, "CLAUDE.md"), content)
+	EnrichContextWithCommands(ctx, ValidateCommands(ctx, dir))
+
+	builtin, err := LoadBuiltinRules()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rule := findRule(builtin, "CD080")
+	if rule == nil {
+		t.Fatal("CD080 not found")
+	}
+	res := NewEngine([]Rule{*rule}).Evaluate(ctx)[0]
+	if !res.Passed || len(res.Spans) != 2 {
+		t.Fatalf("expected CD080 with 2 locations, got passed=%v spans=%+v", res.Passed, res.Spans)
+	}
+	if res.Spans[0].Line != 4 || res.Spans[0].Text != "make lint" || res.Spans[1].Line != 5 {
+		t.Errorf("unexpected spans %+v", res.Spans)
+	}
+}
@@ -0,0 +1,251 @@
+package rules
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFileName is the project configuration file inside .context-doctor/
+const ConfigFileName = "config.yaml"
+
+// Config is a project's .context-doctor/config.yaml
+type Config struct {
+	Version  string                  `yaml:"version,omitempty"`
+	Options  map[string]any          `yaml:"options,omitempty"`  // default CLI options, keyed by flag name
+	Weights  map[Dimension]float64   `yaml:"weights,omitempty"`  // dimension weights for the overall score
+	Rules    map[string]RuleOverride `yaml:"rules,omitempty"`    // per-rule overrides, keyed by rule code
+	Profiles map[string]AgentProfile `yaml:"profiles,omitempty"` // agent profile settings, keyed by profile name
+}
+
+// RuleOverride changes a loaded rule without copying its definition
+type RuleOverride struct {
+	Enabled    *bool          `yaml:"enabled,omitempty"`    // false removes the rule
+	Severity   Severity       `yaml:"severity,omitempty"`   // replaces the rule's severity
+	Threshold  *int           `yaml:"threshold,omitempty"`  // replaces the rule's only numeric value
+	Thresholds map[string]int `yaml:"thresholds,omitempty"` // replaces numeric values by metric name
+}
+
+// LoadConfig reads and validates a config file
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+func (c *Config) validate() error {
+	known := make(map[Dimension]bool)
+	for _, d := range AllDimensions() {
+		known[d] = true
+	}
+	for dim, w := range c.Weights {
+		if !known[dim] {
+			return fmt.Errorf("unknown dimension %q in weights", dim)
+		}
+		if w < 0 {
+			return fmt.Errorf("weight for %s must not be negative", dim)
+		}
+	}
+	if len(c.Weights) > 0 {
+		merged := DefaultDimensionWeights()
+		for dim, w := range c.Weights {
+			merged[dim] = w
+		}
+		total := 0.0
+		for _, w := range merged {
+			total += w
+		}
+		if total == 0 {
+			return fmt.Errorf("dimension weights must not all be zero")
+		}
+	}
+
+	for code, o := range c.Rules {
+		switch o.Severity {
+		case "", SeverityError, SeverityWarning, SeverityInfo:
+		default:
+			return fmt.Errorf("rule %s: invalid severity %q (expected error, warning or info)", code, o.Severity)
+		}
+		if o.Threshold != nil && len(o.Thresholds) > 0 {
+			return fmt.Errorf("rule %s: set either threshold or thresholds, not both", code)
+		}
+	}
+
+	for name, p := range c.Profiles {
+		if p.BaselineInstructions < 0 || p.BaselineTokens < 0 || p.MaxInstructions < 0 || p.WarnInstructions < 0 {
+			return fmt.Errorf("profile %s: baselines and limits must not be negative", name)
+		}
+	}
+	return nil
+}
+
+// DimensionWeights returns the default weights with the configured ones
+// applied, normalised so they sum to 1
+func (c *Config) DimensionWeights() map[Dimension]float64 {
+	weights := DefaultDimensionWeights()
+	if c == nil || len(c.Weights) == 0 {
+		return weights
+	}
+
+	for dim, w := range c.Weights {
+		weights[dim] = w
+	}
+	total := 0.0
+	for _, w := range weights {
+		total += w
+	}
+	if total == 0 {
+		return DefaultDimensionWeights()
+	}
+	for dim := range weights {
+		weights[dim] /= total
+	}
+	return weights
+}
+
+// ApplyRules applies the rule overrides: disabled rules are dropped, and
+// severities and thresholds are replaced. Overrides for codes that aren't
+// loaded are ignored, since custom rules can differ between directories.
+func (c *Config) ApplyRules(rules []Rule) ([]Rule, error) {
+	if c == nil || len(c.Rules) == 0 {
+		return rules, nil
+	}
+
+	overrides := make(map[string]RuleOverride, len(c.Rules))
+	for code, o := range c.Rules {
+		overrides[strings.ToUpper(code)] = o
+	}
+
+	var out []Rule
+	for _, rule := range rules {
+		o, ok := overrides[strings.ToUpper(rule.Code)]
+		if !ok {
+			out = append(out, rule)
+			continue
+		}
+		if o.Enabled != nil && !*o.Enabled {
+			continue
+		}
+		if o.Severity != "" {
+			rule.Severity = o.Severity
+		}
+		if err := applyThresholds(&rule, o); err != nil {
+			return nil, err
+		}
+		out = append(out, rule)
+	}
+	return out, nil
+}
+
+// thresholdActions are the actions whose value is a numeric threshold
+var thresholdActions = map[CheckAction]bool{
+	ActionLessThan:    true,
+	ActionGreaterThan: true,
+	ActionEquals:      true,
+	ActionNotEquals:   true,
+}
+
+func applyThresholds(rule *Rule, o RuleOverride) error {
+	if o.Threshold == nil && len(o.Thresholds) == 0 {
+		return nil
+	}
+
+	// Copy the spec tree so overrides never leak into other rules sharing it
+	rule.MatchSpec = cloneSpec(rule.MatchSpec)
+	specs := thresholdSpecs(&rule.MatchSpec)
+
+	if o.Threshold != nil {
+		if len(specs) != 1 {
+			return fmt.Errorf("rule %s has %d thresholds; use thresholds with metric names (%s)",
+				rule.Code, len(specs), strings.Join(specMetrics(specs), ", "))
+		}
+		setThreshold(rule, specs[0], *o.Threshold)
+		return nil
+	}
+
+	metrics := make([]string, 0, len(o.Thresholds))
+	for m := range o.Thresholds {
+		metrics = append(metrics, m)
+	}
+	sort.Strings(metrics)
+
+	for _, metric := range metrics {
+		found := false
+		for _, spec := range specs {
+			if string(spec.Metric) == metric {
+				setThreshold(rule, spec, o.Thresholds[metric])
+				found = true
+			}
+		}
+		if !found {
+			return fmt.Errorf("rule %s has no threshold for metric %q (has: %s)",
+				rule.Code, metric, strings.Join(specMetrics(specs), ", "))
+		}
+	}
+	return nil
+}
+
+// thresholdSpecs returns the specs within spec that compare a metric to a number
+func thresholdSpecs(spec *MatchSpec) []*MatchSpec {
+	var specs []*MatchSpec
+	if thresholdActions[spec.Action] {
+		if _, ok := toInt(spec.Value); ok {
+			specs = append(specs, spec)
+		}
+	}
+	for i := range spec.SubMatch {
+		specs = append(specs, thresholdSpecs(&spec.SubMatch[i])...)
+	}
+	return specs
+}
+
+func specMetrics(specs []*MatchSpec) []string {
+	names := make([]string, len(specs))
+	for i, s := range specs {
+		names[i] = string(s.Metric)
+	}
+	return names
+}
+
+// setThreshold replaces a spec's value and the first mention of the old value
+// in the rule's message, so "more than 300 lines" keeps matching the check
+func setThreshold(rule *Rule, spec *MatchSpec, value int) {
+	old, _ := toInt(spec.Value)
+	spec.Value = value
+
+	re := regexp.MustCompile(`\b` + strconv.Itoa(old) + `\b`)
+	replaced := false
+	rule.ErrorMessage = re.ReplaceAllStringFunc(rule.ErrorMessage, func(s string) string {
+		if replaced {
+			return s
+		}
+		replaced = true
+		return strconv.Itoa(value)
+	})
+}
+
+func cloneSpec(spec MatchSpec) MatchSpec {
+	if len(spec.SubMatch) > 0 {
+		subs := make([]MatchSpec, len(spec.SubMatch))
+		for i, sub := range spec.SubMatch {
+			subs[i] = cloneSpec(sub)
+		}
+		spec.SubMatch = subs
+	}
+	return spec
+}
@@ -0,0 +1,187 @@
+package rules
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), ConfigFileName)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func findRule(rules []Rule, code string) *Rule {
+	for i := range rules {
+		if rules[i].Code == code {
+			return &rules[i]
+		}
+	}
+	return nil
+}
+
+// =============================================================================
+// LoadConfig
+// =============================================================================
+
+func TestLoadConfig_Valid(t *testing.T) {
+	cfg, err := LoadConfig(writeConfig(t, `
+version: "1"
+options:
+  stale-threshold: 60
+weights:
+  correctness: 0.5
+rules:
+  CD011:
+    enabled: false
+  CD013:
+    severity: info
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Options["stale-threshold"] != 60 {
+		t.Errorf("got options %v", cfg.Options)
+	}
+	if cfg.Rules["CD013"].Severity != SeverityInfo {
+		t.Errorf("got rules %+v", cfg.Rules)
+	}
+}
+
+func TestLoadConfig_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"unknown dimension", "weights:\n  speed: 1\n", "unknown dimension"},
+		{"negative weight", "weights:\n  style: -1\n", "must not be negative"},
+		{"all weights zero", "weights:\n  correctness: 0\n  style: 0\n  compliance: 0\n  freshness: 0\n", "all be zero"},
+		{"bad severity", "rules:\n  CD001:\n    severity: fatal\n", "invalid severity"},
+		{"threshold and thresholds", "rules:\n  CD001:\n    threshold: 1\n    thresholds:\n      lineCount: 2\n", "not both"},
+		{"malformed yaml", "rules: [", "failed to parse"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := LoadConfig(writeConfig(t, tc.content))
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+// =============================================================================
+// DimensionWeights
+// =============================================================================
+
+func TestConfig_DimensionWeights(t *testing.T) {
+	var none *Config
+	if got := none.DimensionWeights(); got[DimensionCorrectness] != 0.40 {
+		t.Errorf("nil config should use defaults, got %v", got)
+	}
+
+	cfg := &Config{Weights: map[Dimension]float64{DimensionStyle: 0}}
+	got := cfg.DimensionWeights()
+	if got[DimensionStyle] != 0 {
+		t.Errorf("style: got %v, want 0", got[DimensionStyle])
+	}
+	// Remaining defaults 0.4/0.2/0.2 are normalised to sum to 1
+	if got[DimensionCorrectness] != 0.5 || got[DimensionFreshness] != 0.25 {
+		t.Errorf("expected normalised weights, got %v", got)
+	}
+}
+
+// =============================================================================
+// ApplyRules
+// =============================================================================
+
+// twoThresholdRule is a custom rule comparing two metrics to numbers
+var twoThresholdRule = Rule{
+	Code:     "X055",
+	Severity: SeverityWarning,
+	MatchSpec: MatchSpec{
+		Action: ActionAnd,
+		SubMatch: []MatchSpec{
+			{Metric: "claude_md_days_since_update", Action: ActionGreaterThan, Value: 90},
+			{Metric: "scope_commits_since_update", Action: ActionGreaterThan, Value: 0},
+		},
+	},
+}
+
+func TestConfig_ApplyRules(t *testing.T) {
+	builtin, err := LoadBuiltinRules()
+	if err != nil {
+		t.Fatal(err)
+	}
+	builtin = append(builtin, twoThresholdRule)
+	disabled := false
+	threshold := 400
+	cfg := &Config{Rules: map[string]RuleOverride{
+		"CD011": {Enabled: &disabled},
+		"cd013": {Severity: SeverityInfo},
+		"CD001": {Threshold: &threshold},
+		"X055":  {Thresholds: map[string]int{"claude_md_days_since_update": 30}},
+		"X999":  {Severity: SeverityError},
+	}}
+
+	got, err := cfg.ApplyRules(builtin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if findRule(got, "CD011") != nil {
+		t.Error("expected CD011 to be removed")
+	}
+	if r := findRule(got, "CD013"); r == nil || r.Severity != SeverityInfo {
+		t.Errorf("expected CD013 severity info (codes are case-insensitive), got %+v", r)
+	}
+	cd001 := findRule(got, "CD001")
+	if cd001.MatchSpec.Value != 400 || cd001.ErrorMessage != "File has more than 400 lines" {
+		t.Errorf("expected CD001 threshold and message updated, got %v %q", cd001.MatchSpec.Value, cd001.ErrorMessage)
+	}
+	x055 := findRule(got, "X055")
+	if x055.MatchSpec.SubMatch[0].Value != 30 || x055.MatchSpec.SubMatch[1].Value != 0 {
+		t.Errorf("expected only the days threshold changed, got %+v", x055.MatchSpec.SubMatch)
+	}
+
+	// Overrides must not leak into the original rules
+	if orig := findRule(builtin, "X055"); orig.MatchSpec.SubMatch[0].Value != 90 {
+		t.Errorf("original X055 modified: %+v", orig.MatchSpec.SubMatch[0])
+	}
+}
+
+func TestConfig_ApplyRules_ThresholdErrors(t *testing.T) {
+	builtin, err := LoadBuiltinRules()
+	if err != nil {
+		t.Fatal(err)
+	}
+	builtin = append(builtin, twoThresholdRule)
+	one := 1
+
+	t.Run("ambiguous threshold", func(t *testing.T) {
+		cfg := &Config{Rules: map[string]RuleOverride{"X055": {Threshold: &one}}}
+		if _, err := cfg.ApplyRules(builtin); err == nil || !strings.Contains(err.Error(), "claude_md_days_since_update") {
+			t.Errorf("expected error listing metrics, got %v", err)
+		}
+	})
+
+	t.Run("unknown metric", func(t *testing.T) {
+		cfg := &Config{Rules: map[string]RuleOverride{"CD001": {Thresholds: map[string]int{"bogus": 1}}}}
+		if _, err := cfg.ApplyRules(builtin); err == nil {
+			t.Error("expected error for unknown metric")
+		}
+	})
+
+	t.Run("rule without thresholds", func(t *testing.T) {
+		cfg := &Config{Rules: map[string]RuleOverride{"CD011": {Threshold: &one}}}
+		if _, err := cfg.ApplyRules(builtin); err == nil {
+			t.Error("expected error for a rule with no numeric value")
+		}
+	})
+}
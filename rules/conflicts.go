@@ -0,0 +1,191 @@
+package rules
+
+import (
+	"regexp"
+	"strings"
+)
+
+// InstructionLocation points at an instruction line in a context file
+type InstructionLocation struct {
+	File string // file path as shown in the report (primary path or ref path)
+	Line int    // 1-based line number
+	Text string // the instruction line, trimmed
+}
+
+// ConflictInfo represents a pair of instructions that contradict each other
+type ConflictInfo struct {
+	Subject string // what the instructions disagree about
+	First   InstructionLocation
+	Second  InstructionLocation
+}
+
+// negativePrefixPattern matches phrasings that forbid something
+var negativePrefixPattern = regexp.MustCompile(`^(never|do not|don't|dont|must not|mustn't|should not|shouldn't|avoid)\s+`)
+
+// positivePrefixPattern matches emphasis words that don't change an instruction's meaning
+var positivePrefixPattern = regexp.MustCompile(`^(always|must|should|make sure to|be sure to|prefer to|prefer)\s+`)
+
+// useVerbPattern matches the verb in "use X" so "never use X" and "avoid X" share a subject
+var useVerbPattern = regexp.MustCompile(`^(use|using)\s+`)
+
+// conditionPattern matches instructions that only apply in some situations.
+// They may legitimately differ from an unconditional instruction, so they are not compared.
+var conditionPattern = regexp.MustCompile(`\b(if|when|whenever|unless|except|only|before|after|while|until)\b`)
+
+// clauseBreakPattern ends the subject of an instruction where an explanation starts
+var clauseBreakPattern = regexp.MustCompile(`\s*([,;:(]|\s[-—–]\s|\.\s|\.$|!|\s(because|since|as|so)\s)`)
+
+// scopePattern splits "X for Y" into the thing chosen and what it applies to
+var scopePattern = regexp.MustCompile(`\s(for|in|on)\s`)
+
+// choiceGroup is a set of mutually exclusive options, such as indentation styles.
+// Two instructions picking different options of one group contradict each other.
+type choiceGroup struct {
+	Subject string
+	Options map[string]*regexp.Regexp
+}
+
+var choiceGroups = []choiceGroup{
+	{
+		Subject: "indentation",
+		Options: map[string]*regexp.Regexp{
+			"tabs":   regexp.MustCompile(`^tabs?\b`),
+			"spaces": regexp.MustCompile(`^(\d+|two|four)?\s*spaces?\b`),
+		},
+	},
+	{
+		Subject: "package manager",
+		Options: map[string]*regexp.Regexp{
+			"npm":  regexp.MustCompile(`^npm\b`),
+			"yarn": regexp.MustCompile(`^yarn\b`),
+			"pnpm": regexp.MustCompile(`^pnpm\b`),
+			"bun":  regexp.MustCompile(`^bun\b`),
+		},
+	},
+	{
+		Subject: "quote style",
+		Options: map[string]*regexp.Regexp{
+			"single quotes": regexp.MustCompile(`^single[- ]quot`),
+			"double quotes": regexp.MustCompile(`^double[- ]quot`),
+		},
+	},
+}
+
+// parsedInstruction is an instruction reduced to its polarity and subject
+type parsedInstruction struct {
+	Loc      InstructionLocation
+	Negative bool
+	Subject  string // what the instruction is about, e.g. "pnpm"
+	Scope    string // what it applies to, e.g. "yaml files" in "use 2 spaces for yaml files"
+}
+
+// FindConflictingInstructions pairs instructions that contradict each other across
+// the primary file and its full reference tree: the same subject with opposite
+// polarity ("always use pnpm" / "never use pnpm"), or different options of a
+// mutually exclusive choice ("use tabs" / "use 2 spaces").
+func FindConflictingInstructions(primary *AnalysisContext, refs []RefInfo) []ConflictInfo {
+	instructions := parseInstructions(primary.FilePath, primary)
+	for _, ref := range FlattenRefs(refs) {
+		if !ref.Exists || ref.Context == nil {
+			continue
+		}
+		instructions = append(instructions, parseInstructions(ref.Path, ref.Context)...)
+	}
+
+	var conflicts []ConflictInfo
+	for i, a := range instructions {
+		for _, b := range instructions[i+1:] {
+			if subject, ok := contradicts(a, b); ok {
+				conflicts = append(conflicts, ConflictInfo{
+					Subject: subject,
+					First:   a.Loc,
+					Second:  b.Loc,
+				})
+			}
+		}
+	}
+	return conflicts
+}
+
+// contradicts reports whether two instructions conflict and what about
+func contradicts(a, b parsedInstruction) (string, bool) {
+	if a.Scope != "" && b.Scope != "" && a.Scope != b.Scope {
+		return "", false
+	}
+
+	if a.Negative != b.Negative {
+		return a.Subject, a.Subject == b.Subject
+	}
+	if a.Negative {
+		return "", false
+	}
+
+	for _, group := range choiceGroups {
+		optA := group.option(a.Subject)
+		optB := group.option(b.Subject)
+		if optA != "" && optB != "" && optA != optB {
+			return group.Subject, true
+		}
+	}
+	return "", false
+}
+
+// option returns which option of the group subject picks, or "" if none
+func (g choiceGroup) option(subject string) string {
+	for name, re := range g.Options {
+		if re.MatchString(subject) {
+			return name
+		}
+	}
+	return ""
+}
+
+func parseInstructions(path string, ctx *AnalysisContext) []parsedInstruction {
+	var parsed []parsedInstruction
+	for _, instr := range extractInstructions(ctx.Lines, ctx.Markdown) {
+		p, ok := parseInstruction(instr.Text)
+		if !ok {
+			continue
+		}
+		p.Loc = InstructionLocation{File: path, Line: instr.Line, Text: instr.Text}
+		parsed = append(parsed, p)
+	}
+	return parsed
+}
+
+// parseInstruction reduces an instruction line to its polarity, subject and scope
+func parseInstruction(line string) (parsedInstruction, bool) {
+	s := strings.ToLower(line)
+	s = strings.TrimLeft(s, "-*+ \t")
+	s = strings.NewReplacer("`", "", "**", "", "__", "").Replace(s)
+	s = strings.TrimSpace(s)
+
+	if conditionPattern.MatchString(s) {
+		return parsedInstruction{}, false
+	}
+
+	var p parsedInstruction
+	if m := negativePrefixPattern.FindString(s); m != "" {
+		p.Negative = true
+		s = s[len(m):]
+	} else if m := positivePrefixPattern.FindString(s); m != "" {
+		s = s[len(m):]
+	}
+	s = strings.TrimPrefix(s, "ever ")
+	s = useVerbPattern.ReplaceAllString(s, "")
+	s = strings.TrimPrefix(s, "the ")
+
+	if loc := clauseBreakPattern.FindStringIndex(s); loc != nil {
+		s = s[:loc[0]]
+	}
+	if loc := scopePattern.FindStringIndex(s); loc != nil {
+		p.Scope = strings.TrimSpace(s[loc[1]:])
+		s = s[:loc[0]]
+	}
+
+	p.Subject = strings.TrimSpace(s)
+	if len(p.Subject) < 2 {
+		return parsedInstruction{}, false
+	}
+	return p, true
+}
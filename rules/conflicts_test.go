@@ -0,0 +1,94 @@
+package rules
+
+import (
+	"testing"
+)
+
+func TestFindConflictingInstructions_OppositePolarity(t *testing.T) {
+	primary := &AnalysisContext{
+		FilePath: "CLAUDE.md",
+		Lines:    []string{"# Tools", "", "- Always use pnpm"},
+	}
+
+	refs := []RefInfo{
+		{
+			Path:   "docs/guide.md",
+			Exists: true,
+			Context: &AnalysisContext{
+				FilePath: "docs/guide.md",
+				Lines:    []string{"- Never use `pnpm`, it breaks the lockfile"},
+			},
+		},
+	}
+
+	conflicts := FindConflictingInstructions(primary, refs)
+	if len(conflicts) != 1 {
+		t.Fatalf("expected 1 conflict, got %d: %+v", len(conflicts), conflicts)
+	}
+	c := conflicts[0]
+	if c.Subject != "pnpm" {
+		t.Errorf("expected subject pnpm, got %q", c.Subject)
+	}
+	if c.First.File != "CLAUDE.md" || c.First.Line != 3 {
+		t.Errorf("unexpected first location %+v", c.First)
+	}
+	if c.Second.File != "docs/guide.md" || c.Second.Line != 1 {
+		t.Errorf("unexpected second location %+v", c.Second)
+	}
+}
+
+func TestFindConflictingInstructions_ExclusiveChoices(t *testing.T) {
+	primary := &AnalysisContext{
+		FilePath: "CLAUDE.md",
+		Lines: []string{
+			"- Use tabs",
+			"- Use 2 spaces for indentation",
+			"- Prefer single quotes",
+		},
+	}
+
+	conflicts := FindConflictingInstructions(primary, nil)
+	if len(conflicts) != 1 {
+		t.Fatalf("expected 1 conflict, got %d: %+v", len(conflicts), conflicts)
+	}
+	if conflicts[0].Subject != "indentation" {
+		t.Errorf("expected indentation conflict, got %q", conflicts[0].Subject)
+	}
+}
+
+func TestFindConflictingInstructions_NoConflict(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+	}{
+		{"same polarity", []string{"- Always use pnpm", "- Use pnpm"}},
+		{"different subjects", []string{"- Always use pnpm", "- Never use yarn"}},
+		{"conditional", []string{"- Never push to main", "- Push to main only after review"}},
+		{"different scopes", []string{"- Use tabs for Go files", "- Use 2 spaces for YAML files"}},
+		{"code block", []string{"- Always use pnpm", "```", "never use pnpm", "```"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary := &AnalysisContext{FilePath: "CLAUDE.md", Lines: tt.lines}
+			if conflicts := FindConflictingInstructions(primary, nil); len(conflicts) != 0 {
+				t.Errorf("expected no conflicts, got %+v", conflicts)
+			}
+		})
+	}
+}
+
+func TestFindConflictingInstructions_SkipsNonExistent(t *testing.T) {
+	primary := &AnalysisContext{
+		FilePath: "CLAUDE.md",
+		Lines:    []string{"- Always use pnpm"},
+	}
+
+	refs := []RefInfo{
+		{Path: "docs/missing.md", Exists: false},
+	}
+
+	if conflicts := FindConflictingInstructions(primary, refs); len(conflicts) != 0 {
+		t.Errorf("expected 0 conflicts, got %d", len(conflicts))
+	}
+}
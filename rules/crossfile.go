@@ -1,86 +1,108 @@
 package rules
 
 import (
+	"slices"
 	"strings"
 )
 
 // DuplicateInfo represents an instruction found in multiple files
 type DuplicateInfo struct {
-	Instruction string   // the normalized instruction text
-	Files       []string // which files contain it
+	Instruction  string   // the normalized instruction text (first occurrence)
+	Files        []string // which files contain it
+	Instructions []string // every wording of the instruction, in order of appearance
+	Similarity   float64  // lowest similarity of a wording to the first (1 = same words)
 }
 
 // AggregateMetrics holds combined metrics across all context files
 type AggregateMetrics struct {
 	TotalInstructionCount int
 	TotalLineCount        int
+	TotalTokenCount       int
 	FileCount             int
 	Duplicates            []DuplicateInfo
+	Conflicts             []ConflictInfo
 }
 
-// FindDuplicateInstructions finds instructions that appear in multiple files
-func FindDuplicateInstructions(primary *AnalysisContext, refs []RefInfo) []DuplicateInfo {
-	allRefs := FlattenRefs(refs)
-
-	// Map from normalized instruction -> list of files containing it
-	instructionFiles := make(map[string][]string)
+// FindDuplicateInstructions finds instructions that appear in multiple files.
+// Instructions are grouped with the first instruction they are at least
+// threshold similar to (see Similarity), so paraphrases form one cluster.
+func FindDuplicateInstructions(primary *AnalysisContext, refs []RefInfo, threshold float64) []DuplicateInfo {
+	type cluster struct {
+		words wordSet
+		info  DuplicateInfo
+	}
+	var clusters []*cluster
 
-	// Extract instructions from primary file
-	primaryInstructions := extractInstructionLines(primary.Lines)
-	for _, instr := range primaryInstructions {
+	add := func(path, instr string) {
 		normalized := normalizeInstruction(instr)
-		if normalized != "" {
-			instructionFiles[normalized] = append(instructionFiles[normalized], primary.FilePath)
+		if normalized == "" {
+			return
 		}
+		words := instructionWords(normalized)
+
+		for _, c := range clusters {
+			score := c.words.similarity(words)
+			if score < threshold {
+				continue
+			}
+			c.info.Similarity = min(c.info.Similarity, score)
+			if !slices.Contains(c.info.Instructions, normalized) {
+				c.info.Instructions = append(c.info.Instructions, normalized)
+			}
+			// Only add the file once per instruction
+			if !slices.Contains(c.info.Files, path) {
+				c.info.Files = append(c.info.Files, path)
+			}
+			return
+		}
+
+		clusters = append(clusters, &cluster{
+			words: words,
+			info: DuplicateInfo{
+				Instruction:  normalized,
+				Files:        []string{path},
+				Instructions: []string{normalized},
+				Similarity:   1,
+			},
+		})
+	}
+
+	// Extract instructions from primary file
+	for _, instr := range extractInstructionLines(primary.Lines, primary.Markdown) {
+		add(primary.FilePath, instr)
 	}
 
 	// Extract instructions from each referenced file (full tree)
-	for _, ref := range allRefs {
+	for _, ref := range FlattenRefs(refs) {
 		if !ref.Exists || ref.Context == nil {
 			continue
 		}
-		refInstructions := extractInstructionLines(ref.Context.Lines)
-		for _, instr := range refInstructions {
-			normalized := normalizeInstruction(instr)
-			if normalized == "" {
-				continue
-			}
-			// Only add the file once per instruction
-			files := instructionFiles[normalized]
-			alreadyHasFile := false
-			for _, f := range files {
-				if f == ref.Path {
-					alreadyHasFile = true
-					break
-				}
-			}
-			if !alreadyHasFile {
-				instructionFiles[normalized] = append(instructionFiles[normalized], ref.Path)
-			}
+		for _, instr := range extractInstructionLines(ref.Context.Lines, ref.Context.Markdown) {
+			add(ref.Path, instr)
 		}
 	}
 
 	// Collect duplicates (instructions in 2+ files)
 	var duplicates []DuplicateInfo
-	for instr, files := range instructionFiles {
-		if len(files) >= 2 {
-			duplicates = append(duplicates, DuplicateInfo{
-				Instruction: instr,
-				Files:       files,
-			})
+	for _, c := range clusters {
+		if len(c.info.Files) >= 2 {
+			duplicates = append(duplicates, c.info)
 		}
 	}
 
 	return duplicates
 }
 
-// ComputeAggregateMetrics computes combined metrics across primary + all referenced files (full tree)
-func ComputeAggregateMetrics(primary *AnalysisContext, refs []RefInfo) AggregateMetrics {
+// ComputeAggregateMetrics computes combined metrics across primary + all referenced files (full tree).
+// similarityThreshold is passed to FindDuplicateInstructions.
+func ComputeAggregateMetrics(primary *AnalysisContext, refs []RefInfo, similarityThreshold float64) AggregateMetrics {
 	allRefs := FlattenRefs(refs)
 
+	// Imported files are counted below with the other refs
 	agg := AggregateMetrics{
-		TotalInstructionCount: primary.InstructionCount,
-		TotalLineCount:        primary.LineCount,
+		TotalInstructionCount: primary.InstructionCount - primary.Imported.Instructions,
+		TotalLineCount:        primary.LineCount - primary.Imported.Lines,
+		TotalTokenCount:       primary.TokenCount - primary.Imported.Tokens,
 		FileCount:             1,
 	}
 
@@ -90,25 +112,48 @@ func ComputeAggregateMetrics(primary *AnalysisContext, refs []RefInfo) Aggregate
 		}
 		agg.TotalInstructionCount += ref.Context.InstructionCount
 		agg.TotalLineCount += ref.Context.LineCount
+		agg.TotalTokenCount += ref.Context.TokenCount
 		agg.FileCount++
 	}
 
-	agg.Duplicates = FindDuplicateInstructions(primary, refs)
+	agg.Duplicates = FindDuplicateInstructions(primary, refs, similarityThreshold)
+	agg.Conflicts = FindConflictingInstructions(primary, refs)
 
 	return agg
 }
 
+// instructionLine is an instruction with its 1-based line number
+type instructionLine struct {
+	Line int
+	Text string
+}
+
 // extractInstructionLines returns lines that look like instructions
 // Reuses the same imperative verb pattern from CountInstructions
-func extractInstructionLines(lines []string) []string {
+func extractInstructionLines(lines []string, doc *MarkdownDoc) []string {
 	var instructions []string
-	for _, line := range lines {
+	for _, instr := range extractInstructions(lines, doc) {
+		instructions = append(instructions, instr.Text)
+	}
+	return instructions
+}
+
+// extractInstructions is extractInstructionLines keeping line numbers
+func extractInstructions(lines []string, doc *MarkdownDoc) []instructionLine {
+	if doc == nil {
+		doc = ParseMarkdown(lines)
+	}
+	var instructions []instructionLine
+	for i, line := range lines {
+		if !doc.isInstructionLine(i + 1) {
+			continue
+		}
 		line = strings.TrimSpace(line)
 		if line == "" || strings.HasPrefix(line, "#") {
 			continue
 		}
 		if imperativeVerbPattern.MatchString(line) {
-			instructions = append(instructions, line)
+			instructions = append(instructions, instructionLine{Line: i + 1, Text: line})
 		}
 	}
 	return instructions
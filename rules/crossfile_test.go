@@ -21,7 +21,7 @@ func TestFindDuplicateInstructions_NoDuplicates(t *testing.T) {
 		},
 	}
 
-	dups := FindDuplicateInstructions(primary, refs)
+	dups := FindDuplicateInstructions(primary, refs, DefaultSimilarityThreshold)
 	if len(dups) != 0 {
 		t.Errorf("expected 0 duplicates, got %d", len(dups))
 	}
@@ -44,7 +44,7 @@ func TestFindDuplicateInstructions_WithDuplicates(t *testing.T) {
 		},
 	}
 
-	dups := FindDuplicateInstructions(primary, refs)
+	dups := FindDuplicateInstructions(primary, refs, DefaultSimilarityThreshold)
 	if len(dups) != 1 {
 		t.Fatalf("expected 1 duplicate, got %d", len(dups))
 	}
@@ -66,7 +66,7 @@ func TestFindDuplicateInstructions_SkipsNonExistent(t *testing.T) {
 		},
 	}
 
-	dups := FindDuplicateInstructions(primary, refs)
+	dups := FindDuplicateInstructions(primary, refs, DefaultSimilarityThreshold)
 	if len(dups) != 0 {
 		t.Errorf("expected 0 duplicates, got %d", len(dups))
 	}
@@ -90,7 +90,7 @@ func TestFindDuplicateInstructions_ShortInstructionsIgnored(t *testing.T) {
 	}
 
 	// "use gofmt" is < 15 chars, should be skipped
-	dups := FindDuplicateInstructions(primary, refs)
+	dups := FindDuplicateInstructions(primary, refs, DefaultSimilarityThreshold)
 	if len(dups) != 0 {
 		t.Errorf("expected 0 duplicates for short instruction, got %d", len(dups))
 	}
@@ -104,7 +104,7 @@ func TestComputeAggregateMetrics_SingleFile(t *testing.T) {
 		Lines:            []string{},
 	}
 
-	agg := ComputeAggregateMetrics(primary, nil)
+	agg := ComputeAggregateMetrics(primary, nil, DefaultSimilarityThreshold)
 
 	if agg.TotalInstructionCount != 20 {
 		t.Errorf("expected TotalInstructionCount=20, got %d", agg.TotalInstructionCount)
@@ -152,7 +152,7 @@ func TestComputeAggregateMetrics_MultipleFiles(t *testing.T) {
 		},
 	}
 
-	agg := ComputeAggregateMetrics(primary, refs)
+	agg := ComputeAggregateMetrics(primary, refs, DefaultSimilarityThreshold)
 
 	if agg.TotalInstructionCount != 45 {
 		t.Errorf("expected TotalInstructionCount=45, got %d", agg.TotalInstructionCount)
@@ -195,8 +195,66 @@ func TestExtractInstructionLines(t *testing.T) {
 		"- Run tests before committing",
 	}
 
-	instructions := extractInstructionLines(lines)
+	instructions := extractInstructionLines(lines, nil)
 	if len(instructions) != 3 {
 		t.Errorf("expected 3 instructions, got %d: %v", len(instructions), instructions)
 	}
 }
+
+func TestFindDuplicateInstructions_Paraphrases(t *testing.T) {
+	primary := &AnalysisContext{
+		FilePath: "CLAUDE.md",
+		Lines:    []string{"- Run make test before committing"},
+	}
+
+	refs := []RefInfo{
+		{
+			Path:   "docs/guide.md",
+			Exists: true,
+			Context: &AnalysisContext{
+				FilePath: "docs/guide.md",
+				Lines:    []string{"- Always run `make test` before you commit"},
+			},
+		},
+	}
+
+	dups := FindDuplicateInstructions(primary, refs, DefaultSimilarityThreshold)
+	if len(dups) != 1 {
+		t.Fatalf("expected 1 duplicate cluster, got %d", len(dups))
+	}
+	if len(dups[0].Instructions) != 2 {
+		t.Errorf("expected 2 wordings, got %v", dups[0].Instructions)
+	}
+	if dups[0].Similarity < DefaultSimilarityThreshold {
+		t.Errorf("expected similarity >= %v, got %v", DefaultSimilarityThreshold, dups[0].Similarity)
+	}
+
+	// An exact-words threshold still accepts reordered and inflected wordings,
+	// but not extra words
+	refs[0].Context.Lines = []string{"- Always run `make test` before you commit changes"}
+	if dups := FindDuplicateInstructions(primary, refs, 1); len(dups) != 0 {
+		t.Errorf("expected no duplicates at threshold 1, got %+v", dups)
+	}
+}
+
+func TestFindDuplicateInstructions_NegationNotDuplicate(t *testing.T) {
+	primary := &AnalysisContext{
+		FilePath: "CLAUDE.md",
+		Lines:    []string{"- Run the linter before committing changes"},
+	}
+
+	refs := []RefInfo{
+		{
+			Path:   "docs/guide.md",
+			Exists: true,
+			Context: &AnalysisContext{
+				FilePath: "docs/guide.md",
+				Lines:    []string{"- Don't run the linter before committing changes"},
+			},
+		},
+	}
+
+	if dups := FindDuplicateInstructions(primary, refs, 0.5); len(dups) != 0 {
+		t.Errorf("expected negated instruction not to match, got %+v", dups)
+	}
+}
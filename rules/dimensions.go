@@ -56,6 +56,12 @@ func DefaultDimensionWeights() map[Dimension]float64 {
 // CalculateDimensionScores computes per-dimension scores from rule results
 // and a freshness score, then produces a weighted overall score.
 func CalculateDimensionScores(results []RuleResult, freshnessScore int) *DimensionScores {
+	return CalculateWeightedDimensionScores(results, freshnessScore, DefaultDimensionWeights())
+}
+
+// CalculateWeightedDimensionScores is CalculateDimensionScores with custom
+// dimension weights, e.g. from the project config.
+func CalculateWeightedDimensionScores(results []RuleResult, freshnessScore int, weights map[Dimension]float64) *DimensionScores {
 	ds := &DimensionScores{
 		Scores: make(map[Dimension]*DimensionScoreResult),
 	}
@@ -114,7 +120,6 @@ func CalculateDimensionScores(results []RuleResult, freshnessScore int) *Dimensi
 	}
 
 	// Weighted average for Overall.
-	weights := DefaultDimensionWeights()
 	total := 0.0
 	for dim, w := range weights {
 		if entry, ok := ds.Scores[dim]; ok {
@@ -0,0 +1,335 @@
+package rules
+
+import (
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DriftStatus is what happened to mentioned code after the context file's
+// last commit
+type DriftStatus string
+
+const (
+	DriftModified DriftStatus = "modified"
+	DriftRenamed  DriftStatus = "renamed"
+	DriftDeleted  DriftStatus = "deleted"
+)
+
+// Drift is a mentioned path or package that changed after the context file
+// was last committed
+type Drift struct {
+	Path      string      // repo-relative, as it was at the context file's last commit
+	Status    DriftStatus // modified, renamed or deleted
+	Commits   int         // commits touching it since, under any of its names
+	RenamedTo string      // the current path of a renamed file or directory
+	Mentions  []Mention   // where the context file mentions it
+}
+
+// DriftReport compares the code a context file mentions with the history
+// since the file's last commit
+type DriftReport struct {
+	Since           string // the context file's last commit
+	DaysSinceUpdate int
+	ScopeCommits    int // commits touching the context file's directory since
+	Commits         int // commits touching any mentioned path since
+	Drifted         []Drift
+}
+
+// AnalyzeDrift finds the paths and packages ctx mentions that were modified,
+// renamed or deleted in commits after the context file's last one. Mentions
+// resolve against the tree of that commit: from the context file's directory,
+// the repo root, or as the tail of a path, and qualifiers such as `auth` in
+// `auth.NewSession` resolve to directories of that name. Uncommitted changes
+// aren't considered. Returns nil outside git or for uncommitted files.
+func AnalyzeDrift(ctx *AnalysisContext, root string) *DriftReport {
+	file, err := realPath(ctx.FilePath)
+	if err != nil {
+		return nil
+	}
+	since, committed := lastCommit(root, file)
+	if since == "" {
+		return nil
+	}
+	report := &DriftReport{
+		Since:           since,
+		DaysSinceUpdate: int(activeSource.Now().Sub(committed).Hours() / 24),
+	}
+
+	history := changesSince(root, since)
+	if scope, ok := RepoPath(root, filepath.Dir(file)); ok {
+		report.ScopeCommits = commitsWithin(history, scope)
+	}
+
+	then := treePaths(root, since)
+	mentioned := resolveMentionedPaths(ctx, root, then)
+	if len(mentioned) == 0 {
+		return report
+	}
+	now := treePaths(root, sourceHead())
+
+	allCommits := map[string]bool{}
+	for _, p := range sortedKeys(mentioned) {
+		d := Drift{Path: p, Mentions: mentioned[p]}
+		current, movedDir := p, ""
+		for _, c := range history {
+			touched := false
+			for _, ch := range c.changes {
+				if !pathWithin(ch.from, current) {
+					continue
+				}
+				touched = true
+				if ch.to == "" {
+					continue
+				}
+				if ch.from == current {
+					current = ch.to // the file itself was renamed
+				} else if rest := strings.TrimPrefix(ch.from, current); strings.HasSuffix(ch.to, rest) {
+					movedDir = strings.TrimSuffix(ch.to, rest)
+				}
+			}
+			if touched {
+				d.Commits++
+				allCommits[c.hash] = true
+			}
+		}
+
+		switch {
+		case now[current] && current != p:
+			d.Status, d.RenamedTo = DriftRenamed, current
+		case now[current]:
+			d.Status = DriftModified
+		case movedDir != "" && now[movedDir]:
+			d.Status, d.RenamedTo = DriftRenamed, movedDir
+		default:
+			d.Status = DriftDeleted
+		}
+		if d.Status != DriftModified || d.Commits > 0 {
+			report.Drifted = append(report.Drifted, d)
+		}
+	}
+	report.Commits = len(allCommits)
+	return report
+}
+
+// commitsWithin counts the commits in history touching a path under dir
+func commitsWithin(history []commitChanges, dir string) int {
+	count := 0
+	for _, c := range history {
+		for _, ch := range c.changes {
+			if dir == "." || pathWithin(ch.from, dir) || (ch.to != "" && pathWithin(ch.to, dir)) {
+				count++
+				break
+			}
+		}
+	}
+	return count
+}
+
+// EnrichContextWithDrift records the report as the drifted_mention_count
+// metric, located at each mention of drifted code, along with the days since
+// the context file's last update and the commits since then in its directory
+// (scope_commits_since_update) and in the code it mentions
+// (mentioned_code_commits_since_update). Without a report, the days are -1.
+func EnrichContextWithDrift(ctx *AnalysisContext, report *DriftReport) {
+	if report == nil {
+		ctx.Metrics["claude_md_days_since_update"] = -1
+		ctx.Metrics["scope_commits_since_update"] = 0
+		ctx.Metrics["mentioned_code_commits_since_update"] = 0
+		ctx.Metrics["drifted_mention_count"] = 0
+		return
+	}
+
+	var mentions []Mention
+	for _, d := range report.Drifted {
+		mentions = append(mentions, d.Mentions...)
+	}
+	spans := MentionSpans(ctx, mentions)
+	sort.Slice(spans, func(i, j int) bool {
+		if spans[i].Line != spans[j].Line {
+			return spans[i].Line < spans[j].Line
+		}
+		return spans[i].Column < spans[j].Column
+	})
+
+	if ctx.MetricSpans == nil {
+		ctx.MetricSpans = make(map[MetricType][]Span)
+	}
+	ctx.Metrics["claude_md_days_since_update"] = report.DaysSinceUpdate
+	ctx.Metrics["scope_commits_since_update"] = report.ScopeCommits
+	ctx.Metrics["mentioned_code_commits_since_update"] = report.Commits
+	ctx.Metrics["drifted_mention_count"] = len(report.Drifted)
+	ctx.MetricSpans["drifted_mention_count"] = spans
+}
+
+// resolveMentionedPaths maps the repo paths ctx mentions, as they were in
+// tree, to the mentions of each
+func resolveMentionedPaths(ctx *AnalysisContext, root string, tree map[string]bool) map[string][]Mention {
+	ctxDir := "."
+	if abs, err := realPath(filepath.Dir(ctx.FilePath)); err == nil {
+		if rel, err := filepath.Rel(root, abs); err == nil {
+			ctxDir = filepath.ToSlash(rel)
+		}
+	}
+
+	resolved := map[string][]Mention{}
+	for _, m := range ExtractMentions(ctx) {
+		var paths []string
+		switch {
+		case m.Kind == MentionPath:
+			paths = resolveTreePath(m.Name, ctxDir, tree)
+		case m.Qualifier != "":
+			paths = packageDirs(strings.ReplaceAll(m.Qualifier, ".", "/"), tree)
+		}
+		for _, p := range paths {
+			resolved[p] = append(resolved[p], m)
+		}
+	}
+	return resolved
+}
+
+// resolveTreePath finds p in tree: relative to dir, at the root, or else as
+// the tail of every path it ends
+func resolveTreePath(p, dir string, tree map[string]bool) []string {
+	for _, candidate := range []string{path.Join(dir, p), p} {
+		if tree[candidate] {
+			return []string{candidate}
+		}
+	}
+	var matches []string
+	suffix := "/" + p
+	for indexed := range tree {
+		if strings.HasSuffix(indexed, suffix) {
+			matches = append(matches, indexed)
+		}
+	}
+	sort.Strings(matches)
+	return matches
+}
+
+// packageDirs finds the directories in tree named like pkg that hold code
+// indexed for symbols
+func packageDirs(pkg string, tree map[string]bool) []string {
+	dirs := map[string]bool{}
+	for p := range tree {
+		if symbolPatterns[strings.ToLower(path.Ext(p))] == nil {
+			continue
+		}
+		dir := path.Dir(p)
+		if dir == pkg || strings.HasSuffix(dir, "/"+pkg) {
+			dirs[dir] = true
+		}
+	}
+	return sortedKeys(dirs)
+}
+
+// pathWithin reports whether p is dir or inside it
+func pathWithin(p, dir string) bool {
+	return p == dir || strings.HasPrefix(p, dir+"/")
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// =============================================================================
+// Git
+// =============================================================================
+
+// pathChange is a path a commit modified, added, deleted or renamed
+type pathChange struct {
+	from string // the path before the commit
+	to   string // the new path of a rename, otherwise empty
+}
+
+type commitChanges struct {
+	hash    string
+	changes []pathChange
+}
+
+// lastCommit returns the last commit touching file and its time
+func lastCommit(root, file string) (string, time.Time) {
+	cmd := exec.Command("git", "log", "-1", "--format=%H %ct", sourceHead(), "--", file)
+	cmd.Dir = root
+	output, err := cmd.Output()
+	if err != nil {
+		return "", time.Time{}
+	}
+	fields := strings.Fields(string(output))
+	if len(fields) != 2 {
+		return "", time.Time{}
+	}
+	unix, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return "", time.Time{}
+	}
+	return fields[0], time.Unix(unix, 0)
+}
+
+// treePaths lists the files and directories in rev's tree
+func treePaths(root, rev string) map[string]bool {
+	cmd := exec.Command("git", "ls-tree", "-r", "--name-only", "-z", rev)
+	cmd.Dir = root
+	output, err := cmd.Output()
+	paths := map[string]bool{}
+	if err != nil {
+		return paths
+	}
+	for _, file := range strings.Split(string(output), "\x00") {
+		for p := file; p != "" && p != "."; p = path.Dir(p) {
+			if paths[p] {
+				break
+			}
+			paths[p] = true
+		}
+	}
+	return paths
+}
+
+// changesSince lists the commits after since up to HEAD, oldest first, with
+// the paths each changed. Renames are detected across the whole tree.
+func changesSince(root, since string) []commitChanges {
+	cmd := exec.Command("git", "log", "--reverse", "--format=%x01%H", "--name-status", "-M", "-z", since+".."+sourceHead())
+	cmd.Dir = root
+	output, err := cmd.Output()
+	if err != nil {
+		return nil
+	}
+
+	var commits []commitChanges
+	for _, block := range strings.Split(string(output), "\x01")[1:] {
+		// With -z: hash, then status and path fields, the first status
+		// after a newline
+		fields := strings.Split(block, "\x00")
+		c := commitChanges{hash: strings.TrimSpace(fields[0])}
+		for i := 1; i < len(fields); i++ {
+			status := strings.TrimSpace(fields[i])
+			if status == "" || i+1 >= len(fields) {
+				continue
+			}
+			switch status[0] {
+			case 'R':
+				if i+2 < len(fields) {
+					c.changes = append(c.changes, pathChange{from: fields[i+1], to: fields[i+2]})
+				}
+				i += 2
+			case 'C':
+				i += 2 // a copy leaves the original in place
+			default:
+				c.changes = append(c.changes, pathChange{from: fields[i+1]})
+				i++
+			}
+		}
+		commits = append(commits, c)
+	}
+	return commits
+}
@@ -0,0 +1,201 @@
+package rules
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func gitRepo(t *testing.T) (dir string, run func(args ...string)) {
+	t.Helper()
+	dir = t.TempDir()
+	run = func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %s", args, out)
+		}
+	}
+	run("init", "-q")
+	run("config", "user.email", "test@test.com")
+	run("config", "user.name", "Test")
+	return dir, run
+}
+
+func TestAnalyzeDrift(t *testing.T) {
+	dir, run := gitRepo(t)
+	content := strings.Join([]string{
+		"# Project",
+		"",
+		"- Tokens are issued in `internal/auth/token.go`; sessions via `auth.NewSession`",
+		"- The server lives in `cmd/server/`",
+		"- `legacy.py` is kept for the import job, configured in `config.yaml`",
+	}, "\n")
+	writeFiles(t, dir, map[string]string{
+		"CLAUDE.md":                content,
+		"config.yaml":              "port: 8080\n",
+		"legacy.py":                "def main():\n    pass\n",
+		"internal/auth/token.go":   "package auth\n",
+		"internal/auth/session.go": "package auth\n\nfunc NewSession() {}\n",
+		"cmd/server/main.go":       "package main\n",
+	})
+	run("add", ".")
+	run("commit", "-qm", "initial")
+
+	run("mv", "internal/auth/token.go", "internal/auth/tokens.go")
+	run("commit", "-qm", "rename token")
+	writeFiles(t, dir, map[string]string{"internal/auth/session.go": "package auth\n\nfunc NewSession(id string) {}\n"})
+	run("mv", "cmd/server", "cmd/api")
+	run("commit", "-qam", "move server")
+	run("rm", "-q", "legacy.py")
+	run("commit", "-qm", "drop legacy")
+
+	report := AnalyzeDrift(BuildContext(filepath.Join(dir, "CLAUDE.md"), content), dir)
+	if report == nil {
+		t.Fatal("expected a report")
+	}
+	if report.Commits != 3 || report.ScopeCommits != 3 || report.DaysSinceUpdate != 0 || len(report.Since) != 40 {
+		t.Errorf("unexpected report %+v", report)
+	}
+
+	var got []string
+	for _, d := range report.Drifted {
+		s := d.Path + " " + string(d.Status)
+		if d.RenamedTo != "" {
+			s += " " + d.RenamedTo
+		}
+		got = append(got, s)
+	}
+	want := []string{
+		"cmd/server renamed cmd/api",
+		"internal/auth modified",
+		"internal/auth/token.go renamed internal/auth/tokens.go",
+		"legacy.py deleted",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("drifted:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if auth := report.Drifted[1]; auth.Commits != 2 || auth.Mentions[0].Text != "auth.NewSession" {
+		t.Errorf("unexpected package drift %+v", auth)
+	}
+}
+
+func TestAnalyzeDrift_FollowsRenames(t *testing.T) {
+	dir, run := gitRepo(t)
+	content := "Entry point: `src/app.ts`"
+	writeFiles(t, dir, map[string]string{"CLAUDE.md": content, "src/app.ts": "export {}\n"})
+	run("add", ".")
+	run("commit", "-qm", "initial")
+
+	run("mv", "src/app.ts", "src/main.ts")
+	run("commit", "-qm", "rename")
+	writeFiles(t, dir, map[string]string{"src/main.ts": "export const x = 1\n"})
+	run("commit", "-qam", "edit")
+
+	report := AnalyzeDrift(BuildContext(filepath.Join(dir, "CLAUDE.md"), content), dir)
+	if report == nil || len(report.Drifted) != 1 {
+		t.Fatalf("expected one drifted path, got %+v", report)
+	}
+	if d := report.Drifted[0]; d.RenamedTo != "src/main.ts" || d.Commits != 2 {
+		t.Errorf("expected the rename and the later edit, got %+v", d)
+	}
+}
+
+func TestAnalyzeDrift_ScopeCommits(t *testing.T) {
+	dir, run := gitRepo(t)
+	content := "# API\n\nHandlers return JSON."
+	writeFiles(t, dir, map[string]string{"api/CLAUDE.md": content, "api/handler.go": "package api\n", "web/app.ts": "export {}\n"})
+	run("add", ".")
+	run("commit", "-qm", "initial")
+	writeFiles(t, dir, map[string]string{"api/handler.go": "package api\n\nfunc Handle() {}\n"})
+	run("commit", "-qam", "edit api")
+	writeFiles(t, dir, map[string]string{"web/app.ts": "export const x = 1\n"})
+	run("commit", "-qam", "edit web")
+
+	// Commits in the file's directory count even though it mentions no code
+	report := AnalyzeDrift(BuildContext(filepath.Join(dir, "api", "CLAUDE.md"), content), dir)
+	if report == nil || report.ScopeCommits != 1 || report.Commits != 0 {
+		t.Errorf("expected one scope commit and no mentioned-code commits, got %+v", report)
+	}
+}
+
+func TestAnalyzeDrift_UpdatedContextFile(t *testing.T) {
+	dir, run := gitRepo(t)
+	writeFiles(t, dir, map[string]string{"CLAUDE.md": "See `lib/db.go`", "lib/db.go": "package lib\n"})
+	run("add", ".")
+	run("commit", "-qm", "initial")
+	writeFiles(t, dir, map[string]string{"lib/db.go": "package lib\n\nvar DB = 1\n"})
+	run("commit", "-qam", "edit db")
+
+	// Committing the context file after the change clears the drift
+	content := "See `lib/db.go` for the connection"
+	writeFiles(t, dir, map[string]string{"CLAUDE.md": content})
+	run("commit", "-qam", "docs")
+
+	report := AnalyzeDrift(BuildContext(filepath.Join(dir, "CLAUDE.md"), content), dir)
+	if report == nil || len(report.Drifted) != 0 || report.Commits != 0 {
+		t.Errorf("expected no drift, got %+v", report)
+	}
+}
+
+func TestAnalyzeDrift_NoHistory(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "CLAUDE.md"), []byte("`main.go`"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ctx := BuildContext(filepath.Join(dir, "CLAUDE.md"), "`main.go`")
+	report := AnalyzeDrift(ctx, dir)
+	if report != nil {
+		t.Fatalf("expected no report outside git, got %+v", report)
+	}
+
+	EnrichContextWithDrift(ctx, report)
+	if ctx.Metrics["claude_md_days_since_update"] != -1 || ctx.Metrics["drifted_mention_count"] != 0 {
+		t.Errorf("unexpected metrics %v", ctx.Metrics)
+	}
+}
+
+func TestDriftRule_LocatesEachMention(t *testing.T) {
+	dir, run := gitRepo(t)
+	content := "# Code\n\n- `pkg/a.go` and `pkg/b.go`\n- again: `pkg/a.go`\n"
+	writeFiles(t, dir, map[string]string{"CLAUDE.md": content, "pkg/a.go": "package pkg\n", "pkg/b.go": "package pkg\n"})
+	run("add", ".")
+	run("commit", "-qm", "initial")
+	run("rm", "-q", "pkg/a.go")
+	run("commit", "-qm", "drop a")
+
+	ctx := BuildContext(filepath.Join(dir, "CLAUDE.md"), content)
+	EnrichContextWithDrift(ctx, AnalyzeDrift(ctx, dir))
+
+	builtin, err := LoadBuiltinRules()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rule := findRule(builtin, "CD055")
+	if rule == nil {
+		t.Fatal("CD055 not found")
+	}
+	// A fresh change is below the default thresholds
+	if res := NewEngine([]Rule{*rule}).Evaluate(ctx)[0]; res.Passed {
+		t.Fatal("expected CD055 to wait for more commits and days")
+	}
+
+	cfg := &Config{Rules: map[string]RuleOverride{"CD055": {Thresholds: map[string]int{
+		"mentioned_code_commits_since_update": 0,
+		"claude_md_days_since_update":         -1,
+	}}}}
+	tuned, err := cfg.ApplyRules([]Rule{*rule})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := NewEngine(tuned).Evaluate(ctx)[0]
+	if !res.Passed || len(res.Spans) != 2 {
+		t.Fatalf("expected CD055 at both mentions, got passed=%v spans=%+v", res.Passed, res.Spans)
+	}
+	if res.Spans[0].Line != 3 || res.Spans[1].Line != 4 || res.Spans[1].Text != "pkg/a.go" {
+		t.Errorf("unexpected spans %+v", res.Spans)
+	}
+}
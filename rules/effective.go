@@ -0,0 +1,230 @@
+package rules
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// LoadSource is why an agent loads a context file
+type LoadSource string
+
+const (
+	SourceUser    LoadSource = "user"    // the user's own file, e.g. ~/.claude/CLAUDE.md
+	SourceProject LoadSource = "project" // a context file in the working directory or an ancestor
+	SourceLocal   LoadSource = "local"   // a personal override, e.g. CLAUDE.local.md
+	SourceImport  LoadSource = "import"  // pulled in by an @path import
+)
+
+// contextCandidate is a file name an agent looks for and the source it counts as
+type contextCandidate struct {
+	Name   string
+	Source LoadSource
+}
+
+// AgentFlavor describes which context files an agent loads at startup
+type AgentFlavor struct {
+	Name string
+	// UserFiles are looked up in the home directory. Each group loads its
+	// first existing candidate, so later candidates are fallbacks.
+	UserFiles [][]contextCandidate
+	// DirFiles are looked up in every directory from the top down to the
+	// working directory, grouped like UserFiles
+	DirFiles [][]contextCandidate
+	// StopAtRepoRoot starts the directory walk at the git root instead of
+	// the filesystem root
+	StopAtRepoRoot bool
+	// Imports reports whether @path imports are expanded
+	Imports bool
+}
+
+// AgentFlavors are the supported agents by name
+var AgentFlavors = map[string]AgentFlavor{
+	// Claude Code reads CLAUDE.md files from every ancestor up to (not
+	// including) the filesystem root, plus CLAUDE.local.md overrides
+	"claude": {
+		Name:      "claude",
+		UserFiles: [][]contextCandidate{{{".claude/CLAUDE.md", SourceUser}}},
+		DirFiles: [][]contextCandidate{
+			{{"CLAUDE.md", SourceProject}},
+			{{".claude/CLAUDE.md", SourceProject}},
+			{{"CLAUDE.local.md", SourceLocal}},
+		},
+		Imports: true,
+	},
+	// Codex reads AGENTS.md from the git root down, where AGENTS.override.md
+	// replaces AGENTS.md in its directory
+	"codex": {
+		Name: "codex",
+		UserFiles: [][]contextCandidate{{
+			{".codex/AGENTS.override.md", SourceLocal},
+			{".codex/AGENTS.md", SourceUser},
+		}},
+		DirFiles: [][]contextCandidate{{
+			{"AGENTS.override.md", SourceLocal},
+			{"AGENTS.md", SourceProject},
+		}},
+		StopAtRepoRoot: true,
+	},
+}
+
+// DefaultAgent is the agent flavour used when none is given
+const DefaultAgent = "claude"
+
+// AgentNames returns the supported agent names, sorted
+func AgentNames() []string {
+	names := make([]string, 0, len(AgentFlavors))
+	for name := range AgentFlavors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LookupAgent returns the named agent flavour
+func LookupAgent(name string) (AgentFlavor, error) {
+	flavor, ok := AgentFlavors[name]
+	if !ok {
+		return AgentFlavor{}, fmt.Errorf("unknown agent %q (available: %s)", name, strings.Join(AgentNames(), ", "))
+	}
+	return flavor, nil
+}
+
+// LoadedFile is a context file in the order the agent loads it
+type LoadedFile struct {
+	Path       string
+	Source     LoadSource
+	ImportedBy string // importing file, for SourceImport
+	Context    *AnalysisContext
+}
+
+// EffectiveContext is everything an agent loads when started in Dir
+type EffectiveContext struct {
+	Agent     string
+	Dir       string
+	Files     []LoadedFile
+	Aggregate AggregateMetrics // totals, duplicates and conflicts across Files
+}
+
+// LoadEffectiveContext simulates an agent starting in dir: the user-level
+// file first, then the context files of each directory from the top down,
+// each followed by its imports. home is the user's home directory ("" skips
+// user-level files).
+func LoadEffectiveContext(dir string, flavor AgentFlavor, home string, similarityThreshold float64) (*EffectiveContext, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+
+	ec := &EffectiveContext{Agent: flavor.Name, Dir: dir}
+	seen := make(map[string]bool)
+
+	load := func(base string, groups [][]contextCandidate) {
+		for _, group := range groups {
+			for _, c := range group {
+				path := filepath.Join(base, c.Name)
+				if seen[path] {
+					break
+				}
+				content, err := os.ReadFile(path)
+				if err != nil {
+					continue
+				}
+				seen[path] = true
+				ctx := BuildContext(path, string(content))
+				ec.Files = append(ec.Files, LoadedFile{Path: path, Source: c.Source, Context: ctx})
+				if flavor.Imports {
+					ec.addImports(ctx, seen)
+				}
+				break
+			}
+		}
+	}
+
+	if home != "" {
+		load(home, flavor.UserFiles)
+	}
+	for _, d := range loadDirs(dir, flavor.StopAtRepoRoot) {
+		load(d, flavor.DirFiles)
+	}
+
+	if len(ec.Files) > 0 {
+		primary := ec.Files[0].Context
+		var refs []RefInfo
+		for _, f := range ec.Files[1:] {
+			refs = append(refs, RefInfo{Path: f.Path, ResolvedPath: f.Path, Exists: true, Context: f.Context})
+		}
+		ec.Aggregate = ComputeAggregateMetrics(primary, refs, similarityThreshold)
+	}
+	return ec, nil
+}
+
+// addImports appends the files ctx imports eagerly, depth first
+func (ec *EffectiveContext) addImports(ctx *AnalysisContext, seen map[string]bool) {
+	importer := map[string]string{}
+	for _, ref := range FlattenRefs(ResolveReferences(ctx, filepath.Dir(ctx.FilePath), 0)) {
+		path, err := filepath.Abs(ref.ResolvedPath)
+		if err != nil {
+			path = ref.ResolvedPath
+		}
+		importer[ref.Path] = path
+		if !ref.Eager || !ref.Exists || ref.Context == nil || seen[path] {
+			continue
+		}
+		seen[path] = true
+
+		by := ctx.FilePath
+		if p, ok := importer[ref.ReferencedBy]; ok {
+			by = p
+		}
+		ec.Files = append(ec.Files, LoadedFile{Path: path, Source: SourceImport, ImportedBy: by, Context: ref.Context})
+	}
+}
+
+// loadDirs returns the directories an agent reads context files from, top
+// down, ending with dir
+func loadDirs(dir string, stopAtRepoRoot bool) []string {
+	top := ""
+	if stopAtRepoRoot {
+		top = GetGitRoot(dir)
+		if top == "" {
+			top = dir
+		}
+	}
+
+	var dirs []string
+	for d := dir; ; d = filepath.Dir(d) {
+		if filepath.Dir(d) == d {
+			break // the filesystem root is never read
+		}
+		dirs = append(dirs, d)
+		if d == top || sameDir(d, top) {
+			break
+		}
+	}
+
+	// Top down
+	for i, j := 0, len(dirs)-1; i < j; i, j = i+1, j-1 {
+		dirs[i], dirs[j] = dirs[j], dirs[i]
+	}
+	return dirs
+}
+
+// sameDir reports whether a and b are the same directory after resolving symlinks
+func sameDir(a, b string) bool {
+	if b == "" {
+		return false
+	}
+	ra, errA := filepath.EvalSymlinks(a)
+	rb, errB := filepath.EvalSymlinks(b)
+	return errA == nil && errB == nil && ra == rb
+}
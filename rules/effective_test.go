@@ -0,0 +1,133 @@
+package rules
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// loadedUnder returns the loaded files below root (Claude Code also reads
+// ancestors of the temp dir), relative to root
+func loadedUnder(root string, ec *EffectiveContext) []string {
+	var out []string
+	for _, f := range ec.Files {
+		if rel, err := filepath.Rel(root, f.Path); err == nil && !strings.HasPrefix(rel, "..") {
+			out = append(out, rel+" "+string(f.Source))
+		}
+	}
+	return out
+}
+
+func TestLoadEffectiveContext_Claude(t *testing.T) {
+	root, _ := filepath.EvalSymlinks(t.TempDir())
+	writeFiles(t, root, map[string]string{
+		"home/.claude/CLAUDE.md":     "- Keep answers short and direct\n",
+		"repo/CLAUDE.md":             "# Repo\n\n- Run make test before committing\n",
+		"repo/CLAUDE.local.md":       "- Use my sandbox database locally\n",
+		"repo/pkg/CLAUDE.md":         "# Pkg\n\n@docs/style.md\n- Handlers live in internal/http\n",
+		"repo/pkg/docs/style.md":     "- Wrap errors with context always\n",
+		"repo/pkg/sub/notes.txt":     "not a context file",
+		"repo/other/CLAUDE.md":       "# Sibling, never loaded\n",
+		"repo/pkg/.claude/CLAUDE.md": "- Prefer table-driven tests\n",
+	})
+
+	ec, err := LoadEffectiveContext(filepath.Join(root, "repo/pkg/sub"), AgentFlavors["claude"], filepath.Join(root, "home"), DefaultSimilarityThreshold)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"home/.claude/CLAUDE.md user",
+		"repo/CLAUDE.md project",
+		"repo/CLAUDE.local.md local",
+		"repo/pkg/CLAUDE.md project",
+		"repo/pkg/docs/style.md import",
+		"repo/pkg/.claude/CLAUDE.md project",
+	}
+	if got := loadedUnder(root, ec); !reflect.DeepEqual(got, want) {
+		t.Errorf("load order = %v, want %v", got, want)
+	}
+
+	for _, f := range ec.Files {
+		if f.Source == SourceImport && f.ImportedBy != filepath.Join(root, "repo/pkg/CLAUDE.md") {
+			t.Errorf("expected style.md to be imported by pkg/CLAUDE.md, got %q", f.ImportedBy)
+		}
+	}
+
+	total := 0
+	for _, f := range ec.Files {
+		total += f.Context.InstructionCount
+	}
+	if ec.Aggregate.FileCount != len(ec.Files) || ec.Aggregate.TotalInstructionCount != total {
+		t.Errorf("aggregate = %d files / %d instructions, want %d / %d",
+			ec.Aggregate.FileCount, ec.Aggregate.TotalInstructionCount, len(ec.Files), total)
+	}
+}
+
+func TestLoadEffectiveContext_Codex(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	root, _ := filepath.EvalSymlinks(t.TempDir())
+	writeFiles(t, root, map[string]string{
+		"AGENTS.md":                    "- Outside the repo, never loaded\n",
+		"home/.codex/AGENTS.md":        "- Global guidance for every repo\n",
+		"repo/AGENTS.md":               "- Run make test before committing\n",
+		"repo/pkg/AGENTS.md":           "- Replaced by the override\n",
+		"repo/pkg/AGENTS.override.md":  "- Use the staging config here\n",
+		"repo/pkg/CLAUDE.md":           "- Claude only\n",
+		"repo/pkg/docs/imported.md":    "- Codex doesn't expand imports\n",
+		"repo/pkg/sub/.gitkeep":        "",
+		"repo/pkg/sub/AGENTS.local.md": "- Not a Codex file\n",
+	})
+	if out, err := exec.Command("git", "init", "-q", filepath.Join(root, "repo")).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v\n%s", err, out)
+	}
+
+	ec, err := LoadEffectiveContext(filepath.Join(root, "repo/pkg/sub"), AgentFlavors["codex"], filepath.Join(root, "home"), DefaultSimilarityThreshold)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"home/.codex/AGENTS.md user",
+		"repo/AGENTS.md project",
+		"repo/pkg/AGENTS.override.md local",
+	}
+	if got := loadedUnder(root, ec); !reflect.DeepEqual(got, want) {
+		t.Errorf("load order = %v, want %v", got, want)
+	}
+}
+
+func TestLoadEffectiveContext_NotADirectory(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{"CLAUDE.md": "# x"})
+	if _, err := LoadEffectiveContext(filepath.Join(root, "CLAUDE.md"), AgentFlavors["claude"], "", DefaultSimilarityThreshold); err == nil {
+		t.Error("expected an error for a file")
+	}
+}
+
+func TestLookupAgent(t *testing.T) {
+	if _, err := LookupAgent("claude"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := LookupAgent("cursor"); err == nil || !strings.Contains(err.Error(), "codex") {
+		t.Errorf("expected an error listing the agents, got %v", err)
+	}
+}
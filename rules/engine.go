@@ -3,6 +3,7 @@ package rules
 import (
 	"regexp"
 	"strings"
+	"unicode/utf8"
 )
 
 // imperativeVerbPattern matches lines that look like instructions
@@ -59,6 +60,10 @@ func (e *Engine) evaluateRule(ctx *AnalysisContext, rule Rule) RuleResult {
 		Details: make(map[string]any),
 	}
 
+	if passed {
+		result.Spans = FindSpans(ctx, &rule.MatchSpec)
+	}
+
 	if isPositiveRule {
 		// Good practice rule: passing = good
 		if passed {
@@ -71,25 +76,45 @@ func (e *Engine) evaluateRule(ctx *AnalysisContext, rule Rule) RuleResult {
 		}
 	}
 
+	applySuppressions(ctx, &result)
+
 	return result
 }
 
 // BuildContext creates an AnalysisContext from file content
 func BuildContext(filePath string, content string) *AnalysisContext {
 	lines := strings.Split(content, "\n")
+	format := DetectFormat(filePath)
+	var frontmatter *Frontmatter
+	if format != nil && format.Frontmatter {
+		frontmatter = parseFrontmatter(lines, format.GlobsKey)
+	}
+	frontmatterEnd := 0
+	if frontmatter != nil {
+		frontmatterEnd = frontmatter.EndLine
+	}
+	doc := parseMarkdown(lines, frontmatterEnd)
+	sections := BuildSections(lines, doc)
 
 	ctx := &AnalysisContext{
 		FilePath:         filePath,
+		Format:           format,
+		Frontmatter:      frontmatter,
 		Content:          content,
 		Lines:            lines,
 		LineCount:        len(lines),
-		InstructionCount: CountInstructions(lines),
+		InstructionCount: countInstructions(lines, doc),
+		TokenCount:       CountTokens(content),
+		Suppressions:     ParseSuppressions(lines, doc),
+		Markdown:         doc,
+		Sections:         sections,
 		Metrics:          make(map[string]any),
 	}
 
 	// Add derived metrics
 	ctx.Metrics["hasProgressiveDisclosure"] = hasProgressiveDisclosure(content)
 	ctx.Metrics["progressiveDisclosureRefs"] = findProgressiveDisclosureRefs(content)
+	ctx.Metrics["imports"] = findImportPaths(lines, doc)
 
 	return ctx
 }
@@ -97,30 +122,70 @@ func BuildContext(filePath string, content string) *AnalysisContext {
 // listItemPattern matches list items (bullets and numbered)
 var listItemPattern = regexp.MustCompile(`^[-*]|\d+\.`)
 
-// CountInstructions estimates the number of instructions in the content
+// CountInstructions estimates the number of instructions in the content.
+// Lines in code blocks, HTML blocks and tables are not instructions.
 func CountInstructions(lines []string) int {
+	return countInstructions(lines, ParseMarkdown(lines))
+}
+
+func countInstructions(lines []string, doc *MarkdownDoc) int {
 	count := 0
+	for _, isInstr := range instructionLines(lines, doc) {
+		if isInstr {
+			count++
+		}
+	}
+	return count
+}
+
+// instructionLines flags, per line, whether the line looks like an instruction
+func instructionLines(lines []string, doc *MarkdownDoc) []bool {
+	flags := make([]bool, len(lines))
 
-	for _, line := range lines {
+	for i, line := range lines {
+		if !doc.isInstructionLine(i + 1) {
+			continue
+		}
 		line = strings.TrimSpace(line)
 		if line == "" || strings.HasPrefix(line, "#") {
 			continue
 		}
 
 		if imperativeVerbPattern.MatchString(line) {
-			count++
+			flags[i] = true
 			continue
 		}
 
 		if listItemPattern.MatchString(line) {
 			trimmed := strings.TrimLeft(line, "-*0123456789. ")
 			if len(trimmed) > 10 {
-				count++
+				flags[i] = true
 			}
 		}
 	}
 
-	return count
+	return flags
+}
+
+// InstructionSpans locates the lines counted in ctx.InstructionCount, each
+// span covering the line's text
+func InstructionSpans(ctx *AnalysisContext) []Span {
+	var spans []Span
+	for i, isInstr := range instructionLines(ctx.Lines, ctx.Markdown) {
+		if !isInstr {
+			continue
+		}
+		line := ctx.Lines[i]
+		text := strings.TrimSpace(line)
+		indent := strings.Index(line, text)
+		spans = append(spans, Span{
+			Line:    i + 1,
+			Column:  utf8.RuneCountInString(line[:indent]) + 1,
+			Text:    text,
+			Snippet: text,
+		})
+	}
+	return spans
 }
 
 // hasProgressiveDisclosure checks if the content references other docs
@@ -62,6 +62,21 @@ func TestEvaluate(t *testing.T) {
 	})
 }
 
+func TestEvaluate_AttachesSpans(t *testing.T) {
+	engine := NewEngine([]Rule{
+		{Code: "R001", MatchSpec: MatchSpec{Action: ActionRegexMatch, Patterns: []string{"semicolons?"}}},
+		{Code: "R002", MatchSpec: MatchSpec{Action: ActionRegexMatch, Patterns: []string{"tabs"}}},
+	})
+	results := engine.Evaluate(makeCtx("# Style\nNever use semicolons", 2, 1, nil))
+
+	if len(results[0].Spans) != 1 || results[0].Spans[0].Line != 2 {
+		t.Errorf("R001: expected span on line 2, got %+v", results[0].Spans)
+	}
+	if results[1].Spans != nil {
+		t.Errorf("R002: expected no spans for an undetected rule, got %+v", results[1].Spans)
+	}
+}
+
 func TestEvaluateSecondary_SkipsPrimaryOnly(t *testing.T) {
 	engine := NewEngine([]Rule{
 		{Code: "R001", PrimaryOnly: true,
@@ -179,6 +194,17 @@ func TestCountInstructions(t *testing.T) {
 			"Some plain text.",
 			"- Prefer explicit error handling over panics",
 		}, 2, 4},
+		{"code blocks, HTML comments and tables excluded", []string{
+			"- Always run tests before committing",
+			"```bash",
+			"# use the race detector",
+			"- run make build",
+			"```",
+			"<!-- Always update this list -->",
+			"| Rule | Why |",
+			"|------|-----|",
+			"| Never use var | hoisting |",
+		}, 1, 1},
 	}
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
@@ -190,6 +216,18 @@ func TestCountInstructions(t *testing.T) {
 	}
 }
 
+func TestInstructionSpans(t *testing.T) {
+	content := "# Rules\n\n  - Always run tests before committing\n\n```sh\nrun make\n```\nSome plain text.\n"
+	ctx := BuildContext("CLAUDE.md", content)
+	spans := InstructionSpans(ctx)
+	if len(spans) != ctx.InstructionCount || len(spans) != 1 {
+		t.Fatalf("expected one span matching InstructionCount %d, got %+v", ctx.InstructionCount, spans)
+	}
+	if s := spans[0]; s.Line != 3 || s.Column != 3 || s.Text != "- Always run tests before committing" {
+		t.Errorf("unexpected span %+v", s)
+	}
+}
+
 // =============================================================================
 // BuildContext
 // =============================================================================
@@ -218,6 +256,9 @@ func TestBuildContext(t *testing.T) {
 		if _, ok := ctx.Metrics["progressiveDisclosureRefs"]; !ok {
 			t.Error("missing progressiveDisclosureRefs metric")
 		}
+		if ctx.Markdown == nil || !ctx.Markdown.InNode(1, NodeHeading) {
+			t.Error("expected parsed markdown with a heading on line 1")
+		}
 	})
 
 	t.Run("empty content", func(t *testing.T) {
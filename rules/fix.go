@@ -0,0 +1,358 @@
+package rules
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// FixAction defines how a rule's fix changes a file
+type FixAction string
+
+const (
+	FixDeleteLine  FixAction = "deleteLine"  // delete every line the rule matched
+	FixReplace     FixAction = "replace"     // rewrite matched lines with a regex template
+	FixMoveSection FixAction = "moveSection" // move the sections matching a heading regex
+)
+
+// Targets for FixMoveSection
+const (
+	FixTargetTop    = "top"
+	FixTargetBottom = "bottom"
+)
+
+// FixSpec describes an automatic fix for a rule's findings
+type FixSpec struct {
+	Action      FixAction `yaml:"action" json:"action"`
+	Pattern     string    `yaml:"pattern,omitempty" json:"pattern,omitempty"`         // replace: regex run on each matched line (default: the matched text)
+	Replacement string    `yaml:"replacement,omitempty" json:"replacement,omitempty"` // replace: template, $1 expands to the first group
+	Section     string    `yaml:"section,omitempty" json:"section,omitempty"`         // moveSection: heading regex of the sections to move
+	To          string    `yaml:"to,omitempty" json:"to,omitempty"`                   // moveSection: "top" (after the title) or "bottom"
+}
+
+// Validate checks that the fix has the fields its action needs
+func (f *FixSpec) Validate() error {
+	switch f.Action {
+	case FixDeleteLine:
+	case FixReplace:
+		if f.Pattern != "" {
+			if _, err := regexp.Compile("(?i)" + f.Pattern); err != nil {
+				return fmt.Errorf("invalid fix pattern: %w", err)
+			}
+		}
+	case FixMoveSection:
+		if f.Section == "" {
+			return fmt.Errorf("moveSection fix needs a section")
+		}
+		if f.To != FixTargetTop && f.To != FixTargetBottom {
+			return fmt.Errorf("moveSection fix needs to: top or to: bottom, got %q", f.To)
+		}
+	default:
+		return fmt.Errorf("unknown fix action %q", f.Action)
+	}
+	return nil
+}
+
+// AppliedFix records one change made by a fix
+type AppliedFix struct {
+	Code   string
+	Action FixAction
+	Line   int // line in the original file the change applies to
+}
+
+// FixResult is the outcome of fixing one file
+type FixResult struct {
+	Path      string
+	Original  string
+	Fixed     string
+	Applied   []AppliedFix
+	Remaining []string // codes of fixable rules that still fire after fixing
+}
+
+// Changed reports whether fixing changed the file
+func (r *FixResult) Changed() bool {
+	return r.Original != r.Fixed
+}
+
+// Fix applies the fixes of every detected rule to ctx's content, then
+// re-evaluates the fixed content to verify the fixes: applying them a second
+// time must not change anything. Detected rules without a fix are left alone.
+// Set secondary for referenced docs, which skip primaryOnly rules.
+func (e *Engine) Fix(ctx *AnalysisContext, secondary bool) (*FixResult, error) {
+	evaluate := e.Evaluate
+	if secondary {
+		evaluate = e.EvaluateSecondary
+	}
+
+	fixed, applied := ApplyFixes(ctx, evaluate(ctx))
+	res := &FixResult{
+		Path:     ctx.FilePath,
+		Original: ctx.Content,
+		Fixed:    fixed,
+		Applied:  applied,
+	}
+	if !res.Changed() {
+		return res, nil
+	}
+
+	verify := rebuildContext(ctx, fixed)
+	results := evaluate(verify)
+	again, reapplied := ApplyFixes(verify, results)
+	if again != fixed {
+		codes := make([]string, 0, len(reapplied))
+		for _, a := range reapplied {
+			codes = append(codes, a.Code)
+		}
+		return nil, fmt.Errorf("%s: fixes are not idempotent (%s changed the file again)", ctx.FilePath, strings.Join(uniqueStrings(codes), ", "))
+	}
+
+	for _, r := range results {
+		if r.Passed && r.Rule.Fix != nil && r.Rule.Category != "good-practice" {
+			res.Remaining = append(res.Remaining, r.Rule.Code)
+		}
+	}
+	return res, nil
+}
+
+// rebuildContext builds a context for new content, carrying over metrics that
+// come from outside the file (detected stacks, references, git activity)
+func rebuildContext(ctx *AnalysisContext, content string) *AnalysisContext {
+	rebuilt := BuildContext(ctx.FilePath, content)
+	for k, v := range ctx.Metrics {
+		if _, ok := rebuilt.Metrics[k]; !ok {
+			rebuilt.Metrics[k] = v
+		}
+	}
+	return rebuilt
+}
+
+// ApplyFixes returns ctx's content with the fixes of every detected rule applied.
+// Line fixes run first, against the original line numbers; when a line is both
+// deleted and replaced, the delete wins. Section moves run afterwards in rule order.
+func ApplyFixes(ctx *AnalysisContext, results []RuleResult) (string, []AppliedFix) {
+	lines := append([]string(nil), ctx.Lines...)
+	deleted := make(map[int]bool)
+	var applied []AppliedFix
+	var moves []RuleResult
+
+	for _, r := range results {
+		fix := r.Rule.Fix
+		if fix == nil || !r.Passed || r.Rule.Category == "good-practice" {
+			continue
+		}
+		switch fix.Action {
+		case FixDeleteLine:
+			for _, line := range spanLines(r.Spans) {
+				if !deleted[line] {
+					deleted[line] = true
+					applied = append(applied, AppliedFix{Code: r.Rule.Code, Action: fix.Action, Line: line})
+				}
+			}
+		case FixReplace:
+			for _, line := range spanLines(r.Spans) {
+				if deleted[line] || line > len(lines) {
+					continue
+				}
+				replaced := replaceLine(lines[line-1], r.Spans, line, fix)
+				if replaced != lines[line-1] {
+					lines[line-1] = replaced
+					applied = append(applied, AppliedFix{Code: r.Rule.Code, Action: fix.Action, Line: line})
+				}
+			}
+		case FixMoveSection:
+			moves = append(moves, r)
+		}
+	}
+
+	kept := lines[:0]
+	for i, l := range lines {
+		if !deleted[i+1] {
+			kept = append(kept, l)
+		}
+	}
+	content := strings.Join(kept, "\n")
+
+	for _, r := range moves {
+		var line int
+		content, line = moveSections(content, r.Rule.Fix)
+		if line > 0 {
+			applied = append(applied, AppliedFix{Code: r.Rule.Code, Action: FixMoveSection, Line: line})
+		}
+	}
+
+	return content, applied
+}
+
+// spanLines returns the distinct lines of spans in ascending order
+func spanLines(spans []Span) []int {
+	seen := make(map[int]bool)
+	var lines []int
+	for _, s := range spans {
+		if !seen[s.Line] {
+			seen[s.Line] = true
+			lines = append(lines, s.Line)
+		}
+	}
+	sort.Ints(lines)
+	return lines
+}
+
+// replaceLine applies a replace fix to one line. Without a pattern, each span
+// on the line has its matched text replaced.
+func replaceLine(line string, spans []Span, lineNum int, fix *FixSpec) string {
+	if fix.Pattern != "" {
+		re, err := regexp.Compile("(?i)" + fix.Pattern)
+		if err != nil {
+			return line
+		}
+		return re.ReplaceAllString(line, fix.Replacement)
+	}
+
+	// Replace right to left so earlier columns stay valid
+	runes := []rune(line)
+	for i := len(spans) - 1; i >= 0; i-- {
+		s := spans[i]
+		if s.Line != lineNum {
+			continue
+		}
+		start := s.Column - 1
+		end := start + len([]rune(s.Text))
+		if start < 0 || end > len(runes) || string(runes[start:end]) != s.Text {
+			continue
+		}
+		runes = append(runes[:start], append([]rune(fix.Replacement), runes[end:]...)...)
+	}
+	return string(runes)
+}
+
+// moveSections moves the sections matching fix.Section to the top or bottom of
+// content. It returns the new content and the original line of the first moved
+// section, or 0 when every section is already in place.
+func moveSections(content string, fix *FixSpec) (string, int) {
+	ctx := BuildContext("", content)
+	lines := ctx.Lines
+
+	var targets []*Section
+	for _, s := range ctx.Sections.FindSections(fix.Section) {
+		// Subsections move with their parent
+		if len(targets) > 0 && s.Line <= targets[len(targets)-1].EndLine {
+			continue
+		}
+		targets = append(targets, s)
+	}
+	if len(targets) == 0 {
+		return content, 0
+	}
+
+	moving := make(map[int]bool)
+	var block []string
+	for _, s := range targets {
+		for l := s.Line; l <= s.EndLine; l++ {
+			moving[l] = true
+			block = append(block, lines[l-1])
+		}
+	}
+	block = trimBlankEdges(block)
+
+	var rest []string
+	insertAt := -1 // index in rest the block goes before
+	anchor := topAnchor(ctx.Sections, targets)
+	for i, l := range lines {
+		if i+1 == anchor {
+			insertAt = len(rest)
+		}
+		if !moving[i+1] {
+			rest = append(rest, l)
+		}
+	}
+
+	var out []string
+	if fix.To == FixTargetBottom || insertAt < 0 {
+		out = append(trimTrailingBlank(rest), "")
+		out = append(out, block...)
+		if len(lines) > 0 && lines[len(lines)-1] == "" {
+			out = append(out, "")
+		}
+	} else {
+		out = append(out, rest[:insertAt]...)
+		out = append(out, block...)
+		out = append(out, "")
+		out = append(out, rest[insertAt:]...)
+	}
+
+	moved := strings.Join(out, "\n")
+	if moved == content || sameModuloBlankLines(moved, content) {
+		return content, 0
+	}
+	return moved, targets[0].Line
+}
+
+// topAnchor returns the line moved sections are inserted before when moving
+// to the top: the first heading after the document title, or the first
+// heading when there is no title. Sections being moved are skipped.
+func topAnchor(root *Section, targets []*Section) int {
+	isTarget := func(s *Section) bool {
+		for _, t := range targets {
+			if s.Line >= t.Line && s.Line <= t.EndLine {
+				return true
+			}
+		}
+		return false
+	}
+
+	var headings []*Section
+	root.Walk(func(s *Section) {
+		if s.Level > 0 {
+			headings = append(headings, s)
+		}
+	})
+
+	for i, h := range headings {
+		if i == 0 && h.Level == 1 && len(h.Children) > 0 && !isTarget(h) {
+			continue // the title stays first
+		}
+		return h.Line
+	}
+	return 0
+}
+
+func trimBlankEdges(lines []string) []string {
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	return trimTrailingBlank(lines)
+}
+
+func trimTrailingBlank(lines []string) []string {
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// sameModuloBlankLines reports whether a and b differ only in blank lines
+func sameModuloBlankLines(a, b string) bool {
+	strip := func(s string) string {
+		var kept []string
+		for _, l := range strings.Split(s, "\n") {
+			if strings.TrimSpace(l) != "" {
+				kept = append(kept, l)
+			}
+		}
+		return strings.Join(kept, "\n")
+	}
+	return strip(a) == strip(b)
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,249 @@
+package rules
+
+import (
+	"strings"
+	"testing"
+)
+
+// =============================================================================
+// FixSpec validation
+// =============================================================================
+
+func TestFixSpecValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		fix     FixSpec
+		wantErr bool
+	}{
+		{"deleteLine", FixSpec{Action: FixDeleteLine}, false},
+		{"replace without pattern", FixSpec{Action: FixReplace, Replacement: "x"}, false},
+		{"replace with invalid pattern", FixSpec{Action: FixReplace, Pattern: "("}, true},
+		{"moveSection to bottom", FixSpec{Action: FixMoveSection, Section: "Notes", To: FixTargetBottom}, false},
+		{"moveSection without section", FixSpec{Action: FixMoveSection, To: FixTargetTop}, true},
+		{"moveSection with bad target", FixSpec{Action: FixMoveSection, Section: "Notes", To: "middle"}, true},
+		{"unknown action", FixSpec{Action: "rewrite"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.fix.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBuiltinFixesAreValid(t *testing.T) {
+	rules, err := LoadBuiltinRules()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fixes := 0
+	for _, r := range rules {
+		if r.Fix != nil {
+			fixes++
+		}
+	}
+	if fixes == 0 {
+		t.Error("expected some builtin rules to declare a fix")
+	}
+}
+
+// =============================================================================
+// Engine.Fix
+// =============================================================================
+
+func fixRule(code string, patterns []string, fix *FixSpec) Rule {
+	return Rule{
+		Code:      code,
+		Severity:  SeverityWarning,
+		Category:  "style",
+		MatchSpec: MatchSpec{Action: ActionRegexMatch, Patterns: patterns},
+		Fix:       fix,
+	}
+}
+
+func TestFix_DeleteLine(t *testing.T) {
+	engine := NewEngine([]Rule{
+		fixRule("F001", []string{"use\\s+tabs"}, &FixSpec{Action: FixDeleteLine}),
+	})
+	ctx := BuildContext("CLAUDE.md", "# Project\n\n- Run make test\n- Use tabs for indentation\n- Keep PRs small\n")
+
+	res, err := engine.Fix(ctx, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "# Project\n\n- Run make test\n- Keep PRs small\n"
+	if res.Fixed != want {
+		t.Errorf("Fixed = %q, want %q", res.Fixed, want)
+	}
+	if len(res.Applied) != 1 || res.Applied[0].Line != 4 || res.Applied[0].Code != "F001" {
+		t.Errorf("unexpected applied fixes: %+v", res.Applied)
+	}
+	if len(res.Remaining) != 0 {
+		t.Errorf("expected no remaining findings, got %v", res.Remaining)
+	}
+}
+
+func TestFix_Replace(t *testing.T) {
+	t.Run("matched text", func(t *testing.T) {
+		engine := NewEngine([]Rule{
+			fixRule("F002", []string{"npm run"}, &FixSpec{Action: FixReplace, Replacement: "pnpm"}),
+		})
+		ctx := BuildContext("CLAUDE.md", "- Build: npm run build\n- Test: npm run test\n")
+
+		res, err := engine.Fix(ctx, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Fixed != "- Build: pnpm build\n- Test: pnpm test\n" {
+			t.Errorf("unexpected fixed content %q", res.Fixed)
+		}
+		if len(res.Applied) != 2 {
+			t.Errorf("expected 2 applied fixes, got %d", len(res.Applied))
+		}
+	})
+
+	t.Run("pattern with group", func(t *testing.T) {
+		engine := NewEngine([]Rule{
+			fixRule("F003", []string{"yarn \\w+"}, &FixSpec{Action: FixReplace, Pattern: "yarn (\\w+)", Replacement: "pnpm $1"}),
+		})
+		ctx := BuildContext("CLAUDE.md", "Run yarn lint before pushing")
+
+		res, err := engine.Fix(ctx, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Fixed != "Run pnpm lint before pushing" {
+			t.Errorf("unexpected fixed content %q", res.Fixed)
+		}
+	})
+}
+
+func TestFix_MoveSection(t *testing.T) {
+	content := strings.Join([]string{
+		"# Project",
+		"",
+		"## Notes",
+		"Historical notes.",
+		"",
+		"## Commands",
+		"- make test",
+		"",
+	}, "\n")
+
+	t.Run("to bottom", func(t *testing.T) {
+		engine := NewEngine([]Rule{
+			fixRule("F004", []string{"historical"}, &FixSpec{Action: FixMoveSection, Section: "Notes", To: FixTargetBottom}),
+		})
+		res, err := engine.Fix(BuildContext("CLAUDE.md", content), false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := "# Project\n\n## Commands\n- make test\n\n## Notes\nHistorical notes.\n"
+		if res.Fixed != want {
+			t.Errorf("Fixed = %q, want %q", res.Fixed, want)
+		}
+		if len(res.Applied) != 1 || res.Applied[0].Line != 3 {
+			t.Errorf("unexpected applied fixes: %+v", res.Applied)
+		}
+	})
+
+	t.Run("to top keeps the title first", func(t *testing.T) {
+		engine := NewEngine([]Rule{
+			fixRule("F005", []string{"make test"}, &FixSpec{Action: FixMoveSection, Section: "Commands", To: FixTargetTop}),
+		})
+		res, err := engine.Fix(BuildContext("CLAUDE.md", content), false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := "# Project\n\n## Commands\n- make test\n\n## Notes\nHistorical notes.\n"
+		if res.Fixed != want {
+			t.Errorf("Fixed = %q, want %q", res.Fixed, want)
+		}
+	})
+
+	t.Run("already in place", func(t *testing.T) {
+		engine := NewEngine([]Rule{
+			fixRule("F006", []string{"make test"}, &FixSpec{Action: FixMoveSection, Section: "Commands", To: FixTargetBottom}),
+		})
+		res, err := engine.Fix(BuildContext("CLAUDE.md", content), false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Changed() || len(res.Applied) != 0 {
+			t.Errorf("expected no change, got %q", res.Fixed)
+		}
+	})
+}
+
+func TestFix_ReportsRemainingFindings(t *testing.T) {
+	// The move keeps the matched text, so the rule still fires
+	engine := NewEngine([]Rule{
+		fixRule("F007", []string{"historical"}, &FixSpec{Action: FixMoveSection, Section: "Notes", To: FixTargetBottom}),
+	})
+	ctx := BuildContext("CLAUDE.md", "# Project\n\n## Notes\nHistorical notes.\n\n## Commands\n- make test\n")
+
+	res, err := engine.Fix(ctx, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Remaining) != 1 || res.Remaining[0] != "F007" {
+		t.Errorf("expected F007 to remain, got %v", res.Remaining)
+	}
+}
+
+func TestFix_NotIdempotent(t *testing.T) {
+	// Every pass adds another "x", so the re-run changes the file again
+	engine := NewEngine([]Rule{
+		fixRule("F008", []string{"x+"}, &FixSpec{Action: FixReplace, Pattern: "(x+)", Replacement: "${1}x"}),
+	})
+	ctx := BuildContext("CLAUDE.md", "value x")
+
+	if _, err := engine.Fix(ctx, false); err == nil || !strings.Contains(err.Error(), "F008") {
+		t.Errorf("expected an idempotency error naming F008, got %v", err)
+	}
+}
+
+func TestFix_SkipsRulesWithoutFix(t *testing.T) {
+	engine := NewEngine([]Rule{
+		fixRule("F009", []string{"tabs"}, nil),
+	})
+	ctx := BuildContext("CLAUDE.md", "Use tabs")
+
+	res, err := engine.Fix(ctx, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Changed() {
+		t.Errorf("expected no change, got %q", res.Fixed)
+	}
+}
+
+func TestFix_RespectsSuppressions(t *testing.T) {
+	engine := NewEngine([]Rule{
+		fixRule("F010", []string{"use\\s+tabs"}, &FixSpec{Action: FixDeleteLine}),
+	})
+	content := "<!-- context-doctor-disable-next-line F010 -->\nUse tabs for Makefiles\n"
+	res, err := engine.Fix(BuildContext("CLAUDE.md", content), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Changed() {
+		t.Errorf("expected a suppressed finding to be left alone, got %q", res.Fixed)
+	}
+}
+
+func TestFix_SecondarySkipsPrimaryOnlyRules(t *testing.T) {
+	rule := fixRule("F011", []string{"use\\s+tabs"}, &FixSpec{Action: FixDeleteLine})
+	rule.PrimaryOnly = true
+	engine := NewEngine([]Rule{rule})
+
+	res, err := engine.Fix(BuildContext("docs/style.md", "Use tabs"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Changed() {
+		t.Errorf("expected primaryOnly fix to be skipped for a referenced doc, got %q", res.Fixed)
+	}
+}
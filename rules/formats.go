@@ -0,0 +1,305 @@
+package rules
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultFormat is the format assumed for files matching no registered format
+const DefaultFormat = "claude"
+
+// ContextFormat describes a kind of agent context file
+type ContextFormat struct {
+	Name  string
+	Agent string // the agent that reads it, for display
+	// Patterns match a file's path relative to the directory it governs, in
+	// path.Match syntax, e.g. ".cursor/rules/*.mdc"
+	Patterns []string
+	// RootOnly formats are only read at the repository root
+	RootOnly bool
+	// Frontmatter formats may open with a YAML block between --- lines.
+	// Its lines are metadata, not instructions.
+	Frontmatter bool
+	// GlobsKey is the frontmatter key listing the files the context applies to
+	GlobsKey string
+	// Profile names the AgentProfile used for the format's files unless one
+	// is chosen explicitly
+	Profile string
+	// SkipRules are the codes of rules that don't apply to the format
+	SkipRules []string
+}
+
+// ContextFormatRegistry holds the known context formats by name
+var ContextFormatRegistry map[string]*ContextFormat
+
+func init() {
+	ContextFormatRegistry = map[string]*ContextFormat{}
+	for _, f := range []ContextFormat{
+		{
+			Name:     "claude",
+			Agent:    "Claude Code",
+			Patterns: []string{"CLAUDE.md", ".claude/CLAUDE.md", "CLAUDE.local.md"},
+			Profile:  "claude-code",
+		},
+		{
+			Name:     "agents",
+			Agent:    "Codex",
+			Patterns: []string{"AGENTS.md", "AGENTS.override.md"},
+			Profile:  "codex",
+		},
+		{
+			Name:     "gemini",
+			Agent:    "Gemini CLI",
+			Patterns: []string{"GEMINI.md"},
+			Profile:  "gemini-cli",
+		},
+		{
+			// Path-specific instruction files scope themselves with applyTo
+			Name:        "copilot",
+			Agent:       "Copilot",
+			Patterns:    []string{".github/copilot-instructions.md", ".github/instructions/*.instructions.md"},
+			RootOnly:    true,
+			Frontmatter: true,
+			GlobsKey:    "applyTo",
+			Profile:     "copilot",
+		},
+		{
+			// Project rules are small and scoped by globs, so they aren't
+			// expected to carry the project's build and test commands
+			Name:        "cursor",
+			Agent:       "Cursor",
+			Patterns:    []string{".cursor/rules/*.mdc", ".cursorrules"},
+			Frontmatter: true,
+			GlobsKey:    "globs",
+			Profile:     "cursor",
+			SkipRules:   stackSuggestionRules,
+		},
+		{
+			Name:        "windsurf",
+			Agent:       "Windsurf",
+			Patterns:    []string{".windsurfrules", ".windsurf/rules/*.md"},
+			RootOnly:    true,
+			Frontmatter: true,
+			GlobsKey:    "globs",
+			Profile:     "windsurf",
+		},
+		{
+			Name:     "cline",
+			Agent:    "Cline",
+			Patterns: []string{".clinerules", ".clinerules/*.md"},
+			RootOnly: true,
+			Profile:  "cline",
+		},
+		{
+			Name:     "aider",
+			Agent:    "Aider",
+			Patterns: []string{"CONVENTIONS.md"},
+			RootOnly: true,
+			Profile:  "aider",
+		},
+	} {
+		RegisterContextFormat(f)
+	}
+}
+
+// stackSuggestionRules are the builtin rules asking for stack-specific commands
+var stackSuggestionRules = []string{
+	"CD070", "CD071", "CD072", "CD073", "CD074", "CD075", "CD076", "CD077", "CD078", "CD079",
+}
+
+// RegisterContextFormat adds a format to the registry, replacing any format of the same name
+func RegisterContextFormat(f ContextFormat) {
+	ContextFormatRegistry[f.Name] = &f
+}
+
+// ContextFormatNames returns the registered format names, sorted
+func ContextFormatNames() []string {
+	names := make([]string, 0, len(ContextFormatRegistry))
+	for name := range ContextFormatRegistry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DetectFormat returns the format of the context file at path, or nil when
+// no registered format matches
+func DetectFormat(filePath string) *ContextFormat {
+	return matchFormat(filepath.ToSlash(filePath), false)
+}
+
+// ContextFileFormat returns the format of rel, a path relative to the repo
+// root, if an agent reads it as a context file. Unlike DetectFormat it
+// honours RootOnly.
+func ContextFileFormat(rel string) *ContextFormat {
+	return matchFormat(filepath.ToSlash(rel), true)
+}
+
+// FormatOf returns ctx's format, falling back to DefaultFormat
+func FormatOf(ctx *AnalysisContext) *ContextFormat {
+	if ctx != nil && ctx.Format != nil {
+		return ctx.Format
+	}
+	return ContextFormatRegistry[DefaultFormat]
+}
+
+func matchFormat(p string, rooted bool) *ContextFormat {
+	for _, name := range ContextFormatNames() {
+		f := ContextFormatRegistry[name]
+		for _, pattern := range f.Patterns {
+			if matchPathSuffix(pattern, p, rooted && f.RootOnly) {
+				return f
+			}
+		}
+	}
+	return nil
+}
+
+// matchPathSuffix matches pattern against the trailing components of p, or
+// against all of p when exact is set
+func matchPathSuffix(pattern, p string, exact bool) bool {
+	want := strings.Count(pattern, "/") + 1
+	parts := strings.Split(strings.TrimPrefix(p, "./"), "/")
+	if len(parts) < want || (exact && len(parts) != want) {
+		return false
+	}
+	ok, _ := path.Match(pattern, strings.Join(parts[len(parts)-want:], "/"))
+	return ok
+}
+
+// ContextFilePathspecs returns git pathspecs covering every registered
+// format's files. Git's * also matches /, so callers should confirm matches
+// with ContextFileFormat.
+func ContextFilePathspecs() []string {
+	var specs []string
+	for _, name := range ContextFormatNames() {
+		f := ContextFormatRegistry[name]
+		for _, pattern := range f.Patterns {
+			specs = append(specs, pattern)
+			if !f.RootOnly {
+				specs = append(specs, "*/"+pattern)
+			}
+		}
+	}
+	return specs
+}
+
+// IsContextFormatDir reports whether a directory name appears in a format's
+// patterns, for walkers that otherwise skip hidden directories
+func IsContextFormatDir(name string) bool {
+	for _, f := range ContextFormatRegistry {
+		for _, pattern := range f.Patterns {
+			dirs := strings.Split(pattern, "/")
+			for _, d := range dirs[:len(dirs)-1] {
+				if d == name {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// ScopeDir returns the directory a context file governs: its own directory,
+// or the one above the format's subdirectory, e.g. "pkg" for
+// "pkg/.cursor/rules/go.mdc"
+func ScopeDir(filePath string) string {
+	dir := filepath.Dir(filePath)
+	f := DetectFormat(filePath)
+	if f == nil {
+		return dir
+	}
+	// The longest matching pattern names the subdirectory, e.g.
+	// ".claude/CLAUDE.md" rather than "CLAUDE.md"
+	depth := 0
+	p := filepath.ToSlash(filePath)
+	for _, pattern := range f.Patterns {
+		if n := strings.Count(pattern, "/"); n > depth && matchPathSuffix(pattern, p, false) {
+			depth = n
+		}
+	}
+	for ; depth > 0; depth-- {
+		dir = filepath.Dir(dir)
+	}
+	return dir
+}
+
+// ApplicableRules returns the rules that apply to files of the format
+func (f *ContextFormat) ApplicableRules(rules []Rule) []Rule {
+	if f == nil || len(f.SkipRules) == 0 {
+		return rules
+	}
+	skip := make(map[string]bool, len(f.SkipRules))
+	for _, code := range f.SkipRules {
+		skip[code] = true
+	}
+	var applicable []Rule
+	for _, r := range rules {
+		if !skip[r.Code] {
+			applicable = append(applicable, r)
+		}
+	}
+	return applicable
+}
+
+// Frontmatter is the YAML metadata block opening a context file
+type Frontmatter struct {
+	EndLine int            // last line of the block, the closing ---
+	Fields  map[string]any // nil when the YAML is invalid
+	Globs   []string       // files the context applies to, from the format's GlobsKey
+	Err     error
+}
+
+// parseFrontmatter reads the YAML block between --- lines at the top of a
+// file. It returns nil when the file has none.
+func parseFrontmatter(lines []string, globsKey string) *Frontmatter {
+	if len(lines) == 0 || strings.TrimSpace(lines[0]) != "---" {
+		return nil
+	}
+	for i := 1; i < len(lines); i++ {
+		if trimmed := strings.TrimSpace(lines[i]); trimmed != "---" && trimmed != "..." {
+			continue
+		}
+		fm := &Frontmatter{EndLine: i + 1}
+		var fields map[string]any
+		if err := yaml.Unmarshal([]byte(strings.Join(lines[1:i], "\n")), &fields); err != nil {
+			fm.Err = fmt.Errorf("invalid frontmatter: %w", err)
+			return fm
+		}
+		if fields == nil {
+			fields = map[string]any{}
+		}
+		fm.Fields = fields
+		if globsKey != "" {
+			fm.Globs = frontmatterList(fields[globsKey])
+		}
+		return fm
+	}
+	return nil
+}
+
+// frontmatterList reads a list field written either as a YAML sequence or as
+// a comma-separated string, the way Cursor and Copilot accept globs
+func frontmatterList(v any) []string {
+	var items []string
+	switch val := v.(type) {
+	case string:
+		for _, s := range strings.Split(val, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				items = append(items, s)
+			}
+		}
+	case []any:
+		for _, item := range val {
+			if s, ok := item.(string); ok && strings.TrimSpace(s) != "" {
+				items = append(items, strings.TrimSpace(s))
+			}
+		}
+	}
+	return items
+}
@@ -0,0 +1,165 @@
+package rules
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"CLAUDE.md", "claude"},
+		{"pkg/api/CLAUDE.md", "claude"},
+		{".claude/CLAUDE.md", "claude"},
+		{"AGENTS.md", "agents"},
+		{"GEMINI.md", "gemini"},
+		{".github/copilot-instructions.md", "copilot"},
+		{".github/instructions/go.instructions.md", "copilot"},
+		{".cursor/rules/go.mdc", "cursor"},
+		{"web/.cursor/rules/react.mdc", "cursor"},
+		{".windsurfrules", "windsurf"},
+		{".clinerules", "cline"},
+		{".clinerules/testing.md", "cline"},
+		{"CONVENTIONS.md", "aider"},
+		{"README.md", ""},
+		{".cursor/rules/notes.md", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got := ""
+			if f := DetectFormat(tt.path); f != nil {
+				got = f.Name
+			}
+			if got != tt.want {
+				t.Errorf("DetectFormat(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContextFileFormat_RootOnly(t *testing.T) {
+	if ContextFileFormat(".github/copilot-instructions.md") == nil {
+		t.Error("expected the root copilot instructions to be a context file")
+	}
+	if f := ContextFileFormat("docs/.github/copilot-instructions.md"); f != nil {
+		t.Errorf("expected a nested copilot file to be ignored, got %s", f.Name)
+	}
+	if ContextFileFormat("pkg/GEMINI.md") == nil {
+		t.Error("expected nested GEMINI.md to be a context file")
+	}
+}
+
+func TestScopeDir(t *testing.T) {
+	tests := map[string]string{
+		"CLAUDE.md":                       ".",
+		"pkg/CLAUDE.md":                   "pkg",
+		"pkg/.claude/CLAUDE.md":           "pkg",
+		"web/.cursor/rules/react.mdc":     "web",
+		".github/copilot-instructions.md": ".",
+		"docs/guide.md":                   "docs",
+	}
+	for path, want := range tests {
+		if got := ScopeDir(path); got != want {
+			t.Errorf("ScopeDir(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestRegisterContextFormat(t *testing.T) {
+	defer delete(ContextFormatRegistry, "test")
+	RegisterContextFormat(ContextFormat{Name: "test", Agent: "Test", Patterns: []string{"TEST_AGENT.md"}})
+
+	if f := DetectFormat("sub/TEST_AGENT.md"); f == nil || f.Name != "test" {
+		t.Errorf("expected the registered format to be detected, got %v", f)
+	}
+	found := false
+	for _, spec := range ContextFilePathspecs() {
+		if spec == "*/TEST_AGENT.md" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("expected the registered format in the git pathspecs")
+	}
+}
+
+func TestApplicableRules(t *testing.T) {
+	all := []Rule{{Code: "CD001"}, {Code: "CD070"}, {Code: "CD079"}}
+
+	if got := ContextFormatRegistry["claude"].ApplicableRules(all); len(got) != 3 {
+		t.Errorf("expected claude to keep every rule, got %d", len(got))
+	}
+	got := ContextFormatRegistry["cursor"].ApplicableRules(all)
+	if len(got) != 1 || got[0].Code != "CD001" {
+		t.Errorf("expected cursor to skip the stack suggestions, got %v", got)
+	}
+}
+
+func TestBuildContext_Frontmatter(t *testing.T) {
+	content := strings.Join([]string{
+		"---",
+		"description: Go conventions",
+		"globs: \"**/*.go, **/go.mod\"",
+		"alwaysApply: false",
+		"---",
+		"- Always wrap errors with %w",
+		"- Run go vet before committing",
+	}, "\n")
+
+	ctx := BuildContext(".cursor/rules/go.mdc", content)
+	if ctx.Format == nil || ctx.Format.Name != "cursor" {
+		t.Fatalf("expected the cursor format, got %v", ctx.Format)
+	}
+	fm := ctx.Frontmatter
+	if fm == nil || fm.Err != nil {
+		t.Fatalf("expected parsed frontmatter, got %+v", fm)
+	}
+	if fm.EndLine != 5 || fm.Fields["description"] != "Go conventions" {
+		t.Errorf("unexpected frontmatter %+v", fm)
+	}
+	if want := []string{"**/*.go", "**/go.mod"}; !reflect.DeepEqual(fm.Globs, want) {
+		t.Errorf("Globs = %v, want %v", fm.Globs, want)
+	}
+	if ctx.InstructionCount != 2 {
+		t.Errorf("expected frontmatter lines not to count as instructions, got %d", ctx.InstructionCount)
+	}
+	if !ctx.Markdown.InNode(3, NodeFrontmatter) || ctx.Markdown.InNode(6, NodeFrontmatter) {
+		t.Error("expected lines 1-5 to be the frontmatter node")
+	}
+}
+
+func TestBuildContext_FrontmatterOnlyForDeclaringFormats(t *testing.T) {
+	// CLAUDE.md has no frontmatter, so a leading --- is an ordinary break
+	ctx := BuildContext("CLAUDE.md", "---\nAlways run tests\n---\n")
+	if ctx.Frontmatter != nil {
+		t.Errorf("expected no frontmatter for CLAUDE.md, got %+v", ctx.Frontmatter)
+	}
+	if ctx.InstructionCount != 1 {
+		t.Errorf("expected 1 instruction, got %d", ctx.InstructionCount)
+	}
+}
+
+func TestParseFrontmatter(t *testing.T) {
+	t.Run("sequence globs", func(t *testing.T) {
+		fm := parseFrontmatter([]string{"---", "applyTo:", "  - \"src/**\"", "  - docs/*.md", "---", "body"}, "applyTo")
+		if fm == nil || !reflect.DeepEqual(fm.Globs, []string{"src/**", "docs/*.md"}) {
+			t.Errorf("unexpected frontmatter %+v", fm)
+		}
+	})
+
+	t.Run("invalid yaml", func(t *testing.T) {
+		fm := parseFrontmatter([]string{"---", "globs: [unclosed", "---", "body"}, "globs")
+		if fm == nil || fm.Err == nil || fm.EndLine != 3 {
+			t.Errorf("expected an error spanning the block, got %+v", fm)
+		}
+	})
+
+	t.Run("unclosed", func(t *testing.T) {
+		if fm := parseFrontmatter([]string{"---", "globs: x", "body"}, "globs"); fm != nil {
+			t.Errorf("expected no frontmatter without a closing line, got %+v", fm)
+		}
+	})
+}
@@ -1,10 +1,5 @@
 package rules
 
-import (
-	"path/filepath"
-	"time"
-)
-
 // ScoreFromDays maps days since last modification to a freshness score.
 func ScoreFromDays(days int) int {
 	switch {
@@ -29,24 +24,10 @@ func ScoreFromDays(days int) int {
 // since the file was last modified in git. Returns (75, -1) if git history
 // is unavailable.
 func CalculateFreshnessScore(filePath string) (score int, days int) {
-	lastMod := getGitLastModified(filePath)
+	lastMod := activeSource.LastModified(filePath)
 	if lastMod.IsZero() {
 		return 75, -1
 	}
-	days = int(time.Since(lastMod).Hours() / 24)
+	days = int(activeSource.Now().Sub(lastMod).Hours() / 24)
 	return ScoreFromDays(days), days
 }
-
-// ScopeActivitySinceUpdate returns the number of commits in the CLAUDE.md's
-// directory since the file was last updated, and the number of days since
-// that update. Returns (0, -1) if git history is unavailable.
-func ScopeActivitySinceUpdate(filePath string) (scopeCommits int, daysSinceUpdate int) {
-	lastMod := getGitLastModified(filePath)
-	if lastMod.IsZero() {
-		return 0, -1
-	}
-	daysSinceUpdate = int(time.Since(lastMod).Hours() / 24)
-	dir := filepath.Dir(filePath)
-	scopeCommits = countCommitsSince(dir, lastMod.Format(time.RFC3339))
-	return scopeCommits, daysSinceUpdate
-}
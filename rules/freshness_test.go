@@ -1,7 +1,6 @@
 package rules
 
 import (
-	"path/filepath"
 	"testing"
 )
 
@@ -38,20 +37,3 @@ func TestScoreFromDays(t *testing.T) {
 		})
 	}
 }
-
-// =============================================================================
-// ScopeActivitySinceUpdate
-// =============================================================================
-
-func TestScopeActivitySinceUpdate_NoGitHistory(t *testing.T) {
-	tmpDir := t.TempDir()
-	fakePath := filepath.Join(tmpDir, "CLAUDE.md")
-
-	scopeCommits, days := ScopeActivitySinceUpdate(fakePath)
-	if scopeCommits != 0 {
-		t.Errorf("expected 0 scope commits, got %d", scopeCommits)
-	}
-	if days != -1 {
-		t.Errorf("expected -1 days, got %d", days)
-	}
-}
@@ -0,0 +1,221 @@
+package rules
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ScopeNode is a directory holding context files. Agents load the context
+// files of every directory from the repo root down to where they work, so a
+// nested file adds to its ancestors rather than replacing them.
+type ScopeNode struct {
+	Dir      string             // directory relative to the repo root ("." for the root)
+	Files    []*AnalysisContext // context files governing Dir, e.g. CLAUDE.md and .cursor/rules/*.mdc
+	Parent   *ScopeNode
+	Children []*ScopeNode
+}
+
+// Walk calls fn for the node and every node below it, parents first
+func (n *ScopeNode) Walk(fn func(*ScopeNode)) {
+	fn(n)
+	for _, c := range n.Children {
+		c.Walk(fn)
+	}
+}
+
+// BuildScopeTree arranges context files by the directory they govern. The
+// root node is always present, even without a context file of its own.
+func BuildScopeTree(root string, files []*AnalysisContext) *ScopeNode {
+	nodes := map[string]*ScopeNode{".": {Dir: "."}}
+	for _, f := range files {
+		dir := ScopeDir(relTo(root, f.FilePath))
+		n, ok := nodes[dir]
+		if !ok {
+			n = &ScopeNode{Dir: dir}
+			nodes[dir] = n
+		}
+		n.Files = append(n.Files, f)
+	}
+
+	dirs := make([]string, 0, len(nodes))
+	for dir := range nodes {
+		if dir != "." {
+			dirs = append(dirs, dir)
+		}
+	}
+	sort.Strings(dirs)
+
+	for _, dir := range dirs {
+		parent := filepath.Dir(dir)
+		for nodes[parent] == nil {
+			next := filepath.Dir(parent)
+			if next == parent {
+				parent = "." // outside root
+				break
+			}
+			parent = next
+		}
+		n := nodes[dir]
+		n.Parent = nodes[parent]
+		n.Parent.Children = append(n.Parent.Children, n)
+	}
+	return nodes["."]
+}
+
+// ancestorFiles returns the context files loaded together with f, nearest
+// first. Agents only read their own file name up the tree (Claude Code reads
+// CLAUDE.md, Codex reads AGENTS.md), so other names don't count.
+func (n *ScopeNode) ancestorFiles(f *AnalysisContext) []*AnalysisContext {
+	name := filepath.Base(f.FilePath)
+	var chain []*AnalysisContext
+	for p := n.Parent; p != nil; p = p.Parent {
+		for _, af := range p.Files {
+			if filepath.Base(af.FilePath) == name {
+				chain = append(chain, af)
+			}
+		}
+	}
+	return chain
+}
+
+// InstructionPair is an instruction in a nested file and the ancestor
+// instruction it repeats
+type InstructionPair struct {
+	Nested     InstructionLocation
+	Ancestor   InstructionLocation
+	Similarity float64
+}
+
+// ScopeOverlap lists the instructions a nested file repeats from an ancestor.
+// The ancestor is already loaded, so the copies only cost attention.
+type ScopeOverlap struct {
+	File     string
+	Ancestor string
+	Pairs    []InstructionPair
+}
+
+// ScopeConflict lists the instructions of a nested file that contradict an ancestor
+type ScopeConflict struct {
+	File      string
+	Ancestor  string
+	Conflicts []ConflictInfo // First is the ancestor's instruction, Second the nested one
+}
+
+// ScopeLoad is what an agent working in a file's directory loads
+type ScopeLoad struct {
+	File         string
+	Chain        []string // the file and its ancestors, nearest first
+	Instructions int
+	Tokens       int
+}
+
+// HierarchyAnalysis is the repo's context files checked as a tree of scopes
+type HierarchyAnalysis struct {
+	Root      *ScopeNode
+	Overlaps  []ScopeOverlap
+	Conflicts []ScopeConflict
+	Loads     []ScopeLoad // one per context file, in tree order
+}
+
+// AnalyzeHierarchy compares every context file with the ancestors it is loaded
+// with. Instructions at least threshold similar (see Similarity) count as
+// repeated. Paths in the result are relative to root.
+func AnalyzeHierarchy(root string, files []*AnalysisContext, threshold float64) *HierarchyAnalysis {
+	ha := &HierarchyAnalysis{Root: BuildScopeTree(root, files)}
+
+	ha.Root.Walk(func(n *ScopeNode) {
+		for _, f := range n.Files {
+			path := relTo(root, f.FilePath)
+			ancestors := n.ancestorFiles(f)
+
+			load := ScopeLoad{
+				File:         path,
+				Chain:        []string{path},
+				Instructions: f.InstructionCount,
+				Tokens:       f.TokenCount,
+			}
+			for _, a := range ancestors {
+				aPath := relTo(root, a.FilePath)
+				load.Chain = append(load.Chain, aPath)
+				load.Instructions += a.InstructionCount
+				load.Tokens += a.TokenCount
+
+				if pairs := repeatedInstructions(path, f, aPath, a, threshold); len(pairs) > 0 {
+					ha.Overlaps = append(ha.Overlaps, ScopeOverlap{File: path, Ancestor: aPath, Pairs: pairs})
+				}
+				if conflicts := contradictingInstructions(path, f, aPath, a); len(conflicts) > 0 {
+					ha.Conflicts = append(ha.Conflicts, ScopeConflict{File: path, Ancestor: aPath, Conflicts: conflicts})
+				}
+			}
+			ha.Loads = append(ha.Loads, load)
+		}
+	})
+	return ha
+}
+
+// repeatedInstructions pairs each instruction of nested with the most similar
+// instruction of ancestor, when they are at least threshold similar
+func repeatedInstructions(nestedPath string, nested *AnalysisContext, ancestorPath string, ancestor *AnalysisContext, threshold float64) []InstructionPair {
+	type candidate struct {
+		loc   InstructionLocation
+		words wordSet
+	}
+	var inherited []candidate
+	for _, instr := range extractInstructions(ancestor.Lines, ancestor.Markdown) {
+		if normalized := normalizeInstruction(instr.Text); normalized != "" {
+			inherited = append(inherited, candidate{
+				loc:   InstructionLocation{File: ancestorPath, Line: instr.Line, Text: instr.Text},
+				words: instructionWords(normalized),
+			})
+		}
+	}
+
+	var pairs []InstructionPair
+	for _, instr := range extractInstructions(nested.Lines, nested.Markdown) {
+		normalized := normalizeInstruction(instr.Text)
+		if normalized == "" {
+			continue
+		}
+		words := instructionWords(normalized)
+
+		best, bestScore := -1, 0.0
+		for i, c := range inherited {
+			if score := words.similarity(c.words); score >= threshold && score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+		if best >= 0 {
+			pairs = append(pairs, InstructionPair{
+				Nested:     InstructionLocation{File: nestedPath, Line: instr.Line, Text: instr.Text},
+				Ancestor:   inherited[best].loc,
+				Similarity: bestScore,
+			})
+		}
+	}
+	return pairs
+}
+
+// contradictingInstructions pairs instructions of nested that contradict ancestor
+func contradictingInstructions(nestedPath string, nested *AnalysisContext, ancestorPath string, ancestor *AnalysisContext) []ConflictInfo {
+	inherited := parseInstructions(ancestorPath, ancestor)
+
+	var conflicts []ConflictInfo
+	for _, b := range parseInstructions(nestedPath, nested) {
+		for _, a := range inherited {
+			if subject, ok := contradicts(a, b); ok {
+				conflicts = append(conflicts, ConflictInfo{Subject: subject, First: a.Loc, Second: b.Loc})
+			}
+		}
+	}
+	return conflicts
+}
+
+// relTo returns path relative to root, falling back to path itself
+func relTo(root, path string) string {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return path
+	}
+	return rel
+}
@@ -0,0 +1,99 @@
+package rules
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func hierarchyFiles(root string, files map[string]string) []*AnalysisContext {
+	var contexts []*AnalysisContext
+	for path, content := range files {
+		contexts = append(contexts, BuildContext(filepath.Join(root, path), content))
+	}
+	return contexts
+}
+
+func TestBuildScopeTree(t *testing.T) {
+	root := "/repo"
+	tree := BuildScopeTree(root, hierarchyFiles(root, map[string]string{
+		"packages/api/CLAUDE.md":          "# API",
+		"packages/api/internal/CLAUDE.md": "# Internal",
+		"packages/web/deep/AGENTS.md":     "# Web",
+	}))
+
+	if tree.Dir != "." || len(tree.Files) != 0 {
+		t.Fatalf("expected an empty root scope, got %q with %d files", tree.Dir, len(tree.Files))
+	}
+
+	var dirs []string
+	tree.Walk(func(n *ScopeNode) {
+		parent := ""
+		if n.Parent != nil {
+			parent = n.Parent.Dir
+		}
+		dirs = append(dirs, n.Dir+"<"+parent)
+	})
+	// Directories without context files are skipped: deep/ hangs off the root
+	want := []string{".<", "packages/api<.", "packages/api/internal<packages/api", "packages/web/deep<."}
+	if !reflect.DeepEqual(dirs, want) {
+		t.Errorf("scopes = %v, want %v", dirs, want)
+	}
+}
+
+func TestAnalyzeHierarchy(t *testing.T) {
+	root := "/repo"
+	files := hierarchyFiles(root, map[string]string{
+		"CLAUDE.md": "# Repo\n\n- Run make test before committing changes\n- Use pnpm for all package scripts\n",
+		"pkg/CLAUDE.md": "# Pkg\n\n- Run make test before committing any changes\n- Never use pnpm\n" +
+			"- Handlers live in internal/http\n",
+		// Codex doesn't read CLAUDE.md, so AGENTS.md is not compared with it
+		"pkg/AGENTS.md": "# Pkg\n\n- Use npm for all package scripts\n",
+	})
+
+	ha := AnalyzeHierarchy(root, files, DefaultSimilarityThreshold)
+
+	if len(ha.Overlaps) != 1 {
+		t.Fatalf("expected 1 overlap, got %+v", ha.Overlaps)
+	}
+	o := ha.Overlaps[0]
+	if o.File != "pkg/CLAUDE.md" || o.Ancestor != "CLAUDE.md" || len(o.Pairs) != 1 {
+		t.Errorf("unexpected overlap %+v", o)
+	}
+	if o.Pairs[0].Nested.Line != 3 || o.Pairs[0].Ancestor.Line != 3 {
+		t.Errorf("unexpected pair %+v", o.Pairs[0])
+	}
+
+	if len(ha.Conflicts) != 1 || len(ha.Conflicts[0].Conflicts) != 1 {
+		t.Fatalf("expected 1 conflict, got %+v", ha.Conflicts)
+	}
+	c := ha.Conflicts[0].Conflicts[0]
+	if c.First.File != "CLAUDE.md" || c.Second.File != "pkg/CLAUDE.md" || !strings.Contains(c.Second.Text, "Never use pnpm") {
+		t.Errorf("unexpected conflict %+v", c)
+	}
+
+	loads := make(map[string]ScopeLoad)
+	for _, l := range ha.Loads {
+		loads[l.File] = l
+	}
+	nested := loads["pkg/CLAUDE.md"]
+	if !reflect.DeepEqual(nested.Chain, []string{"pkg/CLAUDE.md", "CLAUDE.md"}) {
+		t.Errorf("unexpected chain %v", nested.Chain)
+	}
+	var rootCtx, pkgCtx *AnalysisContext
+	for _, f := range files {
+		switch f.FilePath {
+		case "/repo/CLAUDE.md":
+			rootCtx = f
+		case "/repo/pkg/CLAUDE.md":
+			pkgCtx = f
+		}
+	}
+	if nested.Instructions != rootCtx.InstructionCount+pkgCtx.InstructionCount {
+		t.Errorf("expected the load to sum both files, got %d", nested.Instructions)
+	}
+	if len(loads["pkg/AGENTS.md"].Chain) != 1 {
+		t.Errorf("expected AGENTS.md to have no ancestors, got %v", loads["pkg/AGENTS.md"].Chain)
+	}
+}
@@ -0,0 +1,55 @@
+package rules
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Revision is a commit that changed a file
+type Revision struct {
+	Commit string
+	Time   time.Time
+	Author string
+	Path   string // the file's repo-relative path in the commit
+}
+
+// FileRevisions lists the commits that changed the file at rel in the
+// repository at root, oldest first. Renames are followed, so earlier
+// revisions may have another Path; commits deleting the file are left out.
+func FileRevisions(root, rel string) ([]Revision, error) {
+	out, err := gitBytes(root, "log", "--follow", "--format=%x01%H%x00%ct%x00%an", "--name-status", "-z", "--", rel)
+	if err != nil {
+		return nil, fmt.Errorf("reading the history of %s: %w", rel, err)
+	}
+
+	var revs []Revision
+	// Each commit is "\x01<hash>\0<time>\0<author>\0\n<status>\0<path>\0",
+	// with the old and new path for renames
+	for _, block := range strings.Split(string(out), "\x01")[1:] {
+		fields := strings.Split(strings.TrimRight(block, "\x00"), "\x00")
+		if len(fields) < 5 {
+			continue
+		}
+		status := strings.TrimSpace(fields[3])
+		if strings.HasPrefix(status, "D") {
+			continue
+		}
+		unix, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		revs = append(revs, Revision{
+			Commit: fields[0],
+			Time:   time.Unix(unix, 0),
+			Author: fields[2],
+			Path:   fields[len(fields)-1],
+		})
+	}
+
+	for i, j := 0, len(revs)-1; i < j; i, j = i+1, j-1 {
+		revs[i], revs[j] = revs[j], revs[i]
+	}
+	return revs, nil
+}
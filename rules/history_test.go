@@ -0,0 +1,34 @@
+package rules
+
+import (
+	"testing"
+)
+
+func TestFileRevisions(t *testing.T) {
+	dir, run := gitRepo(t)
+	writeFiles(t, dir, map[string]string{"CLAUDE.md": "one"})
+	run("add", ".")
+	run("commit", "-qm", "first")
+	writeFiles(t, dir, map[string]string{"other.txt": "x"})
+	run("add", ".")
+	run("commit", "-qm", "unrelated")
+	run("mv", "CLAUDE.md", "AGENTS.md")
+	run("commit", "-qm", "rename")
+	writeFiles(t, dir, map[string]string{"AGENTS.md": "two"})
+	run("commit", "-qam", "edit")
+
+	revs, err := FileRevisions(dir, "AGENTS.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, r := range revs {
+		paths = append(paths, r.Path)
+		if r.Author != "Test" || len(r.Commit) != 40 || r.Time.IsZero() {
+			t.Errorf("unexpected revision %+v", r)
+		}
+	}
+	if len(paths) != 3 || paths[0] != "CLAUDE.md" || paths[1] != "AGENTS.md" || paths[2] != "AGENTS.md" {
+		t.Errorf("expected the renamed history oldest first, got %v", paths)
+	}
+}
@@ -0,0 +1,114 @@
+package rules
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// maxImportDepth is how many hops of nested @imports Claude Code follows
+const maxImportDepth = 5
+
+// importPattern matches Claude Code @path imports: an @ at the start of a line
+// or after whitespace, followed by a path (e.g. @docs/setup.md, @~/.claude/my.md)
+var importPattern = regexp.MustCompile(`(?:^|[\s(])@(~?[\w./-]+)`)
+
+// codeSpanPattern matches inline code, where @ is not an import
+var codeSpanPattern = regexp.MustCompile("`[^`]*`")
+
+// ExpandedLine is a line of a context file with its @imports expanded inline
+type ExpandedLine struct {
+	File          string // file the line comes from
+	Line          int    // 1-based line number within File
+	Text          string
+	IsInstruction bool
+	Depth         int // 0 for the primary file, 1 for its imports, ...
+}
+
+// FindImports returns the @import paths on a line, skipping inline code
+func FindImports(line string) []string {
+	line = codeSpanPattern.ReplaceAllString(line, "")
+	var paths []string
+	for _, m := range importPattern.FindAllStringSubmatch(line, -1) {
+		path := strings.TrimRight(m[1], ".,;:")
+		// A bare word is a mention (@alice), not a file
+		if !strings.ContainsAny(path, "./") {
+			continue
+		}
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+// findImportPaths returns the @import paths of a file, skipping code blocks
+func findImportPaths(lines []string, doc *MarkdownDoc) []string {
+	var paths []string
+	for i, line := range lines {
+		if doc.isInstructionLine(i + 1) {
+			paths = append(paths, FindImports(line)...)
+		}
+	}
+	return paths
+}
+
+// resolveImportPath resolves an import relative to the importing file's directory
+func resolveImportPath(path, baseDir string) string {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, rest)
+		}
+	}
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(baseDir, path)
+}
+
+// ExpandImports returns the lines of ctx in the order the agent reads them:
+// each imported file follows the line importing it, recursively up to
+// maxImportDepth. Imports in code blocks are ignored, missing files are
+// skipped and a file already on the import chain is not expanded again.
+func ExpandImports(ctx *AnalysisContext, baseDir string) []ExpandedLine {
+	seen := make(map[string]bool)
+	if abs, err := filepath.Abs(ctx.FilePath); err == nil {
+		seen[abs] = true
+	}
+	return expandImports(ctx, ctx.FilePath, baseDir, 0, seen)
+}
+
+func expandImports(ctx *AnalysisContext, path, baseDir string, depth int, seen map[string]bool) []ExpandedLine {
+	doc := markdownOf(ctx)
+	instr := instructionLines(ctx.Lines, doc)
+
+	var out []ExpandedLine
+	for i, line := range ctx.Lines {
+		out = append(out, ExpandedLine{
+			File:          path,
+			Line:          i + 1,
+			Text:          line,
+			IsInstruction: instr[i],
+			Depth:         depth,
+		})
+
+		if depth >= maxImportDepth || !doc.isInstructionLine(i+1) {
+			continue
+		}
+		for _, imp := range FindImports(line) {
+			resolved := resolveImportPath(imp, baseDir)
+			abs, err := filepath.Abs(resolved)
+			if err != nil || seen[abs] {
+				continue
+			}
+			content, err := activeSource.ReadFile(resolved)
+			if err != nil {
+				continue
+			}
+			seen[abs] = true
+			child := BuildContext(resolved, string(content))
+			out = append(out, expandImports(child, resolved, filepath.Dir(resolved), depth+1, seen)...)
+			delete(seen, abs)
+		}
+	}
+	return out
+}
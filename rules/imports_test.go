@@ -0,0 +1,62 @@
+package rules
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFindImports(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{"@docs/setup.md", []string{"docs/setup.md"}},
+		{"See @README.md and @~/.claude/my.md.", []string{"README.md", "~/.claude/my.md"}},
+		{"Mail me at dev@example.com", nil},
+		{"Ask @alice for access", nil},
+		{"Use `@decorator` syntax", nil},
+	}
+
+	for _, tt := range tests {
+		if got := FindImports(tt.line); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("FindImports(%q) = %v, want %v", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestExpandImports(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("docs/a.md", "A1\n@b.md")
+	write("docs/b.md", "B1\n@../CLAUDE.md")
+	write("CLAUDE.md", "top\n@docs/a.md\n```\n@docs/a.md\n```\nbottom")
+
+	primary := filepath.Join(dir, "CLAUDE.md")
+	content, _ := os.ReadFile(primary)
+	lines := ExpandImports(BuildContext(primary, string(content)), dir)
+
+	var got []string
+	for _, l := range lines {
+		got = append(got, l.Text)
+	}
+	// b.md imports the primary file again, which is a cycle and isn't expanded;
+	// the import in the code block is ignored
+	want := []string{"top", "@docs/a.md", "A1", "@b.md", "B1", "@../CLAUDE.md", "```", "@docs/a.md", "```", "bottom"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expanded lines = %q, want %q", got, want)
+	}
+	if lines[4].Depth != 2 || filepath.Base(lines[4].File) != "b.md" || lines[4].Line != 1 {
+		t.Errorf("unexpected origin for B1: %+v", lines[4])
+	}
+}
@@ -19,6 +19,9 @@ func LoadBuiltinRules() ([]Rule, error) {
 	if err := yaml.Unmarshal(builtinRulesYAML, &rulesFile); err != nil {
 		return nil, fmt.Errorf("failed to parse builtin rules: %w", err)
 	}
+	if err := validateFixes(rulesFile.Rules); err != nil {
+		return nil, fmt.Errorf("invalid builtin rules: %w", err)
+	}
 	return rulesFile.Rules, nil
 }
 
@@ -45,9 +48,25 @@ func LoadRulesFromFile(path string) ([]Rule, error) {
 		return nil, fmt.Errorf("unsupported file format: %s", ext)
 	}
 
+	if err := validateFixes(rulesFile.Rules); err != nil {
+		return nil, err
+	}
 	return rulesFile.Rules, nil
 }
 
+// validateFixes checks the fix block of every rule that has one
+func validateFixes(rules []Rule) error {
+	for _, r := range rules {
+		if r.Fix == nil {
+			continue
+		}
+		if err := r.Fix.Validate(); err != nil {
+			return fmt.Errorf("rule %s: %w", r.Code, err)
+		}
+	}
+	return nil
+}
+
 // DiscoverCustomRules finds and loads custom rules from a directory
 func DiscoverCustomRules(dir string) ([]Rule, error) {
 	var allRules []Rule
@@ -0,0 +1,523 @@
+package rules
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// NodeType names a markdown block node
+type NodeType string
+
+const (
+	NodeDocument      NodeType = "document"
+	NodeHeading       NodeType = "heading"
+	NodeParagraph     NodeType = "paragraph"
+	NodeList          NodeType = "list"
+	NodeListItem      NodeType = "list_item"
+	NodeBlockQuote    NodeType = "blockquote"
+	NodeCodeBlock     NodeType = "code_block"
+	NodeHTMLBlock     NodeType = "html_block"
+	NodeTable         NodeType = "table"
+	NodeThematicBreak NodeType = "thematic_break"
+	NodeFrontmatter   NodeType = "frontmatter" // YAML metadata opening the file, for formats that declare it
+
+	// NodeAll selects every line, including code and HTML, in MatchSpec.Nodes
+	NodeAll NodeType = "all"
+)
+
+// Node bits mark the nodes enclosing a line, so they fit in one mask
+const (
+	bitHeading uint16 = 1 << iota
+	bitParagraph
+	bitList
+	bitListItem
+	bitBlockQuote
+	bitCodeBlock
+	bitHTMLBlock
+	bitTable
+	bitThematicBreak
+	bitFrontmatter
+)
+
+// nodeBits gives each node type its bit
+var nodeBits = map[NodeType]uint16{
+	NodeHeading:       bitHeading,
+	NodeParagraph:     bitParagraph,
+	NodeList:          bitList,
+	NodeListItem:      bitListItem,
+	NodeBlockQuote:    bitBlockQuote,
+	NodeCodeBlock:     bitCodeBlock,
+	NodeHTMLBlock:     bitHTMLBlock,
+	NodeTable:         bitTable,
+	NodeThematicBreak: bitThematicBreak,
+	NodeFrontmatter:   bitFrontmatter,
+}
+
+// hiddenNodes are skipped unless a spec targets them explicitly: text in code
+// samples, HTML comments and frontmatter isn't an instruction to the agent
+const hiddenNodes = bitCodeBlock | bitHTMLBlock | bitFrontmatter
+
+// nonInstructionNodes hold lines that never count as instructions
+const nonInstructionNodes = hiddenNodes | bitTable
+
+// MarkdownNode is a block in the document tree. Containers (lists, list items
+// and block quotes) hold their content as children.
+type MarkdownNode struct {
+	Type     NodeType
+	Line     int    // first line, 1-based
+	EndLine  int    // last line, inclusive
+	Level    int    // heading level 1-6
+	Info     string // fenced code info string, e.g. "bash"
+	Text     string // heading text
+	Children []*MarkdownNode
+}
+
+// MarkdownDoc is a parsed markdown file
+type MarkdownDoc struct {
+	Root     *MarkdownNode
+	source   []string
+	lines    []uint16          // per line, the bits of every node enclosing it
+	selected map[string]string // Select results, keyed by node types
+}
+
+// ParseMarkdown parses lines into a block tree with a small line-based parser
+// for the blocks context files use: headings, lists, quotes, fenced and
+// indented code, HTML blocks, thematic breaks and pipe tables. It is not a
+// full CommonMark implementation (e.g. lazy continuation lines and link
+// reference definitions aren't recognised) and inline markup is left as text.
+func ParseMarkdown(lines []string) *MarkdownDoc {
+	return parseMarkdown(lines, 0)
+}
+
+// parseMarkdown parses lines whose first frontmatterEnd lines are a
+// frontmatter block
+func parseMarkdown(lines []string, frontmatterEnd int) *MarkdownDoc {
+	var children []*MarkdownNode
+	if frontmatterEnd > 0 {
+		children = append(children, &MarkdownNode{Type: NodeFrontmatter, Line: 1, EndLine: frontmatterEnd})
+	}
+	children = append(children, parseBlocks(lines[frontmatterEnd:], frontmatterEnd)...)
+
+	doc := &MarkdownDoc{
+		Root: &MarkdownNode{
+			Type:     NodeDocument,
+			Line:     1,
+			EndLine:  len(lines),
+			Children: children,
+		},
+		source: lines,
+		lines:  make([]uint16, len(lines)),
+	}
+	doc.Walk(func(n *MarkdownNode) bool {
+		bit := nodeBits[n.Type]
+		for l := n.Line; l <= n.EndLine && l <= len(doc.lines); l++ {
+			if l >= 1 {
+				doc.lines[l-1] |= bit
+			}
+		}
+		return true
+	})
+	return doc
+}
+
+// Walk visits every node depth-first; returning false skips a node's children
+func (d *MarkdownDoc) Walk(fn func(*MarkdownNode) bool) {
+	var walk func(n *MarkdownNode)
+	walk = func(n *MarkdownNode) {
+		if !fn(n) {
+			return
+		}
+		for _, c := range n.Children {
+			walk(c)
+		}
+	}
+	walk(d.Root)
+}
+
+// InNode reports whether a line (1-based) lies inside a node of the given type
+func (d *MarkdownDoc) InNode(line int, t NodeType) bool {
+	if line < 1 || line > len(d.lines) {
+		return false
+	}
+	return d.lines[line-1]&nodeBits[t] != 0
+}
+
+// isInstructionLine reports whether a line may hold an instruction, i.e. isn't
+// part of a code block, HTML block or table
+func (d *MarkdownDoc) isInstructionLine(line int) bool {
+	if d == nil || line < 1 || line > len(d.lines) {
+		return true
+	}
+	return d.lines[line-1]&nonInstructionNodes == 0
+}
+
+// Select returns the lines matching the node types with every other line
+// blanked, so line numbers stay valid. With no types it keeps everything but
+// code and HTML blocks; those are only kept when targeted (or with "all").
+func (d *MarkdownDoc) Select(types []NodeType) string {
+	key := fmt.Sprint(types)
+	if content, ok := d.selected[key]; ok {
+		return content
+	}
+	content := d.selectLines(types)
+	if d.selected == nil {
+		d.selected = make(map[string]string)
+	}
+	d.selected[key] = content
+	return content
+}
+
+func (d *MarkdownDoc) selectLines(types []NodeType) string {
+	lines := d.source
+	var want uint16
+	for _, t := range types {
+		if t == NodeAll {
+			return strings.Join(lines, "\n")
+		}
+		want |= nodeBits[t]
+	}
+
+	out := make([]string, len(lines))
+	for i, line := range lines {
+		var mask uint16
+		if i < len(d.lines) {
+			mask = d.lines[i]
+		}
+		keep := mask&hiddenNodes == 0
+		if len(types) > 0 {
+			keep = mask&want != 0 && mask&hiddenNodes&^want == 0
+		}
+		if keep {
+			out[i] = line
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+var (
+	atxHeadingPattern    = regexp.MustCompile(`^(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	thematicBreakPattern = regexp.MustCompile(`^(?:(?:\*[ \t]*){3,}|(?:-[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+	setextPattern        = regexp.MustCompile(`^(=+|-+)[ \t]*$`)
+	listMarkerPattern    = regexp.MustCompile(`^([-*+]|\d{1,9}[.)])([ \t]+|$)`)
+	htmlTagPattern       = regexp.MustCompile(`^</?[A-Za-z][A-Za-z0-9-]*(?:[ \t/>]|$)`)
+	tableDelimPattern    = regexp.MustCompile(`^\|?[ \t]*:?-+:?[ \t]*(\|[ \t]*:?-+:?[ \t]*)*\|?$`)
+)
+
+// indentWidth returns the leading whitespace width (tabs count as 4) and the rest of the line
+func indentWidth(line string) (int, string) {
+	width := 0
+	for i, r := range line {
+		switch r {
+		case ' ':
+			width++
+		case '\t':
+			width += 4 - width%4
+		default:
+			return width, line[i:]
+		}
+	}
+	return width, ""
+}
+
+// dedent strips up to n columns of leading whitespace
+func dedent(line string, n int) string {
+	width := 0
+	for i, r := range line {
+		if width >= n {
+			return line[i:]
+		}
+		switch r {
+		case ' ':
+			width++
+		case '\t':
+			width += 4 - width%4
+		default:
+			return line[i:]
+		}
+	}
+	return ""
+}
+
+func isBlank(line string) bool {
+	return strings.TrimSpace(line) == ""
+}
+
+// fenceOpen returns the fence character and length when line opens a code fence
+func fenceOpen(rest string) (byte, int, string, bool) {
+	if len(rest) < 3 || (rest[0] != '`' && rest[0] != '~') {
+		return 0, 0, "", false
+	}
+	ch := rest[0]
+	n := 0
+	for n < len(rest) && rest[n] == ch {
+		n++
+	}
+	if n < 3 {
+		return 0, 0, "", false
+	}
+	info := strings.TrimSpace(rest[n:])
+	if ch == '`' && strings.Contains(info, "`") {
+		return 0, 0, "", false
+	}
+	if fields := strings.Fields(info); len(fields) > 0 {
+		info = fields[0]
+	}
+	return ch, n, info, true
+}
+
+func isFenceClose(rest string, ch byte, n int) bool {
+	count := 0
+	for count < len(rest) && rest[count] == ch {
+		count++
+	}
+	return count >= n && strings.TrimSpace(rest[count:]) == ""
+}
+
+// startsBlock reports whether a line would interrupt a paragraph
+func startsBlock(line string) bool {
+	indent, rest := indentWidth(line)
+	if indent >= 4 || rest == "" {
+		return false
+	}
+	if _, _, _, ok := fenceOpen(rest); ok {
+		return true
+	}
+	return atxHeadingPattern.MatchString(rest) ||
+		thematicBreakPattern.MatchString(rest) ||
+		strings.HasPrefix(rest, ">") ||
+		strings.HasPrefix(rest, "<!--") ||
+		listMarkerPattern.MatchString(rest)
+}
+
+// parseBlocks parses container content; offset is the line number before lines[0]
+func parseBlocks(lines []string, offset int) []*MarkdownNode {
+	var nodes []*MarkdownNode
+	i := 0
+
+	for i < len(lines) {
+		line := lines[i]
+		if isBlank(line) {
+			i++
+			continue
+		}
+		indent, rest := indentWidth(line)
+		start := offset + i + 1
+
+		// Indented code block
+		if indent >= 4 {
+			end := i
+			for j := i; j < len(lines); j++ {
+				if isBlank(lines[j]) {
+					continue
+				}
+				if w, _ := indentWidth(lines[j]); w < 4 {
+					break
+				}
+				end = j
+			}
+			nodes = append(nodes, &MarkdownNode{Type: NodeCodeBlock, Line: start, EndLine: offset + end + 1})
+			i = end + 1
+			continue
+		}
+
+		// Fenced code block, running to the end of the container when unclosed
+		if ch, n, info, ok := fenceOpen(rest); ok {
+			end := len(lines) - 1
+			for j := i + 1; j < len(lines); j++ {
+				w, r := indentWidth(lines[j])
+				if w < 4 && isFenceClose(r, ch, n) {
+					end = j
+					break
+				}
+			}
+			nodes = append(nodes, &MarkdownNode{Type: NodeCodeBlock, Line: start, EndLine: offset + end + 1, Info: info})
+			i = end + 1
+			continue
+		}
+
+		// HTML comment, or an HTML block running to the next blank line
+		if strings.HasPrefix(rest, "<!--") {
+			end := len(lines) - 1
+			for j := i; j < len(lines); j++ {
+				from := 0
+				if j == i {
+					from = strings.Index(lines[j], "<!--") + 4
+				}
+				if strings.Contains(lines[j][from:], "-->") {
+					end = j
+					break
+				}
+			}
+			nodes = append(nodes, &MarkdownNode{Type: NodeHTMLBlock, Line: start, EndLine: offset + end + 1})
+			i = end + 1
+			continue
+		}
+		if htmlTagPattern.MatchString(rest) {
+			end := i
+			for end+1 < len(lines) && !isBlank(lines[end+1]) {
+				end++
+			}
+			nodes = append(nodes, &MarkdownNode{Type: NodeHTMLBlock, Line: start, EndLine: offset + end + 1})
+			i = end + 1
+			continue
+		}
+
+		if m := atxHeadingPattern.FindStringSubmatch(rest); m != nil {
+			nodes = append(nodes, &MarkdownNode{Type: NodeHeading, Line: start, EndLine: start, Level: len(m[1]), Text: strings.TrimSpace(m[2])})
+			i++
+			continue
+		}
+
+		if thematicBreakPattern.MatchString(rest) {
+			nodes = append(nodes, &MarkdownNode{Type: NodeThematicBreak, Line: start, EndLine: start})
+			i++
+			continue
+		}
+
+		// Block quote: strip the markers and parse the inside
+		if strings.HasPrefix(rest, ">") {
+			var inner []string
+			j := i
+			for j < len(lines) {
+				w, r := indentWidth(lines[j])
+				if w >= 4 || !strings.HasPrefix(r, ">") {
+					break
+				}
+				r = strings.TrimPrefix(r, ">")
+				r = strings.TrimPrefix(r, " ")
+				inner = append(inner, r)
+				j++
+			}
+			nodes = append(nodes, &MarkdownNode{
+				Type:     NodeBlockQuote,
+				Line:     start,
+				EndLine:  offset + j,
+				Children: parseBlocks(inner, offset+i),
+			})
+			i = j
+			continue
+		}
+
+		if listMarkerPattern.MatchString(rest) {
+			list, next := parseList(lines, i, offset)
+			nodes = append(nodes, list)
+			i = next
+			continue
+		}
+
+		// GFM table: a row followed by a delimiter row
+		if strings.Contains(rest, "|") && i+1 < len(lines) && tableDelimPattern.MatchString(strings.TrimSpace(lines[i+1])) {
+			end := i + 1
+			for end+1 < len(lines) && !isBlank(lines[end+1]) && strings.Contains(lines[end+1], "|") {
+				end++
+			}
+			nodes = append(nodes, &MarkdownNode{Type: NodeTable, Line: start, EndLine: offset + end + 1})
+			i = end + 1
+			continue
+		}
+
+		// Paragraph, possibly turned into a setext heading by an underline
+		end := i
+		heading := 0
+		for j := i + 1; j < len(lines); j++ {
+			if isBlank(lines[j]) {
+				break
+			}
+			if w, r := indentWidth(lines[j]); w < 4 && setextPattern.MatchString(r) {
+				heading = 2
+				if r[0] == '=' {
+					heading = 1
+				}
+				end = j
+				break
+			}
+			if startsBlock(lines[j]) {
+				break
+			}
+			end = j
+		}
+		if heading > 0 {
+			text := strings.Join(trimAll(lines[i:end]), " ")
+			nodes = append(nodes, &MarkdownNode{Type: NodeHeading, Line: start, EndLine: offset + end + 1, Level: heading, Text: text})
+		} else {
+			nodes = append(nodes, &MarkdownNode{Type: NodeParagraph, Line: start, EndLine: offset + end + 1})
+		}
+		i = end + 1
+	}
+
+	return nodes
+}
+
+// parseList collects consecutive list items starting at lines[i]
+func parseList(lines []string, i, offset int) (*MarkdownNode, int) {
+	list := &MarkdownNode{Type: NodeList, Line: offset + i + 1}
+
+	for i < len(lines) {
+		indent, rest := indentWidth(lines[i])
+		m := listMarkerPattern.FindStringSubmatch(rest)
+		if indent >= 4 || m == nil {
+			break
+		}
+
+		// Content starts after the marker and up to 4 spaces; more means indented code
+		contentIndent := indent + len(m[1]) + 1
+		first := strings.TrimPrefix(rest[len(m[1]):], " ")
+		if gap, _ := indentWidth(m[2]); gap >= 1 && gap <= 4 {
+			contentIndent = indent + len(m[1]) + gap
+			first = rest[len(m[0]):]
+		}
+
+		inner := []string{first}
+		end := i
+		for j := i + 1; j < len(lines); j++ {
+			if isBlank(lines[j]) {
+				inner = append(inner, "")
+				continue
+			}
+			w, _ := indentWidth(lines[j])
+			lazy := w < contentIndent && !isBlank(lines[j-1]) && !startsBlock(lines[j])
+			if w < contentIndent && !lazy {
+				break
+			}
+			if lazy {
+				inner = append(inner, strings.TrimSpace(lines[j]))
+			} else {
+				inner = append(inner, dedent(lines[j], contentIndent))
+			}
+			end = j
+		}
+		inner = inner[:end-i+1]
+
+		list.Children = append(list.Children, &MarkdownNode{
+			Type:     NodeListItem,
+			Line:     offset + i + 1,
+			EndLine:  offset + end + 1,
+			Children: parseBlocks(inner, offset+i),
+		})
+		list.EndLine = offset + end + 1
+
+		// Blank lines may separate items of the same list
+		i = end + 1
+		for i < len(lines) && isBlank(lines[i]) {
+			i++
+		}
+		if i < len(lines) {
+			w, r := indentWidth(lines[i])
+			if w >= 4 || !listMarkerPattern.MatchString(r) {
+				break
+			}
+		}
+	}
+
+	// Resume after the last item; the caller skips any blank lines that follow
+	return list, list.EndLine - offset
+}
+
+func trimAll(lines []string) []string {
+	out := make([]string, len(lines))
+	for i, l := range lines {
+		out[i] = strings.TrimSpace(l)
+	}
+	return out
+}
@@ -0,0 +1,215 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// nodeSummary lists a document's nodes as "type:line-endLine", depth-first.
+func nodeSummary(doc *MarkdownDoc) []string {
+	var out []string
+	doc.Walk(func(n *MarkdownNode) bool {
+		if n.Type != NodeDocument {
+			out = append(out, fmt.Sprintf("%s:%d-%d", n.Type, n.Line, n.EndLine))
+		}
+		return true
+	})
+	return out
+}
+
+func parse(content string) *MarkdownDoc {
+	return ParseMarkdown(strings.Split(content, "\n"))
+}
+
+// =============================================================================
+// ParseMarkdown
+// =============================================================================
+
+func TestParseMarkdown_Blocks(t *testing.T) {
+	doc := parse(strings.Join([]string{
+		"# Title",            // 1
+		"",                   // 2
+		"Intro paragraph",    // 3
+		"continues here",     // 4
+		"",                   // 5
+		"```bash",            // 6
+		"make build",         // 7
+		"```",                // 8
+		"",                   // 9
+		"<!-- a comment",     // 10
+		"spanning lines -->", // 11
+		"",                   // 12
+		"| Col | Col |",      // 13
+		"|-----|-----|",      // 14
+		"| a   | b   |",      // 15
+		"",                   // 16
+		"---",                // 17
+		"",                   // 18
+		"    indented code",  // 19
+		"",                   // 20
+		"> quoted",           // 21
+		"Setext",             // 22
+		"======",             // 23
+	}, "\n"))
+
+	want := []string{
+		"heading:1-1",
+		"paragraph:3-4",
+		"code_block:6-8",
+		"html_block:10-11",
+		"table:13-15",
+		"thematic_break:17-17",
+		"code_block:19-19",
+		"blockquote:21-21",
+		"paragraph:21-21",
+		"heading:22-23",
+	}
+	got := nodeSummary(doc)
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("got  %v\nwant %v", got, want)
+	}
+}
+
+func TestParseMarkdown_Headings(t *testing.T) {
+	doc := parse("## Build ##\n\nText\n---")
+	var headings []*MarkdownNode
+	doc.Walk(func(n *MarkdownNode) bool {
+		if n.Type == NodeHeading {
+			headings = append(headings, n)
+		}
+		return true
+	})
+
+	if len(headings) != 2 {
+		t.Fatalf("expected 2 headings, got %d", len(headings))
+	}
+	if headings[0].Level != 2 || headings[0].Text != "Build" {
+		t.Errorf("ATX heading: got level %d text %q", headings[0].Level, headings[0].Text)
+	}
+	if headings[1].Level != 2 || headings[1].Text != "Text" {
+		t.Errorf("setext heading: got level %d text %q", headings[1].Level, headings[1].Text)
+	}
+	if doc.InNode(1, NodeParagraph) {
+		t.Error("a heading line must not also be a paragraph")
+	}
+	if parse("#hashtag").InNode(1, NodeHeading) {
+		t.Error("# without a space is not a heading")
+	}
+}
+
+func TestParseMarkdown_ListWithNestedCode(t *testing.T) {
+	doc := parse(strings.Join([]string{
+		"- Run the tests:", // 1
+		"  ```bash",        // 2
+		"  go test ./...",  // 3
+		"  ```",            // 4
+		"- Second item",    // 5
+		"  wrapped",        // 6
+		"",                 // 7
+		"1. Ordered",       // 8
+	}, "\n"))
+
+	want := []string{
+		"list:1-8",
+		"list_item:1-4",
+		"paragraph:1-1",
+		"code_block:2-4",
+		"list_item:5-6",
+		"paragraph:5-6",
+		"list_item:8-8",
+		"paragraph:8-8",
+	}
+	got := nodeSummary(doc)
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("got  %v\nwant %v", got, want)
+	}
+
+	var info string
+	doc.Walk(func(n *MarkdownNode) bool {
+		if n.Type == NodeCodeBlock {
+			info = n.Info
+		}
+		return true
+	})
+	if info != "bash" {
+		t.Errorf("code block info = %q, want bash", info)
+	}
+}
+
+func TestParseMarkdown_UnclosedFence(t *testing.T) {
+	doc := parse("text\n\n```\n- Always do X\n- Never do Y")
+	for line := 3; line <= 5; line++ {
+		if !doc.InNode(line, NodeCodeBlock) {
+			t.Errorf("line %d: an unclosed fence should run to the end of the file", line)
+		}
+	}
+}
+
+// =============================================================================
+// Select
+// =============================================================================
+
+func TestMarkdownDoc_Select(t *testing.T) {
+	content := strings.Join([]string{
+		"# Style",
+		"- Use camelCase",
+		"```js",
+		"// use camelCase here too",
+		"```",
+		"<!-- use snake_case -->",
+	}, "\n")
+	doc := parse(content)
+
+	tests := []struct {
+		name  string
+		nodes []NodeType
+		want  []string
+	}{
+		{"default skips code and HTML", nil,
+			[]string{"# Style", "- Use camelCase", "", "", "", ""}},
+		{"code only", []NodeType{NodeCodeBlock},
+			[]string{"", "", "```js", "// use camelCase here too", "```", ""}},
+		{"list items", []NodeType{NodeListItem},
+			[]string{"", "- Use camelCase", "", "", "", ""}},
+		{"all", []NodeType{NodeAll},
+			strings.Split(content, "\n")},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := doc.Select(tc.nodes)
+			if got != strings.Join(tc.want, "\n") {
+				t.Errorf("got %q", got)
+			}
+		})
+	}
+}
+
+func TestSpecContent_NodesAffectMatching(t *testing.T) {
+	ctx := makeCtx("Intro\n\n```\nmax line length 80\n```\n", 5, 0, nil)
+
+	prose := &MatchSpec{Action: ActionRegexMatch, Patterns: []string{"line length"}}
+	if EvaluateSpec(ctx, prose) {
+		t.Error("expected code block text to be ignored by default")
+	}
+
+	code := &MatchSpec{Action: ActionRegexMatch, Patterns: []string{"line length"}, Nodes: []NodeType{NodeCodeBlock}}
+	if !EvaluateSpec(ctx, code) {
+		t.Error("expected match when targeting code blocks")
+	}
+	if spans := FindSpans(ctx, code); len(spans) != 1 || spans[0].Line != 4 {
+		t.Errorf("expected span on line 4, got %+v", spans)
+	}
+
+	// Absence checks see code blocks unless told otherwise
+	for _, action := range []CheckAction{ActionRegexNotMatch, ActionNotContains, ActionNotPresent} {
+		missing := &MatchSpec{Action: action, Patterns: []string{"line length"}}
+		if EvaluateSpec(ctx, missing) {
+			t.Errorf("%s: expected text in a code block to count as present", action)
+		}
+		missing.Nodes = []NodeType{NodeParagraph}
+		if !EvaluateSpec(ctx, missing) {
+			t.Errorf("%s: expected explicit nodes to hide the code block", action)
+		}
+	}
+}
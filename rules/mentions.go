@@ -0,0 +1,324 @@
+package rules
+
+import (
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MentionKind is what a backticked mention names
+type MentionKind string
+
+const (
+	MentionPath   MentionKind = "path"   // a file or directory, e.g. `internal/auth/`
+	MentionSymbol MentionKind = "symbol" // a code identifier, e.g. `NewEngine`
+)
+
+// Mention is a backticked path or identifier in a context file
+type Mention struct {
+	Line   int    // 1-based line number
+	Column int    // 1-based column of the text inside the backticks
+	Text   string // the backticked text
+	Kind   MentionKind
+	Name   string // the path without "./" or a trailing "/", or the identifier looked up
+	// Qualifier is what precedes a qualified identifier, e.g. "rules" in
+	// `rules.NewEngine`; it may name a package
+	Qualifier string
+}
+
+// DanglingMention is a mention the working tree no longer has
+type DanglingMention struct {
+	Mention
+	Reason string
+}
+
+// mentionFileExts are extensions that mark a bare name like `main.go` as a file
+var mentionFileExts = map[string]bool{
+	".go": true, ".mod": true, ".sum": true, ".py": true, ".pyi": true, ".ts": true, ".tsx": true,
+	".js": true, ".jsx": true, ".mjs": true, ".cjs": true, ".json": true, ".yaml": true, ".yml": true,
+	".toml": true, ".ini": true, ".cfg": true, ".rs": true, ".rb": true, ".java": true, ".kt": true,
+	".c": true, ".h": true, ".cpp": true, ".cs": true, ".swift": true, ".sql": true, ".proto": true,
+	".sh": true, ".lock": true, ".txt": true, ".xml": true, ".html": true, ".css": true, ".scss": true,
+	".tf": true, ".env": true,
+}
+
+// notMentionedNames are backticked words that look like files or identifiers
+// but name products and platforms
+var notMentionedNames = map[string]bool{
+	"node.js": true, "next.js": true, "nuxt.js": true, "vue.js": true, "react.js": true,
+	"express.js": true, "d3.js": true, "three.js": true, "chart.js": true, "socket.io": true,
+	"github": true, "gitlab": true, "bitbucket": true, "typescript": true, "javascript": true,
+	"postgresql": true, "mysql": true, "mongodb": true, "graphql": true, "openapi": true,
+	"dynamodb": true, "bigquery": true, "cloudformation": true, "powershell": true,
+	"vscode": true, "ios": true, "macos": true, "oauth": true, "websocket": true, "npm": true,
+	"pypi": true, "pnpm": true, "yarn": true, "fastapi": true, "sqlite": true, "redis": true,
+	"openai": true, "chatgpt": true, "devops": true, "clickhouse": true, "elasticsearch": true,
+}
+
+// identifierMention matches `Name`, `pkg.Name`, `Name()` or `obj.method(args)`
+var identifierMention = regexp.MustCompile(`^([A-Za-z_]\w*(?:\.[A-Za-z_]\w*)*)(\([^()]*\))?$`)
+
+// ExtractMentions finds the paths and identifiers in ctx's inline code.
+// URLs and .md references (checked as references) are left out.
+func ExtractMentions(ctx *AnalysisContext) []Mention {
+	doc := markdownOf(ctx)
+	var mentions []Mention
+	for i, line := range ctx.Lines {
+		lineNo := i + 1
+		if doc.InNode(lineNo, NodeCodeBlock) || doc.InNode(lineNo, NodeHTMLBlock) || doc.InNode(lineNo, NodeFrontmatter) {
+			continue
+		}
+		for _, m := range inlineCodePattern.FindAllStringSubmatchIndex(line, -1) {
+			text := line[m[2]:m[3]]
+			kind, name, qualifier, ok := classifyMention(text)
+			if !ok {
+				continue
+			}
+			mentions = append(mentions, Mention{
+				Line:      lineNo,
+				Column:    utf8.RuneCountInString(line[:m[2]]) + 1,
+				Text:      text,
+				Kind:      kind,
+				Name:      name,
+				Qualifier: qualifier,
+			})
+		}
+	}
+	return mentions
+}
+
+func classifyMention(text string) (kind MentionKind, name, qualifier string, ok bool) {
+	if text == "" || strings.ContainsAny(text, " \t$*?<>{}[]|&;:'\"@#=,!%~\\") ||
+		strings.HasPrefix(text, "/") || strings.HasPrefix(text, "-") || notMentionedNames[strings.ToLower(text)] {
+		return "", "", "", false
+	}
+	name = strings.TrimSuffix(strings.TrimPrefix(text, "./"), "/")
+	if strings.HasSuffix(strings.ToLower(name), ".md") {
+		return "", "", "", false
+	}
+	if strings.Contains(text, "/") {
+		if name == "" || strings.HasPrefix(name, "..") {
+			return "", "", "", false
+		}
+		return MentionPath, name, "", true
+	}
+	if strings.HasSuffix(text, "/") || mentionFileExts[strings.ToLower(filepath.Ext(name))] {
+		return MentionPath, name, "", true
+	}
+
+	m := identifierMention.FindStringSubmatch(text)
+	if m == nil {
+		return "", "", "", false
+	}
+	parts := strings.Split(m[1], ".")
+	ident := parts[len(parts)-1]
+	called := m[2] != ""
+	if called || len(parts) > 1 || isMixedCase(ident) {
+		return MentionSymbol, ident, strings.Join(parts[:len(parts)-1], "."), true
+	}
+	return "", "", "", false
+}
+
+// isMixedCase reports whether s looks like a camelCase or PascalCase
+// identifier rather than a word, an acronym or an ALL_CAPS constant
+func isMixedCase(s string) bool {
+	var lower, upper bool
+	for i, r := range s {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r) && i > 0:
+			upper = true
+		}
+	}
+	return lower && upper
+}
+
+// ValidateMentions checks ctx's mentions against the working tree at root:
+// paths must exist relative to the context file, the root, or as the tail
+// of a repo path; identifiers must be declared or used in the repo's Go,
+// Python or TypeScript/JavaScript code. Identifiers aren't checked in repos
+// without such code.
+func ValidateMentions(ctx *AnalysisContext, root string) []DanglingMention {
+	mentions := ExtractMentions(ctx)
+	if len(mentions) == 0 {
+		return nil
+	}
+	idx := LoadRepoIndex(root)
+	ctxDir := filepath.Dir(ctx.FilePath)
+
+	var dangling []DanglingMention
+	for _, m := range mentions {
+		switch m.Kind {
+		case MentionPath:
+			if isScriptPath(m.Text) {
+				continue // checked as a command
+			}
+			if !idx.HasPath(m.Name, ctxDir) {
+				dangling = append(dangling, DanglingMention{m, "no file or directory " + m.Name})
+			}
+		case MentionSymbol:
+			if idx.SourceFiles > 0 && !idx.HasSymbol(m.Name) {
+				dangling = append(dangling, DanglingMention{m, "no symbol " + m.Name + " in the code"})
+			}
+		}
+	}
+	return dangling
+}
+
+// EnrichContextWithMentions records the dangling mentions as the
+// dangling_path_count and dangling_symbol_count metrics, located at each
+// mention
+func EnrichContextWithMentions(ctx *AnalysisContext, dangling []DanglingMention) {
+	byKind := map[MentionKind][]Mention{}
+	for _, d := range dangling {
+		byKind[d.Kind] = append(byKind[d.Kind], d.Mention)
+	}
+
+	if ctx.MetricSpans == nil {
+		ctx.MetricSpans = make(map[MetricType][]Span)
+	}
+	ctx.Metrics["dangling_path_count"] = len(byKind[MentionPath])
+	ctx.Metrics["dangling_symbol_count"] = len(byKind[MentionSymbol])
+	ctx.MetricSpans["dangling_path_count"] = MentionSpans(ctx, byKind[MentionPath])
+	ctx.MetricSpans["dangling_symbol_count"] = MentionSpans(ctx, byKind[MentionSymbol])
+}
+
+// MentionSpans locates mentions in ctx
+func MentionSpans(ctx *AnalysisContext, mentions []Mention) []Span {
+	var spans []Span
+	for _, m := range mentions {
+		snippet := ""
+		if m.Line <= len(ctx.Lines) {
+			snippet = strings.TrimSpace(ctx.Lines[m.Line-1])
+		}
+		spans = append(spans, Span{Line: m.Line, Column: m.Column, Text: m.Text, Snippet: snippet})
+	}
+	return spans
+}
+
+// =============================================================================
+// Repo index
+// =============================================================================
+
+// RepoIndex lists a working tree's files and the identifiers in its code
+type RepoIndex struct {
+	Root        string
+	Paths       map[string]bool // files and directories, relative and slash-separated
+	Declared    map[string]bool // identifiers declared in Go, Python and TypeScript/JavaScript
+	Used        map[string]bool // every identifier appearing in that code
+	SourceFiles int             // number of files indexed for symbols
+}
+
+// maxIndexedFileSize skips generated and vendored blobs when indexing symbols
+const maxIndexedFileSize = 1 << 20
+
+var (
+	repoIndexMu    sync.Mutex
+	repoIndexCache = map[string]*RepoIndex{}
+)
+
+// LoadRepoIndex indexes the repo at root as the active source shows it,
+// respecting .gitignore in git working trees. Indexes are cached per root and
+// source for the life of the process.
+func LoadRepoIndex(root string) *RepoIndex {
+	repoIndexMu.Lock()
+	defer repoIndexMu.Unlock()
+	key := root + "\x00" + sourceKey()
+	if idx, ok := repoIndexCache[key]; ok {
+		return idx
+	}
+
+	idx := &RepoIndex{
+		Root:     root,
+		Paths:    map[string]bool{},
+		Declared: map[string]bool{},
+		Used:     map[string]bool{},
+	}
+	for _, rel := range activeSource.ListFiles(root) {
+		for p := rel; p != "." && p != "/"; p = path.Dir(p) {
+			idx.Paths[p] = true
+		}
+		if decls := symbolPatterns[strings.ToLower(path.Ext(rel))]; decls != nil {
+			idx.indexSymbols(filepath.Join(root, filepath.FromSlash(rel)), decls)
+		}
+	}
+	repoIndexCache[key] = idx
+	return idx
+}
+
+// HasPath reports whether p exists relative to dir or the root, or is the
+// tail of an indexed path
+func (idx *RepoIndex) HasPath(p, dir string) bool {
+	for _, base := range []string{dir, idx.Root} {
+		if _, err := activeSource.Stat(filepath.Join(base, filepath.FromSlash(p))); err == nil {
+			return true
+		}
+	}
+	if idx.Paths[p] {
+		return true
+	}
+	suffix := "/" + p
+	for indexed := range idx.Paths {
+		if strings.HasSuffix(indexed, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasSymbol reports whether the code declares or uses name
+func (idx *RepoIndex) HasSymbol(name string) bool {
+	return idx.Declared[name] || idx.Used[name]
+}
+
+// symbolPatterns are declaration patterns by file extension; the first
+// group is the declared name
+var symbolPatterns = func() map[string][]*regexp.Regexp {
+	goDecls := []*regexp.Regexp{
+		regexp.MustCompile(`^func\s+(?:\([^)]*\)\s*)?([A-Za-z_]\w*)`),
+		regexp.MustCompile(`^(?:type|var|const)\s+([A-Za-z_]\w*)`),
+		regexp.MustCompile(`^\t([A-Za-z_]\w*)[\s(=,]`), // fields, methods and block declarations
+	}
+	pyDecls := []*regexp.Regexp{
+		regexp.MustCompile(`^\s*(?:async\s+)?def\s+([A-Za-z_]\w*)`),
+		regexp.MustCompile(`^\s*class\s+([A-Za-z_]\w*)`),
+		regexp.MustCompile(`^([A-Za-z_]\w*)\s*(?::[^=]+)?=`),
+		regexp.MustCompile(`^\s+self\.([A-Za-z_]\w*)\s*(?::[^=]+)?=`),
+	}
+	tsDecls := []*regexp.Regexp{
+		regexp.MustCompile(`\b(?:function\*?|class|interface|type|enum|namespace)\s+([A-Za-z_$][\w$]*)`),
+		regexp.MustCompile(`\b(?:const|let|var)\s+([A-Za-z_$][\w$]*)`),
+		regexp.MustCompile(`^\s+(?:(?:public|private|protected|static|readonly|async|get|set)\s+)*([A-Za-z_$][\w$]*)\s*[(:=?]`),
+	}
+	return map[string][]*regexp.Regexp{
+		".go": goDecls,
+		".py": pyDecls, ".pyi": pyDecls,
+		".ts": tsDecls, ".tsx": tsDecls, ".js": tsDecls, ".jsx": tsDecls, ".mjs": tsDecls, ".cjs": tsDecls,
+	}
+}()
+
+var identifierToken = regexp.MustCompile(`[A-Za-z_$][\w$]*`)
+
+func (idx *RepoIndex) indexSymbols(file string, decls []*regexp.Regexp) {
+	data, err := activeSource.ReadFile(file)
+	if err != nil || len(data) > maxIndexedFileSize {
+		return
+	}
+	idx.SourceFiles++
+
+	for _, line := range strings.Split(string(data), "\n") {
+		for _, re := range decls {
+			if m := re.FindStringSubmatch(line); m != nil {
+				idx.Declared[m[1]] = true
+			}
+		}
+		for _, tok := range identifierToken.FindAllString(line, -1) {
+			idx.Used[tok] = true
+		}
+	}
+}
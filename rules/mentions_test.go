@@ -0,0 +1,137 @@
+package rules
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// =============================================================================
+// ExtractMentions
+// =============================================================================
+
+func TestExtractMentions(t *testing.T) {
+	content := strings.Join([]string{
+		"# Layout",
+		"",
+		"Handlers live in `internal/api/` and start in `cmd/server/main.go`; see `go.mod`.",
+		"Call `rules.NewEngine` or `Evaluate()`, and keep `useSession` pure.",
+		"Run `make test` or `./scripts/setup.sh`; docs are in `docs/guide.md`.",
+		"We use `Node.js`, `GitHub`, `TODO`, `true`, `https://x.dev/a`, `src/*.ts` and `<name>/`.",
+		"",
+		"```go",
+		"`internal/ignored.go`",
+		"```",
+	}, "\n")
+
+	var got []string
+	for _, m := range ExtractMentions(BuildContext("CLAUDE.md", content)) {
+		got = append(got, string(m.Kind)+":"+m.Name)
+	}
+	want := []string{
+		"path:internal/api", "path:cmd/server/main.go", "path:go.mod",
+		"symbol:NewEngine", "symbol:Evaluate", "symbol:useSession", "path:scripts/setup.sh",
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestExtractMentions_Location(t *testing.T) {
+	mentions := ExtractMentions(BuildContext("CLAUDE.md", "Edit `a/b.go` then `NewThing`."))
+	if len(mentions) != 2 {
+		t.Fatalf("expected 2 mentions, got %+v", mentions)
+	}
+	if mentions[1].Line != 1 || mentions[1].Column != 21 || mentions[1].Text != "NewThing" {
+		t.Errorf("unexpected location %+v", mentions[1])
+	}
+}
+
+// =============================================================================
+// ValidateMentions
+// =============================================================================
+
+func TestValidateMentions(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod":                   "module example\n",
+		"internal/auth/session.go": "package auth\n\ntype Session struct {\n\tUserID string\n}\n\nfunc (s *Session) Refresh() error { return nil }\n\nfunc NewSession() *Session { return &Session{} }\n",
+		"web/src/api.ts":           "export async function fetchUser(id: string) {}\nexport class ApiClient {\n  baseUrl = '';\n  async request(path: string) {}\n}\n",
+		"tools/gen.py":             "class Generator:\n    def __init__(self):\n        self.outputDir = 'out'\n\ndef run_all():\n    pass\n",
+	})
+
+	content := strings.Join([]string{
+		"- `internal/auth/` has `NewSession` and `Session.Refresh()`; `UserID` is required",
+		"- `auth/session.go` is the entry point, not `internal/auth/token.go` or `pkg/`",
+		"- `fetchUser()`, `ApiClient.request()`, `baseUrl` and `Generator` with `outputDir`",
+		"- `NewToken`, `client.retryAll()` and `useAuthStore` were removed",
+		"- `scripts/gone.sh` is left to the command checks",
+	}, "\n")
+	ctx := BuildContext(filepath.Join(dir, "CLAUDE.md"), content)
+
+	var dangling []string
+	for _, d := range ValidateMentions(ctx, dir) {
+		dangling = append(dangling, d.Text+" => "+d.Reason)
+	}
+	sort.Strings(dangling)
+	want := []string{
+		"NewToken => no symbol NewToken in the code",
+		"client.retryAll() => no symbol retryAll in the code",
+		"internal/auth/token.go => no file or directory internal/auth/token.go",
+		"pkg/ => no file or directory pkg",
+		"useAuthStore => no symbol useAuthStore in the code",
+	}
+	if strings.Join(dangling, "\n") != strings.Join(want, "\n") {
+		t.Errorf("dangling mentions:\n%s\nwant:\n%s", strings.Join(dangling, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestValidateMentions_RelativeToContextFile(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{"services/billing/handlers/invoice.rb": "class Invoice; end\n"})
+	ctx := BuildContext(filepath.Join(root, "services", "billing", "CLAUDE.md"), "Handlers are in `./handlers/`.")
+
+	if dangling := ValidateMentions(ctx, root); len(dangling) != 0 {
+		t.Errorf("expected the path to resolve from the context file, got %+v", dangling)
+	}
+}
+
+func TestValidateMentions_NoCodeSkipsSymbols(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{"lib/app.rb": "class App; end\n"})
+	ctx := BuildContext(filepath.Join(root, "CLAUDE.md"), "Use `AppConfig` from `lib/app.rb`.")
+
+	if dangling := ValidateMentions(ctx, root); len(dangling) != 0 {
+		t.Errorf("expected no symbol checks without Go, Python or TypeScript, got %+v", dangling)
+	}
+}
+
+func TestDanglingMentionRules_LocateEachMention(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"main.go": "package main\n\nfunc main() {}\n"})
+	content := "# Code\n\n- `main.go` calls `runServer()`\n- config is in `config/app.yaml`\n"
+	ctx := BuildContext(filepath.Join(dir, "CLAUDE.md"), content)
+	EnrichContextWithMentions(ctx, ValidateMentions(ctx, dir))
+
+	builtin, err := LoadBuiltinRules()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for code, want := range map[string]Span{
+		"CD081": {Line: 4, Column: 17, Text: "config/app.yaml"},
+		"CD082": {Line: 3, Column: 20, Text: "runServer()"},
+	} {
+		rule := findRule(builtin, code)
+		if rule == nil {
+			t.Fatalf("%s not found", code)
+		}
+		res := NewEngine([]Rule{*rule}).Evaluate(ctx)[0]
+		if !res.Passed || len(res.Spans) != 1 {
+			t.Fatalf("expected %s with 1 location, got passed=%v spans=%+v", code, res.Passed, res.Spans)
+		}
+		if got := res.Spans[0]; got.Line != want.Line || got.Column != want.Column || got.Text != want.Text {
+			t.Errorf("%s: unexpected span %+v", code, got)
+		}
+	}
+}
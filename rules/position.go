@@ -0,0 +1,133 @@
+package rules
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Strength is how strongly an instruction is worded
+type Strength string
+
+const (
+	StrengthCritical Strength = "critical" // MUST, NEVER, ALWAYS, ...
+	StrengthNormal   Strength = "normal"
+	StrengthSoft     Strength = "soft" // prefer, consider, ...
+)
+
+// minPositionTokens is the size below which a file is read closely enough
+// that an instruction's position doesn't matter
+const minPositionTokens = 500
+
+// criticalPattern matches wording that marks an instruction as non-negotiable
+var criticalPattern = regexp.MustCompile(`(?i)\b(must|never|always|required|mandatory|important|critical|forbidden|under no circumstances)\b|\bDO NOT\b|\bDON'T\b`)
+
+// softPattern matches wording that marks an instruction as a preference
+var softPattern = regexp.MustCompile(`(?i)\b(prefer|consider|ideally|optionally|try to|if possible|where possible|when possible|may|could)\b`)
+
+// ClassifyStrength returns how strongly an instruction is worded
+func ClassifyStrength(instruction string) Strength {
+	switch {
+	case criticalPattern.MatchString(instruction):
+		return StrengthCritical
+	case softPattern.MatchString(instruction):
+		return StrengthSoft
+	default:
+		return StrengthNormal
+	}
+}
+
+// Emphasis scores how insistently an instruction is worded: a point for each
+// critical marker, two for one written in capitals (NEVER, MUST)
+func Emphasis(instruction string) int {
+	score := 0
+	for _, m := range criticalPattern.FindAllString(instruction, -1) {
+		score++
+		if m == strings.ToUpper(m) {
+			score++
+		}
+	}
+	return score
+}
+
+// PositionedInstruction is an instruction with its place in the loaded context
+type PositionedInstruction struct {
+	File     string
+	Line     int
+	Text     string
+	Strength Strength
+	Position float64 // share of the context's tokens before the instruction (0-1)
+}
+
+// PositionAnalysis describes where critical instructions sit in the primary
+// file once its @imports are expanded. Models attend most to the start and
+// end of their context, so directives in the middle third are easily missed.
+type PositionAnalysis struct {
+	TotalTokens   int
+	Critical      []PositionedInstruction // every critical instruction, in reading order
+	Buried        []PositionedInstruction // critical instructions in the middle third
+	AttentionRisk int                     // percentage of critical instructions that are buried
+}
+
+// AnalyzePositions locates the critical instructions of the primary file and
+// its imports. Files under minPositionTokens are never considered at risk.
+func AnalyzePositions(ctx *AnalysisContext, baseDir string) *PositionAnalysis {
+	lines := ExpandImports(ctx, baseDir)
+
+	// Token offset of each line; +1 for the newline
+	offsets := make([]int, len(lines))
+	total := 0
+	for i, l := range lines {
+		offsets[i] = total
+		total += CountTokens(l.Text) + 1
+	}
+
+	pa := &PositionAnalysis{TotalTokens: total}
+	for i, l := range lines {
+		if !l.IsInstruction {
+			continue
+		}
+		strength := ClassifyStrength(l.Text)
+		if strength != StrengthCritical {
+			continue
+		}
+		instr := PositionedInstruction{
+			File:     l.File,
+			Line:     l.Line,
+			Text:     strings.TrimSpace(l.Text),
+			Strength: strength,
+			Position: float64(offsets[i]) / float64(total),
+		}
+		pa.Critical = append(pa.Critical, instr)
+		if total >= minPositionTokens && instr.Position >= 1.0/3 && instr.Position < 2.0/3 {
+			pa.Buried = append(pa.Buried, instr)
+		}
+	}
+
+	if len(pa.Critical) > 0 {
+		pa.AttentionRisk = len(pa.Buried) * 100 / len(pa.Critical)
+	}
+	return pa
+}
+
+// SuggestedOrder moves each buried instruction out of the middle third to the
+// nearer edge of the context: those before its midpoint to the start, the rest
+// to the end. Both groups are ranked by emphasis so the strongest instructions
+// sit closest to the edges, first in start and last in end; ties keep reading
+// order.
+func (pa *PositionAnalysis) SuggestedOrder() (start, end []PositionedInstruction) {
+	for _, b := range pa.Buried {
+		if b.Position < 0.5 {
+			start = append(start, b)
+		} else {
+			end = append(end, b)
+		}
+	}
+	sort.SliceStable(start, func(i, j int) bool {
+		return Emphasis(start[i].Text) > Emphasis(start[j].Text)
+	})
+	sort.SliceStable(end, func(i, j int) bool {
+		return Emphasis(end[i].Text) < Emphasis(end[j].Text)
+	})
+	return start, end
+}
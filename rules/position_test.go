@@ -0,0 +1,131 @@
+package rules
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestClassifyStrength(t *testing.T) {
+	tests := map[string]Strength{
+		"- You MUST run make test":           StrengthCritical,
+		"- Never commit secrets":             StrengthCritical,
+		"- DO NOT edit generated files":      StrengthCritical,
+		"- Prefer table-driven tests":        StrengthSoft,
+		"- Consider splitting large modules": StrengthSoft,
+		"- Run make lint before pushing":     StrengthNormal,
+	}
+
+	for line, want := range tests {
+		if got := ClassifyStrength(line); got != want {
+			t.Errorf("ClassifyStrength(%q) = %s, want %s", line, got, want)
+		}
+	}
+}
+
+func TestEmphasis(t *testing.T) {
+	tests := map[string]int{
+		"- Run make lint before pushing":               0,
+		"- Never commit secrets":                       1,
+		"- NEVER commit secrets":                       2,
+		"- You MUST always run make test":              3,
+		"- IMPORTANT: NEVER force-push, always rebase": 5,
+	}
+	for line, want := range tests {
+		if got := Emphasis(line); got != want {
+			t.Errorf("Emphasis(%q) = %d, want %d", line, got, want)
+		}
+	}
+}
+
+// fillerDoc returns a document with the given lines placed among filler instructions
+func fillerDoc(n int, insert map[int]string) string {
+	var lines []string
+	for i := 0; i < n; i++ {
+		if l, ok := insert[i]; ok {
+			lines = append(lines, l)
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("- Run step %d of the build with the usual flags", i))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestAnalyzePositions(t *testing.T) {
+	content := fillerDoc(60, map[int]string{
+		1:  "- NEVER commit secrets",
+		30: "- You MUST run make test before pushing",
+		58: "- ALWAYS update the changelog",
+	})
+	ctx := BuildContext("CLAUDE.md", content)
+
+	pa := AnalyzePositions(ctx, t.TempDir())
+	if len(pa.Critical) != 3 {
+		t.Fatalf("expected 3 critical instructions, got %d", len(pa.Critical))
+	}
+	if len(pa.Buried) != 1 || pa.Buried[0].Line != 31 {
+		t.Fatalf("expected the line 31 instruction to be buried, got %+v", pa.Buried)
+	}
+	if pa.AttentionRisk != 33 {
+		t.Errorf("expected attention risk 33, got %d", pa.AttentionRisk)
+	}
+}
+
+func TestSuggestedOrder(t *testing.T) {
+	content := fillerDoc(60, map[int]string{
+		22: "- Always run the linter",
+		25: "- NEVER commit secrets",
+		28: "- You must run make test",
+		33: "- Never edit generated files",
+		36: "- You MUST NOT skip CI, NEVER",
+	})
+	pa := AnalyzePositions(BuildContext("CLAUDE.md", content), t.TempDir())
+	if len(pa.Buried) != 5 {
+		t.Fatalf("expected 5 buried instructions, got %+v", pa.Buried)
+	}
+
+	start, end := pa.SuggestedOrder()
+	lines := func(instrs []PositionedInstruction) []int {
+		var out []int
+		for _, in := range instrs {
+			out = append(out, in.Line)
+		}
+		return out
+	}
+	// Strongest first at the start, strongest last at the end
+	if got := fmt.Sprint(lines(start)); got != "[26 23 29]" {
+		t.Errorf("start order %s", got)
+	}
+	if got := fmt.Sprint(lines(end)); got != "[34 37]" {
+		t.Errorf("end order %s", got)
+	}
+}
+
+func TestAnalyzePositions_SmallFile(t *testing.T) {
+	ctx := BuildContext("CLAUDE.md", "- Run tests\n- You MUST run make test\n- Run lint")
+	pa := AnalyzePositions(ctx, t.TempDir())
+	if len(pa.Critical) != 1 || len(pa.Buried) != 0 {
+		t.Errorf("small files should not bury instructions, got %+v", pa)
+	}
+}
+
+func TestAnalyzePositions_FollowsImports(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "rules.md"), []byte("- NEVER push to main"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The import sits in the middle, so the imported directive is buried
+	content := fillerDoc(60, map[int]string{30: "@rules.md"})
+	ctx := BuildContext(filepath.Join(dir, "CLAUDE.md"), content)
+
+	pa := AnalyzePositions(ctx, dir)
+	if len(pa.Buried) != 1 {
+		t.Fatalf("expected the imported instruction to be buried, got %+v", pa.Buried)
+	}
+	if filepath.Base(pa.Buried[0].File) != "rules.md" || pa.Buried[0].Line != 1 {
+		t.Errorf("expected location rules.md:1, got %s:%d", pa.Buried[0].File, pa.Buried[0].Line)
+	}
+}
@@ -0,0 +1,153 @@
+package rules
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// AgentProfile is what an agent adds to every context file it loads, and the
+// instruction budget a file should stay within for that agent. Baselines are
+// rough estimates of the agent's system prompt; projects can tune them, or
+// describe the "custom" profile, under profiles in the config.
+type AgentProfile struct {
+	Name  string `yaml:"-"`
+	Agent string `yaml:"agent,omitempty"` // display name, e.g. "Claude Code"
+	// BaselineInstructions and BaselineTokens estimate the agent's own
+	// system prompt, which competes with the context file for attention
+	BaselineInstructions int `yaml:"baselineInstructions,omitempty"`
+	BaselineTokens       int `yaml:"baselineTokens,omitempty"`
+	// MaxInstructions and WarnInstructions limit the instructions the agent
+	// sees, baseline included: CD003 fires above MaxInstructions, CD004
+	// above WarnInstructions
+	MaxInstructions  int `yaml:"maxInstructions,omitempty"`
+	WarnInstructions int `yaml:"warnInstructions,omitempty"`
+}
+
+// CustomProfile is the profile for agents without a built-in one. It has no
+// baseline until the config describes the agent.
+const CustomProfile = "custom"
+
+// Default instruction limits: LLMs reliably follow 150-200 instructions
+const (
+	DefaultMaxInstructions  = 150
+	DefaultWarnInstructions = 100
+)
+
+// AgentProfiles are the built-in profiles by name
+var AgentProfiles = map[string]AgentProfile{
+	"claude-code": {Agent: "Claude Code", BaselineInstructions: 50, BaselineTokens: 15000},
+	"codex":       {Agent: "Codex", BaselineInstructions: 50, BaselineTokens: 8000},
+	"gemini-cli":  {Agent: "Gemini CLI", BaselineInstructions: 50, BaselineTokens: 10000},
+	"copilot":     {Agent: "Copilot", BaselineInstructions: 30, BaselineTokens: 4000},
+	"cursor":      {Agent: "Cursor", BaselineInstructions: 30, BaselineTokens: 6000},
+	"windsurf":    {Agent: "Windsurf", BaselineInstructions: 30, BaselineTokens: 6000},
+	"cline":       {Agent: "Cline", BaselineInstructions: 50, BaselineTokens: 10000},
+	"aider":       {Agent: "Aider", BaselineInstructions: 20, BaselineTokens: 2000},
+	CustomProfile: {Agent: "Custom agent"},
+}
+
+// profileLimitRules are the rules whose thresholds come from the profile
+var profileLimitRules = map[string]func(AgentProfile) int{
+	"CD003": func(p AgentProfile) int { return p.MaxInstructions },
+	"CD004": func(p AgentProfile) int { return p.WarnInstructions },
+}
+
+// ProfileNames returns the built-in profile names, sorted
+func ProfileNames() []string {
+	names := make([]string, 0, len(AgentProfiles))
+	for name := range AgentProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ProfileNames returns the built-in profile names and those the config
+// defines, sorted
+func (c *Config) ProfileNames() []string {
+	names := ProfileNames()
+	if c != nil {
+		for name := range c.Profiles {
+			if _, ok := AgentProfiles[name]; !ok {
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Profile returns the named profile with the config's settings for it
+// applied over the built-in ones and the default limits filling any gaps
+func (c *Config) Profile(name string) (AgentProfile, error) {
+	p, builtin := AgentProfiles[name]
+	var custom AgentProfile
+	configured := false
+	if c != nil {
+		custom, configured = c.Profiles[name]
+	}
+	if !builtin && !configured {
+		return AgentProfile{}, fmt.Errorf("unknown profile %q (available: %s)", name, strings.Join(c.ProfileNames(), ", "))
+	}
+
+	p.Name = name
+	if custom.Agent != "" {
+		p.Agent = custom.Agent
+	}
+	if custom.BaselineInstructions != 0 {
+		p.BaselineInstructions = custom.BaselineInstructions
+	}
+	if custom.BaselineTokens != 0 {
+		p.BaselineTokens = custom.BaselineTokens
+	}
+	if custom.MaxInstructions != 0 {
+		p.MaxInstructions = custom.MaxInstructions
+	}
+	if custom.WarnInstructions != 0 {
+		p.WarnInstructions = custom.WarnInstructions
+	}
+
+	if p.Agent == "" {
+		p.Agent = name
+	}
+	if p.MaxInstructions == 0 {
+		p.MaxInstructions = DefaultMaxInstructions
+	}
+	if p.WarnInstructions == 0 {
+		p.WarnInstructions = DefaultWarnInstructions
+	}
+	return p, nil
+}
+
+// ProfileFor returns the profile for ctx: the named one, or with name empty,
+// the one its format declares
+func (c *Config) ProfileFor(ctx *AnalysisContext, name string) (AgentProfile, error) {
+	if name == "" {
+		name = FormatOf(ctx).Profile
+	}
+	return c.Profile(name)
+}
+
+// ApplyRules sets the thresholds of the rules the profile limits. Rules not
+// checking a single threshold are left alone.
+func (p AgentProfile) ApplyRules(rules []Rule) []Rule {
+	out := make([]Rule, 0, len(rules))
+	for _, rule := range rules {
+		if limit, ok := profileLimitRules[rule.Code]; ok {
+			// Custom definitions of the code may check something else, so an
+			// error just leaves the rule as it is
+			value := limit(p)
+			_ = applyThresholds(&rule, RuleOverride{Threshold: &value})
+		}
+		out = append(out, rule)
+	}
+	return out
+}
+
+// ApplyMetrics records what the agent sees of ctx: its instructions and tokens
+// plus the agent's own
+func (p AgentProfile) ApplyMetrics(ctx *AnalysisContext) {
+	ctx.Metrics["effective_instruction_count"] = ctx.InstructionCount + p.BaselineInstructions
+	ctx.Metrics["effective_token_count"] = ctx.TokenCount + p.BaselineTokens
+}
@@ -0,0 +1,140 @@
+package rules
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConfig_Profile(t *testing.T) {
+	t.Run("built-in without config", func(t *testing.T) {
+		var cfg *Config
+		p, err := cfg.Profile("claude-code")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.Name != "claude-code" || p.BaselineInstructions != 50 {
+			t.Errorf("unexpected profile %+v", p)
+		}
+		if p.MaxInstructions != DefaultMaxInstructions || p.WarnInstructions != DefaultWarnInstructions {
+			t.Errorf("expected the default limits, got %+v", p)
+		}
+	})
+
+	t.Run("config overrides and custom profiles", func(t *testing.T) {
+		cfg, err := LoadConfig(writeConfig(t, `
+profiles:
+  cursor:
+    maxInstructions: 120
+  custom:
+    agent: Internal bot
+    baselineInstructions: 40
+  review-bot:
+    baselineTokens: 900
+`))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		cursor, _ := cfg.Profile("cursor")
+		if cursor.MaxInstructions != 120 || cursor.BaselineInstructions != AgentProfiles["cursor"].BaselineInstructions {
+			t.Errorf("expected only the limit to change, got %+v", cursor)
+		}
+		custom, _ := cfg.Profile(CustomProfile)
+		if custom.Agent != "Internal bot" || custom.BaselineInstructions != 40 {
+			t.Errorf("unexpected custom profile %+v", custom)
+		}
+		bot, err := cfg.Profile("review-bot")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bot.Agent != "review-bot" || bot.BaselineTokens != 900 {
+			t.Errorf("unexpected config-only profile %+v", bot)
+		}
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		var cfg *Config
+		if _, err := cfg.Profile("emacs"); err == nil || !strings.Contains(err.Error(), "claude-code") {
+			t.Errorf("expected an error listing the profiles, got %v", err)
+		}
+	})
+
+	t.Run("negative values", func(t *testing.T) {
+		_, err := LoadConfig(writeConfig(t, "profiles:\n  custom:\n    baselineTokens: -1\n"))
+		if err == nil || !strings.Contains(err.Error(), "profile custom") {
+			t.Errorf("expected a validation error, got %v", err)
+		}
+	})
+}
+
+func TestConfig_ProfileFor(t *testing.T) {
+	var cfg *Config
+	tests := []struct {
+		path, name, want string
+	}{
+		{"CLAUDE.md", "", "claude-code"},
+		{"AGENTS.md", "", "codex"},
+		{".cursor/rules/go.mdc", "", "cursor"},
+		{".github/copilot-instructions.md", "", "copilot"},
+		{"notes.md", "", "claude-code"}, // unknown files fall back to the default format
+		{"AGENTS.md", "cursor", "cursor"},
+	}
+	for _, tt := range tests {
+		p, err := cfg.ProfileFor(BuildContext(tt.path, ""), tt.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.Name != tt.want {
+			t.Errorf("ProfileFor(%s, %q) = %s, want %s", tt.path, tt.name, p.Name, tt.want)
+		}
+	}
+}
+
+func TestAgentProfile_DrivesInstructionRules(t *testing.T) {
+	builtin, err := LoadBuiltinRules()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 80 instructions: within budget with Aider's small baseline, over the
+	// warning limit with Claude Code's
+	content := strings.Repeat("- Run the tests\n", 80)
+	evaluate := func(name string) map[string]bool {
+		var cfg *Config
+		p, err := cfg.Profile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx := BuildContext("CLAUDE.md", content)
+		p.ApplyMetrics(ctx)
+		fired := map[string]bool{}
+		for _, r := range NewEngine(p.ApplyRules(builtin)).Evaluate(ctx) {
+			fired[r.Rule.Code] = r.Passed
+		}
+		return fired
+	}
+
+	if fired := evaluate("claude-code"); !fired["CD004"] || fired["CD003"] {
+		t.Errorf("claude-code: expected CD004 only, got CD003=%v CD004=%v", fired["CD003"], fired["CD004"])
+	}
+	if fired := evaluate("aider"); fired["CD004"] || fired["CD003"] {
+		t.Errorf("aider: expected neither rule, got CD003=%v CD004=%v", fired["CD003"], fired["CD004"])
+	}
+}
+
+func TestAgentProfile_ApplyRulesRewritesMessage(t *testing.T) {
+	builtin, err := LoadBuiltinRules()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := AgentProfile{Name: "strict", MaxInstructions: 90, WarnInstructions: 60}
+	rules := p.ApplyRules(builtin)
+
+	cd003 := findRule(rules, "CD003")
+	if cd003 == nil || cd003.MatchSpec.Value != 90 || !strings.Contains(cd003.ErrorMessage, ">90") {
+		t.Errorf("expected CD003 to use the profile's limit, got %+v", cd003)
+	}
+	if orig := findRule(builtin, "CD003"); orig.MatchSpec.Value == 90 {
+		t.Error("expected the original rules to be left unchanged")
+	}
+}
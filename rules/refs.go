@@ -1,17 +1,26 @@
 package rules
 
 import (
-	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
 	"time"
 )
 
+// RefKind is how a file refers to another
+type RefKind string
+
+const (
+	RefImport    RefKind = "import"    // @path import, loaded along with the importing file
+	RefReference RefKind = "reference" // mention such as "see docs/foo.md", read on demand
+)
+
 // RefInfo holds information about a referenced documentation file
 type RefInfo struct {
 	Path            string           // referenced path (e.g., "docs/architecture.md")
 	ResolvedPath    string           // absolute path on disk
+	Kind            RefKind          // import or reference
+	Eager           bool             // loaded at session start: imported by the primary file or another eager import
 	Exists          bool             // whether the file exists
 	LastModified    time.Time        // last modification time
 	DaysSinceUpdate int              // days since last update
@@ -22,22 +31,41 @@ type RefInfo struct {
 	Children        []RefInfo        // files referenced by this file
 }
 
-// ResolveReferences recursively resolves progressive disclosure refs from the context.
-// It follows references in referenced files, detecting circular references.
+// refTarget is a path found in a file, before it is resolved
+type refTarget struct {
+	path     string
+	resolved string
+	kind     RefKind
+}
+
+// ResolveReferences recursively resolves the @imports and progressive disclosure
+// refs of the context. Imports are followed up to maxImportDepth hops and are
+// eager while every file above them is; references are read on demand, and so
+// is everything below them. Circular references are skipped.
 func ResolveReferences(ctx *AnalysisContext, baseDir string, staleThresholdDays int) []RefInfo {
 	seen := make(map[string]bool)
+	if ctx.FilePath != "" {
+		if abs, err := filepath.Abs(ctx.FilePath); err == nil {
+			seen[abs] = true
+		}
+	}
 	repoRoot := GetGitRoot(baseDir)
-	return resolveRefsRecursive(ctx, baseDir, repoRoot, staleThresholdDays, ctx.FilePath, 0, seen)
+	return resolveRefsRecursive(ctx, baseDir, repoRoot, staleThresholdDays, ctx.FilePath, 0, true, 0, seen)
 }
 
-func resolveRefsRecursive(ctx *AnalysisContext, baseDir string, repoRoot string, staleThresholdDays int, referencedBy string, depth int, seen map[string]bool) []RefInfo {
-	rawRefs, ok := ctx.Metrics["progressiveDisclosureRefs"].([]string)
-	if !ok || len(rawRefs) == 0 {
-		return nil
-	}
+// refTargets lists the imports of ctx, then its references. Imports resolve
+// against the file's directory; references fall back to the repo root.
+func refTargets(ctx *AnalysisContext, baseDir, repoRoot string, importDepth int) []refTarget {
+	var targets []refTarget
 
-	var refs []RefInfo
+	if importDepth < maxImportDepth {
+		imports, _ := ctx.Metrics["imports"].([]string)
+		for _, imp := range imports {
+			targets = append(targets, refTarget{path: imp, resolved: resolveImportPath(imp, baseDir), kind: RefImport})
+		}
+	}
 
+	rawRefs, _ := ctx.Metrics["progressiveDisclosureRefs"].([]string)
 	for _, ref := range rawRefs {
 		ref = strings.TrimSpace(ref)
 		if ref == "" {
@@ -48,33 +76,47 @@ func resolveRefsRecursive(ctx *AnalysisContext, baseDir string, repoRoot string,
 
 		// Fallback: if not found relative to baseDir, try repo root
 		if repoRoot != "" {
-			if _, err := os.Stat(resolved); err != nil {
+			if _, err := activeSource.Stat(resolved); err != nil {
 				fromRoot := filepath.Join(repoRoot, ref)
-				if _, err := os.Stat(fromRoot); err == nil {
+				if _, err := activeSource.Stat(fromRoot); err == nil {
 					resolved = fromRoot
 				}
 			}
 		}
+		targets = append(targets, refTarget{path: ref, resolved: resolved, kind: RefReference})
+	}
+
+	return targets
+}
+
+func resolveRefsRecursive(ctx *AnalysisContext, baseDir string, repoRoot string, staleThresholdDays int, referencedBy string, depth int, eager bool, importDepth int, seen map[string]bool) []RefInfo {
+	var refs []RefInfo
+
+	for _, target := range refTargets(ctx, baseDir, repoRoot, importDepth) {
+		resolved := target.resolved
 
 		absResolved, err := filepath.Abs(resolved)
 		if err != nil {
 			absResolved = resolved
 		}
 
-		// Cycle detection
+		// Cycle detection; a file both imported and referenced keeps the import,
+		// which comes first
 		if seen[absResolved] {
 			continue
 		}
 		seen[absResolved] = true
 
 		info := RefInfo{
-			Path:         ref,
+			Path:         target.path,
 			ResolvedPath: resolved,
+			Kind:         target.kind,
+			Eager:        eager && target.kind == RefImport,
 			ReferencedBy: referencedBy,
 			Depth:        depth,
 		}
 
-		stat, err := os.Stat(resolved)
+		stat, err := activeSource.Stat(resolved)
 		if err != nil {
 			refs = append(refs, info)
 			continue
@@ -83,22 +125,26 @@ func resolveRefsRecursive(ctx *AnalysisContext, baseDir string, repoRoot string,
 		info.Exists = true
 
 		// Try git log first for last modified time
-		lastMod := getGitLastModified(resolved)
+		lastMod := activeSource.LastModified(resolved)
 		if lastMod.IsZero() {
 			lastMod = stat.ModTime()
 		}
 		info.LastModified = lastMod
-		info.DaysSinceUpdate = int(time.Since(lastMod).Hours() / 24)
+		info.DaysSinceUpdate = int(activeSource.Now().Sub(lastMod).Hours() / 24)
 		info.IsStale = staleThresholdDays > 0 && info.DaysSinceUpdate > staleThresholdDays
 
 		// Build analysis context for the referenced file
-		content, err := os.ReadFile(resolved)
+		content, err := activeSource.ReadFile(resolved)
 		if err == nil {
 			info.Context = BuildContext(resolved, string(content))
 
 			// Recurse into this file's references
 			childBaseDir := filepath.Dir(resolved)
-			info.Children = resolveRefsRecursive(info.Context, childBaseDir, repoRoot, staleThresholdDays, ref, depth+1, seen)
+			childImportDepth := 0
+			if target.kind == RefImport {
+				childImportDepth = importDepth + 1
+			}
+			info.Children = resolveRefsRecursive(info.Context, childBaseDir, repoRoot, staleThresholdDays, target.path, depth+1, info.Eager, childImportDepth, seen)
 		}
 
 		refs = append(refs, info)
@@ -130,22 +176,6 @@ func GetGitRoot(dir string) string {
 	return strings.TrimSpace(string(output))
 }
 
-// countCommitsSince counts commits in dir since the given date string (RFC3339 or git-compatible).
-// Returns 0 on error (consistent with other git helpers).
-func countCommitsSince(dir string, since string) int {
-	cmd := exec.Command("git", "log", "--since="+since, "--oneline", "--", ".")
-	cmd.Dir = dir
-	output, err := cmd.Output()
-	if err != nil {
-		return 0
-	}
-	trimmed := strings.TrimSpace(string(output))
-	if trimmed == "" {
-		return 0
-	}
-	return len(strings.Split(trimmed, "\n"))
-}
-
 // getGitLastModified tries to get the last commit date for a file using git
 func getGitLastModified(filePath string) time.Time {
 	cmd := exec.Command("git", "log", "-1", "--format=%ci", filePath)
@@ -188,4 +218,28 @@ func EnrichContextWithRefMetrics(ctx *AnalysisContext, refs []RefInfo) {
 	ctx.Metrics["broken_references_count"] = brokenCount
 	ctx.Metrics["stale_references_count"] = staleCount
 	ctx.Metrics["referenced_files"] = refFiles
+
+	addImportedContent(ctx, allRefs)
+}
+
+// addImportedContent counts eagerly imported files toward the context's line,
+// instruction and token counts, since the agent loads them with it
+func addImportedContent(ctx *AnalysisContext, allRefs []RefInfo) {
+	var imported ImportedContent
+	for _, ref := range allRefs {
+		if !ref.Eager || ref.Context == nil {
+			continue
+		}
+		imported.Files++
+		imported.Lines += ref.Context.LineCount
+		imported.Instructions += ref.Context.InstructionCount
+		imported.Tokens += ref.Context.TokenCount
+	}
+
+	// Replace any earlier count so enriching twice doesn't add it twice
+	ctx.LineCount += imported.Lines - ctx.Imported.Lines
+	ctx.InstructionCount += imported.Instructions - ctx.Imported.Instructions
+	ctx.TokenCount += imported.Tokens - ctx.Imported.Tokens
+	ctx.Imported = imported
+	ctx.Metrics["imported_file_count"] = imported.Files
 }
@@ -349,89 +349,127 @@ func TestResolveReferences_RepoRootFallback(t *testing.T) {
 	}
 }
 
-// =============================================================================
-// countCommitsSince
-// =============================================================================
+func TestEnrichContextWithRefMetrics_Empty(t *testing.T) {
+	ctx := &AnalysisContext{
+		Metrics: make(map[string]any),
+	}
 
-func TestCountCommitsSince_NoGitRepo(t *testing.T) {
-	tmpDir := t.TempDir()
-	count := countCommitsSince(tmpDir, "2020-01-01T00:00:00Z")
-	if count != 0 {
-		t.Errorf("expected 0 for non-git dir, got %d", count)
+	EnrichContextWithRefMetrics(ctx, nil)
+
+	brokenCount, ok := ctx.Metrics["broken_references_count"].(int)
+	if !ok || brokenCount != 0 {
+		t.Errorf("expected broken_references_count=0, got %v", ctx.Metrics["broken_references_count"])
 	}
 }
 
-func TestCountCommitsSince_WithCommits(t *testing.T) {
+func TestResolveReferences_ImportsVsReferences(t *testing.T) {
 	tmpDir := t.TempDir()
-
-	// Initialize repo and create a commit
-	run := func(args ...string) {
-		cmd := exec.Command(args[0], args[1:]...)
-		cmd.Dir = tmpDir
-		if out, err := cmd.CombinedOutput(); err != nil {
-			t.Fatalf("%v failed: %s", args, out)
+	write := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
 		}
 	}
-	run("git", "init")
-	run("git", "config", "user.email", "test@test.com")
-	run("git", "config", "user.name", "Test")
+	// style.md is imported and imports rules.md (eager); it also mentions
+	// lazy.md, whose own import is only read on demand. The import of
+	// CLAUDE.md is a cycle.
+	write("docs/style.md", "# Style\n\n- Use tabs in Makefiles\n@rules.md\nSee lazy.md for history.\n@../CLAUDE.md\n")
+	write("docs/rules.md", "- Always run the linter\n")
+	write("docs/lazy.md", "# History\n\n@old.md\n")
+	write("docs/old.md", "- Old rule\n")
+	write("docs/arch.md", "# Arch\n")
 
-	if err := os.WriteFile(filepath.Join(tmpDir, "file.txt"), []byte("hello"), 0644); err != nil {
-		t.Fatal(err)
-	}
-	run("git", "add", "file.txt")
-	run("git", "commit", "-m", "initial")
+	primary := filepath.Join(tmpDir, "CLAUDE.md")
+	content := "# Project\n\n@docs/style.md\nSee docs/arch.md for the architecture.\n"
+	write("CLAUDE.md", content)
+	ctx := BuildContext(primary, content)
 
-	// Count commits since a year ago — should find at least 1
-	since := time.Now().Add(-365 * 24 * time.Hour).Format(time.RFC3339)
-	count := countCommitsSince(tmpDir, since)
-	if count < 1 {
-		t.Errorf("expected at least 1 commit, got %d", count)
+	refs := ResolveReferences(ctx, tmpDir, 90)
+	got := make(map[string]RefInfo)
+	for _, ref := range FlattenRefs(refs) {
+		got[ref.Path] = ref
+	}
+	if len(got) != 5 {
+		t.Fatalf("expected 5 refs, got %d: %v", len(got), got)
+	}
+
+	tests := []struct {
+		path  string
+		kind  RefKind
+		eager bool
+	}{
+		{"docs/style.md", RefImport, true},
+		{"rules.md", RefImport, true},
+		{"lazy.md", RefReference, false},
+		{"old.md", RefImport, false},
+		{"docs/arch.md", RefReference, false},
+	}
+	for _, tt := range tests {
+		ref, ok := got[tt.path]
+		if !ok {
+			t.Errorf("%s: not resolved", tt.path)
+			continue
+		}
+		if ref.Kind != tt.kind || ref.Eager != tt.eager {
+			t.Errorf("%s: kind=%s eager=%v, want kind=%s eager=%v", tt.path, ref.Kind, ref.Eager, tt.kind, tt.eager)
+		}
+	}
+	if _, ok := got["../CLAUDE.md"]; ok {
+		t.Error("expected the import of the primary file to be skipped as a cycle")
 	}
 }
 
-func TestCountCommitsSince_DormantSubdir(t *testing.T) {
+func TestResolveReferences_ImportDepthLimit(t *testing.T) {
 	tmpDir := t.TempDir()
-
-	run := func(args ...string) {
-		cmd := exec.Command(args[0], args[1:]...)
-		cmd.Dir = tmpDir
-		if out, err := cmd.CombinedOutput(); err != nil {
-			t.Fatalf("%v failed: %s", args, out)
+	// CLAUDE.md -> f1 -> f2 -> ... each by @import
+	for i := 1; i <= maxImportDepth+2; i++ {
+		content := "- Level " + string(rune('0'+i)) + " rule\n@f" + string(rune('0'+i+1)) + ".md\n"
+		if err := os.WriteFile(filepath.Join(tmpDir, "f"+string(rune('0'+i))+".md"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
 		}
 	}
-	run("git", "init")
-	run("git", "config", "user.email", "test@test.com")
-	run("git", "config", "user.name", "Test")
+	ctx := BuildContext(filepath.Join(tmpDir, "CLAUDE.md"), "@f1.md\n")
 
-	// Create a subdir with a file committed long ago
-	subDir := filepath.Join(tmpDir, "subdir")
-	if err := os.MkdirAll(subDir, 0755); err != nil {
-		t.Fatal(err)
-	}
-	if err := os.WriteFile(filepath.Join(subDir, "old.txt"), []byte("old"), 0644); err != nil {
-		t.Fatal(err)
-	}
-	run("git", "add", "subdir/old.txt")
-	run("git", "commit", "-m", "old commit")
-
-	// Count commits in subdir since 1 second from now (future) — should be 0
-	since := time.Now().Add(time.Second).Format(time.RFC3339)
-	count := countCommitsSince(subDir, since)
-	if count != 0 {
-		t.Errorf("expected 0 commits since future date, got %d", count)
+	flat := FlattenRefs(ResolveReferences(ctx, tmpDir, 90))
+	if len(flat) != maxImportDepth {
+		t.Errorf("expected imports followed %d hops, got %d", maxImportDepth, len(flat))
 	}
 }
 
-func TestEnrichContextWithRefMetrics_Empty(t *testing.T) {
-	ctx := &AnalysisContext{
-		Metrics: make(map[string]any),
+func TestEnrichContextWithRefMetrics_CountsEagerImports(t *testing.T) {
+	primary := BuildContext("CLAUDE.md", "# Project\n\n- Run make test before pushing\n")
+	imported := BuildContext("docs/style.md", "- Use tabs in Makefiles\n- Wrap errors with context\n")
+	lazy := BuildContext("docs/arch.md", "- Keep handlers thin and simple\n")
+	own := *primary
+
+	refs := []RefInfo{
+		{Path: "docs/style.md", Kind: RefImport, Eager: true, Exists: true, Context: imported},
+		{Path: "docs/arch.md", Kind: RefReference, Exists: true, Context: lazy},
 	}
+	EnrichContextWithRefMetrics(primary, refs)
+	EnrichContextWithRefMetrics(primary, refs) // counting twice must not double
 
-	EnrichContextWithRefMetrics(ctx, nil)
+	if primary.LineCount != own.LineCount+imported.LineCount {
+		t.Errorf("LineCount = %d, want %d", primary.LineCount, own.LineCount+imported.LineCount)
+	}
+	if primary.InstructionCount != own.InstructionCount+imported.InstructionCount {
+		t.Errorf("InstructionCount = %d, want %d", primary.InstructionCount, own.InstructionCount+imported.InstructionCount)
+	}
+	if primary.TokenCount != own.TokenCount+imported.TokenCount {
+		t.Errorf("TokenCount = %d, want %d", primary.TokenCount, own.TokenCount+imported.TokenCount)
+	}
+	if primary.Imported.Files != 1 {
+		t.Errorf("expected 1 imported file, got %d", primary.Imported.Files)
+	}
 
-	brokenCount, ok := ctx.Metrics["broken_references_count"].(int)
-	if !ok || brokenCount != 0 {
-		t.Errorf("expected broken_references_count=0, got %v", ctx.Metrics["broken_references_count"])
+	// The aggregate counts every file once
+	agg := ComputeAggregateMetrics(primary, refs, DefaultSimilarityThreshold)
+	want := own.InstructionCount + imported.InstructionCount + lazy.InstructionCount
+	if agg.TotalInstructionCount != want {
+		t.Errorf("TotalInstructionCount = %d, want %d", agg.TotalInstructionCount, want)
 	}
 }
@@ -0,0 +1,190 @@
+package rules
+
+import (
+	"regexp"
+	"strings"
+)
+
+// AllSections is the MatchSpec.Section selector matching every headed section
+const AllSections = "*"
+
+// Section is a heading and the content under it. Line, instruction and token
+// counts cover the section's own lines, up to the next heading of any level;
+// subsections are counted separately in Children.
+type Section struct {
+	Title            string // heading text ("" for the root)
+	Level            int    // heading level 1-6 (0 for the root)
+	Line             int    // heading line, 1-based
+	EndLine          int    // last line of the section including its subsections
+	LineCount        int    // lines from the heading to the next heading
+	InstructionCount int    // instructions in those lines
+	TokenCount       int    // tokens in those lines
+	Children         []*Section
+}
+
+// BuildSections builds the heading tree of a parsed file. The root section
+// covers the whole file and holds text before the first heading.
+func BuildSections(lines []string, doc *MarkdownDoc) *Section {
+	root := &Section{Line: 1, EndLine: len(lines)}
+
+	var headings []*MarkdownNode
+	doc.Walk(func(n *MarkdownNode) bool {
+		// Headings inside lists and quotes don't structure the document
+		if n.Type == NodeHeading {
+			headings = append(headings, n)
+		}
+		return n.Type == NodeDocument
+	})
+
+	instr := instructionLines(lines, doc)
+	countInstr := func(from, to int) int {
+		n := 0
+		for l := from; l <= to && l <= len(instr); l++ {
+			if instr[l-1] {
+				n++
+			}
+		}
+		return n
+	}
+
+	countTokens := func(from, to int) int {
+		if from > to {
+			return 0
+		}
+		return CountTokens(strings.Join(lines[from-1:to], "\n"))
+	}
+
+	ownEnd := func(i int) int {
+		if i+1 < len(headings) {
+			return headings[i+1].Line - 1
+		}
+		return len(lines)
+	}
+
+	preambleEnd := len(lines)
+	if len(headings) > 0 {
+		preambleEnd = headings[0].Line - 1
+	}
+	root.LineCount = preambleEnd
+	root.InstructionCount = countInstr(1, preambleEnd)
+	root.TokenCount = countTokens(1, preambleEnd)
+
+	stack := []*Section{root}
+	for i, h := range headings {
+		end := ownEnd(i)
+		s := &Section{
+			Title:            h.Text,
+			Level:            h.Level,
+			Line:             h.Line,
+			EndLine:          len(lines),
+			LineCount:        end - h.Line + 1,
+			InstructionCount: countInstr(h.Line, end),
+			TokenCount:       countTokens(h.Line, end),
+		}
+
+		// Close every open section at this level or deeper
+		for len(stack) > 1 && stack[len(stack)-1].Level >= s.Level {
+			stack[len(stack)-1].EndLine = h.Line - 1
+			stack = stack[:len(stack)-1]
+		}
+		parent := stack[len(stack)-1]
+		parent.Children = append(parent.Children, s)
+		stack = append(stack, s)
+	}
+
+	return root
+}
+
+// Walk visits the section and its subsections depth-first
+func (s *Section) Walk(fn func(*Section)) {
+	fn(s)
+	for _, c := range s.Children {
+		c.Walk(fn)
+	}
+}
+
+// FindSections returns the headed sections whose title matches the selector:
+// a case-insensitive regex, or "*" for every section
+func (s *Section) FindSections(selector string) []*Section {
+	var match func(title string) bool
+	if selector == AllSections {
+		match = func(string) bool { return true }
+	} else {
+		re, err := regexp.Compile("(?i)" + selector)
+		if err != nil {
+			needle := strings.ToLower(selector)
+			match = func(title string) bool { return strings.Contains(strings.ToLower(title), needle) }
+		} else {
+			match = re.MatchString
+		}
+	}
+
+	var found []*Section
+	s.Walk(func(sec *Section) {
+		if sec.Level > 0 && match(sec.Title) {
+			found = append(found, sec)
+		}
+	})
+	return found
+}
+
+// sectionsOf returns ctx's section tree, building it for contexts not made by BuildContext
+func sectionsOf(ctx *AnalysisContext) *Section {
+	if ctx.Sections == nil {
+		lines := strings.Split(ctx.Content, "\n")
+		ctx.Sections = BuildSections(lines, markdownOf(ctx))
+	}
+	return ctx.Sections
+}
+
+// restrictToSections blanks every line of content outside the sections
+// matching the selector; subsections belong to their parent
+func restrictToSections(ctx *AnalysisContext, content, selector string) string {
+	sections := sectionsOf(ctx).FindSections(selector)
+	lines := strings.Split(content, "\n")
+	keep := make([]bool, len(lines))
+	for _, s := range sections {
+		for l := s.Line; l <= s.EndLine && l <= len(lines); l++ {
+			keep[l-1] = true
+		}
+	}
+	for i := range lines {
+		if !keep[i] {
+			lines[i] = ""
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// sectionMetric returns a section's value for the line, instruction or token count metric
+func sectionMetric(s *Section, metric MetricType) (int, bool) {
+	switch metric {
+	case MetricLineCount:
+		return s.LineCount, true
+	case MetricInstructionCount:
+		return s.InstructionCount, true
+	case MetricTokenCount:
+		return s.TokenCount, true
+	default:
+		return 0, false
+	}
+}
+
+// matchingSections returns the sections selected by spec whose metric
+// satisfies cmp against the spec's value
+func matchingSections(ctx *AnalysisContext, spec *MatchSpec, cmp func(actual, threshold int) bool) []*Section {
+	threshold, ok := toInt(spec.Value)
+	if !ok {
+		return nil
+	}
+	var found []*Section
+	for _, s := range sectionsOf(ctx).FindSections(spec.Section) {
+		if actual, ok := sectionMetric(s, spec.Metric); ok && cmp(actual, threshold) {
+			found = append(found, s)
+		}
+	}
+	return found
+}
+
+func lessThan(actual, threshold int) bool    { return actual < threshold }
+func greaterThan(actual, threshold int) bool { return actual > threshold }
@@ -0,0 +1,126 @@
+package rules
+
+import (
+	"strings"
+	"testing"
+)
+
+const sectionedDoc = `Preamble text
+# Project
+Overview of the project.
+## Build & Test
+- Always run make test before committing
+- Run make lint for style checks
+### Integration
+- Never run integration tests against production
+## Code Style
+- Prefer small functions in every package`
+
+func TestBuildSections(t *testing.T) {
+	ctx := BuildContext("CLAUDE.md", sectionedDoc)
+	root := ctx.Sections
+
+	if root.Level != 0 || root.LineCount != 1 {
+		t.Errorf("root: level %d, %d lines; want preamble of 1 line", root.Level, root.LineCount)
+	}
+	if len(root.Children) != 1 || root.Children[0].Title != "Project" {
+		t.Fatalf("expected one top-level section, got %+v", root.Children)
+	}
+
+	project := root.Children[0]
+	if len(project.Children) != 2 {
+		t.Fatalf("expected Build & Test and Code Style under Project, got %d", len(project.Children))
+	}
+
+	build := project.Children[0]
+	if build.Title != "Build & Test" || build.Line != 4 || build.EndLine != 8 {
+		t.Errorf("build: %q lines %d-%d, want lines 4-8", build.Title, build.Line, build.EndLine)
+	}
+	if build.LineCount != 3 || build.InstructionCount != 2 {
+		t.Errorf("build: %d lines, %d instructions; want own content only (3, 2)", build.LineCount, build.InstructionCount)
+	}
+	if len(build.Children) != 1 || build.Children[0].InstructionCount != 1 {
+		t.Errorf("expected Integration subsection with 1 instruction, got %+v", build.Children)
+	}
+
+	style := project.Children[1]
+	if style.EndLine != 10 {
+		t.Errorf("last section should run to the end of the file, got %d", style.EndLine)
+	}
+}
+
+func TestBuildSections_IgnoresHeadingsInCode(t *testing.T) {
+	ctx := BuildContext("CLAUDE.md", "# Real\n```\n# not a heading\n```")
+	if got := len(ctx.Sections.FindSections(AllSections)); got != 1 {
+		t.Errorf("expected 1 section, got %d", got)
+	}
+}
+
+func TestFindSections(t *testing.T) {
+	root := BuildContext("CLAUDE.md", sectionedDoc).Sections
+
+	tests := []struct {
+		selector string
+		want     []string
+	}{
+		{"*", []string{"Project", "Build & Test", "Integration", "Code Style"}},
+		{"build", []string{"Build & Test"}},
+		{"^(build|code)", []string{"Build & Test", "Code Style"}},
+		{"deploy", nil},
+		{"[unclosed", nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.selector, func(t *testing.T) {
+			var got []string
+			for _, s := range root.FindSections(tc.selector) {
+				got = append(got, s.Title)
+			}
+			if strings.Join(got, "|") != strings.Join(tc.want, "|") {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSectionSelector_Content(t *testing.T) {
+	ctx := BuildContext("CLAUDE.md", sectionedDoc)
+
+	spec := &MatchSpec{Action: ActionRegexNotMatch, Section: "build", Patterns: []string{`make\s+test`}}
+	if EvaluateSpec(ctx, spec) {
+		t.Error("Build section mentions make test, so regexNotMatch should not fire")
+	}
+
+	spec.Section = "code style"
+	if !EvaluateSpec(ctx, spec) {
+		t.Error("Code Style doesn't mention make test, so regexNotMatch should fire")
+	}
+
+	// Subsections belong to their parent
+	spec = &MatchSpec{Action: ActionRegexMatch, Section: "build", Patterns: []string{"production"}}
+	if !EvaluateSpec(ctx, spec) {
+		t.Error("expected the Integration subsection to be part of Build & Test")
+	}
+}
+
+func TestSectionSelector_Metrics(t *testing.T) {
+	ctx := BuildContext("CLAUDE.md", sectionedDoc)
+
+	spec := &MatchSpec{Metric: MetricInstructionCount, Section: AllSections, Action: ActionGreaterThan, Value: 1}
+	if !EvaluateSpec(ctx, spec) {
+		t.Fatal("expected Build & Test (2 instructions) to exceed 1")
+	}
+	spans := FindSpans(ctx, spec)
+	if len(spans) != 1 || spans[0].Line != 4 || spans[0].Text != "Build & Test" {
+		t.Errorf("expected a span at the Build & Test heading, got %+v", spans)
+	}
+
+	spec.Value = 2
+	if EvaluateSpec(ctx, spec) {
+		t.Error("no section has more than 2 instructions")
+	}
+
+	lt := &MatchSpec{Metric: MetricLineCount, Section: "integration", Action: ActionLessThan, Value: 3}
+	if !EvaluateSpec(ctx, lt) {
+		t.Error("expected Integration (2 lines) to be under 3 lines")
+	}
+}
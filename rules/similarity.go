@@ -0,0 +1,98 @@
+package rules
+
+import (
+	"regexp"
+	"strings"
+)
+
+// DefaultSimilarityThreshold is the word-overlap score at which two instructions
+// count as duplicates. 1 only matches instructions with the same stemmed words.
+const DefaultSimilarityThreshold = 0.8
+
+// wordPattern splits text into words for similarity comparison
+var wordPattern = regexp.MustCompile(`[a-z0-9]+`)
+
+// stopWords carry no meaning of their own in an instruction
+var stopWords = map[string]bool{
+	"a": true, "an": true, "the": true, "to": true, "of": true, "and": true, "or": true,
+	"you": true, "your": true, "we": true, "our": true, "it": true, "its": true,
+	"is": true, "are": true, "be": true, "do": true, "this": true, "that": true,
+	"always": true, "please": true, "sure": true, "must": true, "should": true,
+}
+
+// negationWords flip the meaning of an instruction; instructions only match
+// when both or neither contain one
+var negationWords = map[string]bool{
+	"not": true, "never": true, "no": true, "avoid": true,
+}
+
+// wordSet is an instruction reduced to its distinct stemmed words
+type wordSet struct {
+	words   map[string]bool
+	negated bool
+}
+
+// instructionWords lowercases, splits, drops stop words and stems an instruction
+func instructionWords(s string) wordSet {
+	s = strings.ToLower(s)
+	s = strings.ReplaceAll(s, "n't", " not")
+
+	set := wordSet{words: make(map[string]bool)}
+	for _, w := range wordPattern.FindAllString(s, -1) {
+		if stopWords[w] {
+			continue
+		}
+		if negationWords[w] {
+			set.negated = true
+		}
+		set.words[stem(w)] = true
+	}
+	return set
+}
+
+// Similarity returns the Jaccard similarity (0-1) of the stemmed words of two instructions.
+// Instructions where only one side is negated ("use X" / "never use X") score 0.
+func Similarity(a, b string) float64 {
+	return instructionWords(a).similarity(instructionWords(b))
+}
+
+func (a wordSet) similarity(b wordSet) float64 {
+	if a.negated != b.negated || len(a.words) == 0 || len(b.words) == 0 {
+		return 0
+	}
+	shared := 0
+	for w := range a.words {
+		if b.words[w] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a.words)+len(b.words)-shared)
+}
+
+// stem strips common English suffixes so "committing", "commits" and "commit"
+// compare equal. It is deliberately crude; both sides go through the same steps.
+func stem(w string) string {
+	switch {
+	case len(w) > 4 && strings.HasSuffix(w, "ies"):
+		w = w[:len(w)-3] + "y"
+	case len(w) > 3 && strings.HasSuffix(w, "s") && !strings.HasSuffix(w, "ss"):
+		w = w[:len(w)-1]
+	}
+
+	switch {
+	case len(w) > 5 && strings.HasSuffix(w, "ing"):
+		w = w[:len(w)-3]
+	case len(w) > 4 && strings.HasSuffix(w, "ed"):
+		w = w[:len(w)-2]
+	}
+
+	// committ -> commit, runn -> run
+	if n := len(w); n > 2 && w[n-1] == w[n-2] && !strings.ContainsRune("aeiouslz", rune(w[n-1])) {
+		w = w[:n-1]
+	}
+
+	if len(w) >= 3 && strings.HasSuffix(w, "e") {
+		w = w[:len(w)-1]
+	}
+	return w
+}
@@ -0,0 +1,46 @@
+package rules
+
+import (
+	"testing"
+)
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		min  float64
+		max  float64
+	}{
+		{"Run make test before committing", "Always run `make test` before you commit", 1, 1},
+		{"Use pnpm for installs", "use pnpm for installs", 1, 1},
+		{"Use pnpm for installs", "Use yarn for installs", 0.3, 0.7},
+		{"Use pnpm", "Never use pnpm", 0, 0},
+		{"Avoid global state", "Never use global state", 0.4, 0.8},
+		{"", "Use pnpm", 0, 0},
+	}
+
+	for _, tt := range tests {
+		got := Similarity(tt.a, tt.b)
+		if got < tt.min || got > tt.max {
+			t.Errorf("Similarity(%q, %q) = %v, want between %v and %v", tt.a, tt.b, got, tt.min, tt.max)
+		}
+	}
+}
+
+func TestStem(t *testing.T) {
+	tests := map[string]string{
+		"committing":   "commit",
+		"commits":      "commit",
+		"commit":       "commit",
+		"running":      "run",
+		"uses":         "us",
+		"use":          "us",
+		"dependencies": "dependency",
+		"pass":         "pass",
+	}
+
+	for word, want := range tests {
+		if got := stem(word); got != want {
+			t.Errorf("stem(%q) = %q, want %q", word, got, want)
+		}
+	}
+}
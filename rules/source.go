@@ -0,0 +1,393 @@
+package rules
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Source is where an analysis reads the repository from: the working tree,
+// or a revision or the index of a git repository. Context files, the docs
+// they reference and import, and the repo files checked against them
+// (stacks, commands, mentions) are all read through the active source.
+type Source interface {
+	ReadFile(path string) ([]byte, error)
+	Stat(path string) (fs.FileInfo, error)
+	// ListFiles lists the files under root, slash-separated and relative to it
+	ListFiles(root string) []string
+	// LastModified returns when the last commit the source includes changed
+	// path, or the zero time if none did
+	LastModified(path string) time.Time
+	// Now is the moment the source shows, which ages are measured from
+	Now() time.Time
+}
+
+// activeSource is the source every analysis reads from
+var activeSource Source = WorkingTree{}
+
+// SetSource makes s the source analyses read from
+func SetSource(s Source) {
+	activeSource = s
+}
+
+// ActiveSource returns the source analyses read from
+func ActiveSource() Source {
+	return activeSource
+}
+
+// =============================================================================
+// Working tree
+// =============================================================================
+
+// WorkingTree reads files from disk
+type WorkingTree struct{}
+
+func (WorkingTree) ReadFile(path string) ([]byte, error) { return os.ReadFile(path) }
+
+func (WorkingTree) Stat(path string) (fs.FileInfo, error) { return os.Stat(path) }
+
+func (WorkingTree) LastModified(path string) time.Time { return getGitLastModified(path) }
+
+func (WorkingTree) Now() time.Time { return time.Now() }
+
+// ListFiles lists tracked and untracked but not ignored files in git repos,
+// otherwise everything outside hidden, node_modules and vendor directories
+func (WorkingTree) ListFiles(root string) []string {
+	cmd := exec.Command("git", "ls-files", "--cached", "--others", "--exclude-standard")
+	cmd.Dir = root
+	if output, err := cmd.Output(); err == nil {
+		var files []string
+		for _, line := range strings.Split(string(output), "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				files = append(files, line)
+			}
+		}
+		return files
+	}
+
+	var files []string
+	_ = filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() {
+			base := info.Name()
+			if p != root && (strings.HasPrefix(base, ".") || base == "node_modules" || base == "vendor") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if rel, err := filepath.Rel(root, p); err == nil {
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	return files
+}
+
+// =============================================================================
+// Git revisions and the index
+// =============================================================================
+
+// StagedRevision names the index when creating a GitSource
+const StagedRevision = ":"
+
+// GitSource reads the files of a commit, or of the index, from git objects
+// without checking anything out. Paths outside the repository are read from
+// disk. Close it when done.
+type GitSource struct {
+	Root   string    // repository root
+	Rev    string    // the revision asked for, or StagedRevision
+	Commit string    // the commit Rev resolved to; HEAD's for the index
+	Time   time.Time // the commit's time; for the index, when it was read
+
+	blobs map[string]string // object names by repo-relative path
+	dirs  map[string]bool
+
+	mu  sync.Mutex
+	cat *exec.Cmd // a "git cat-file --batch" reading the blobs
+	in  io.WriteCloser
+	out *bufio.Reader
+}
+
+// NewGitSource lists the files of rev in the repository at root, or with
+// StagedRevision, the files in its index
+func NewGitSource(root, rev string) (*GitSource, error) {
+	s := &GitSource{Root: root, Rev: rev, blobs: map[string]string{}, dirs: map[string]bool{}}
+
+	var listing []byte
+	var err error
+	if rev == StagedRevision {
+		s.Commit, _ = gitOutput(root, "rev-parse", "--verify", "-q", "HEAD") // none before the first commit
+		s.Time = time.Now()
+		listing, err = gitBytes(root, "ls-files", "--stage", "-z")
+	} else {
+		out, logErr := gitOutput(root, "log", "-1", "--format=%H %ct", rev+"^{commit}", "--")
+		var unix int64
+		if _, scanErr := fmt.Sscanf(out, "%s %d", &s.Commit, &unix); logErr != nil || scanErr != nil {
+			return nil, fmt.Errorf("unknown revision %q", rev)
+		}
+		s.Time = time.Unix(unix, 0)
+		listing, err = gitBytes(root, "ls-tree", "-r", "-z", "--full-tree", s.Commit)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Entries are "<mode> <type> <object>\t<path>" in trees and
+	// "<mode> <object> <stage>\t<path>" in the index
+	for _, entry := range bytes.Split(listing, []byte{0}) {
+		meta, p, ok := strings.Cut(string(entry), "\t")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(meta)
+		if len(fields) != 3 || strings.HasPrefix(fields[0], "160") {
+			continue // submodules have no blob
+		}
+		object := fields[2]
+		if rev == StagedRevision {
+			object = fields[1]
+		}
+		if _, dup := s.blobs[p]; !dup {
+			s.blobs[p] = object
+		}
+		for d := path.Dir(p); d != "." && !s.dirs[d]; d = path.Dir(d) {
+			s.dirs[d] = true
+		}
+	}
+	return s, nil
+}
+
+// StagedFiles returns the paths, relative to root, that the index adds,
+// modifies or deletes compared with HEAD. A rename stages both paths.
+func StagedFiles(root string) (map[string]bool, error) {
+	out, err := gitBytes(root, "diff", "--cached", "--name-only", "--no-renames", "-z")
+	if err != nil {
+		return nil, fmt.Errorf("listing staged files: %w", err)
+	}
+	staged := map[string]bool{}
+	for _, p := range strings.Split(string(out), "\x00") {
+		if p != "" {
+			staged[p] = true
+		}
+	}
+	return staged, nil
+}
+
+// rel returns p relative to the repository root, or false outside it
+func (s *GitSource) rel(p string) (string, bool) {
+	return RepoPath(s.Root, p)
+}
+
+func (s *GitSource) ReadFile(p string) ([]byte, error) {
+	rel, ok := s.rel(p)
+	if !ok {
+		return os.ReadFile(p)
+	}
+	object, ok := s.blobs[rel]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: p, Err: fs.ErrNotExist}
+	}
+	data, err := s.readBlob(object)
+	if err != nil {
+		return nil, &fs.PathError{Op: "read", Path: p, Err: err}
+	}
+	return data, nil
+}
+
+// readBlob reads an object through one cat-file process for all reads
+func (s *GitSource) readBlob(object string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cat == nil {
+		cmd := exec.Command("git", "cat-file", "--batch")
+		cmd.Dir = s.Root
+		in, err := cmd.StdinPipe()
+		if err != nil {
+			return nil, err
+		}
+		out, err := cmd.StdoutPipe()
+		if err != nil {
+			return nil, err
+		}
+		if err := cmd.Start(); err != nil {
+			return nil, err
+		}
+		s.cat, s.in, s.out = cmd, in, bufio.NewReader(out)
+	}
+
+	if _, err := fmt.Fprintln(s.in, object); err != nil {
+		return nil, err
+	}
+	// "<object> <type> <size>", then the content and a newline
+	header, err := s.out.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.Fields(header)
+	if len(fields) != 3 {
+		return nil, fmt.Errorf("object %s: %s", object, strings.TrimSpace(header))
+	}
+	size, err := strconv.Atoi(fields[2])
+	if err != nil {
+		return nil, err
+	}
+	data := make([]byte, size+1)
+	if _, err := io.ReadFull(s.out, data); err != nil {
+		return nil, err
+	}
+	return data[:size], nil
+}
+
+// Close stops the process reading blobs
+func (s *GitSource) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cat == nil {
+		return nil
+	}
+	s.in.Close()
+	err := s.cat.Wait()
+	s.cat = nil
+	return err
+}
+
+func (s *GitSource) Stat(p string) (fs.FileInfo, error) {
+	rel, ok := s.rel(p)
+	if !ok {
+		return os.Stat(p)
+	}
+	_, file := s.blobs[rel]
+	if !file && !s.dirs[rel] && rel != "." {
+		return nil, &fs.PathError{Op: "stat", Path: p, Err: fs.ErrNotExist}
+	}
+	return gitFileInfo{name: path.Base(rel), dir: !file, modTime: s.Time}, nil
+}
+
+func (s *GitSource) ListFiles(root string) []string {
+	prefix, ok := s.rel(root)
+	if !ok {
+		return WorkingTree{}.ListFiles(root)
+	}
+	var files []string
+	for p := range s.blobs {
+		switch {
+		case prefix == ".":
+			files = append(files, p)
+		case strings.HasPrefix(p, prefix+"/"):
+			files = append(files, strings.TrimPrefix(p, prefix+"/"))
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
+func (s *GitSource) LastModified(p string) time.Time {
+	rel, ok := s.rel(p)
+	if !ok || s.Commit == "" {
+		return time.Time{}
+	}
+	out, err := gitOutput(s.Root, "log", "-1", "--format=%ct", s.Commit, "--", rel)
+	if err != nil {
+		return time.Time{}
+	}
+	unix, err := strconv.ParseInt(out, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(unix, 0)
+}
+
+func (s *GitSource) Now() time.Time { return s.Time }
+
+// gitFileInfo describes a file or directory in a git revision
+type gitFileInfo struct {
+	name    string
+	dir     bool
+	modTime time.Time
+}
+
+func (fi gitFileInfo) Name() string       { return fi.name }
+func (fi gitFileInfo) Size() int64        { return 0 }
+func (fi gitFileInfo) ModTime() time.Time { return fi.modTime }
+func (fi gitFileInfo) IsDir() bool        { return fi.dir }
+func (fi gitFileInfo) Sys() any           { return nil }
+
+func (fi gitFileInfo) Mode() fs.FileMode {
+	if fi.dir {
+		return fs.ModeDir | 0755
+	}
+	return 0644
+}
+
+// sourceKey identifies what the active source shows, for caches
+func sourceKey() string {
+	if s, ok := activeSource.(*GitSource); ok {
+		return s.Rev + "@" + s.Commit
+	}
+	return ""
+}
+
+// sourceHead is the commit the active source shows, for history queries
+func sourceHead() string {
+	if s, ok := activeSource.(*GitSource); ok && s.Rev != StagedRevision {
+		return s.Commit
+	}
+	return "HEAD"
+}
+
+// RepoPath returns p relative to the repository at root, slash-separated,
+// or false when p is outside it
+func RepoPath(root, p string) (string, bool) {
+	abs, err := realPath(p)
+	if err != nil {
+		return "", false
+	}
+	rel, err := filepath.Rel(root, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// realPath makes p absolute with symlinks resolved, like the paths git
+// reports. Missing trailing components, e.g. of a deleted file, are kept.
+func realPath(p string) (string, error) {
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return "", err
+	}
+	var missing []string
+	for dir := abs; ; dir = filepath.Dir(dir) {
+		if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+			return filepath.Join(append([]string{resolved}, missing...)...), nil
+		}
+		if filepath.Dir(dir) == dir {
+			return abs, nil
+		}
+		missing = append([]string{filepath.Base(dir)}, missing...)
+	}
+}
+
+func gitBytes(dir string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	return cmd.Output()
+}
+
+func gitOutput(dir string, args ...string) (string, error) {
+	out, err := gitBytes(dir, args...)
+	return strings.TrimSpace(string(out)), err
+}
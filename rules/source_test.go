@@ -0,0 +1,118 @@
+package rules
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGitSource_Revision(t *testing.T) {
+	dir, run := gitRepo(t)
+	writeFiles(t, dir, map[string]string{"CLAUDE.md": "v1", "docs/guide.md": "guide"})
+	run("add", ".")
+	run("commit", "-qm", "first")
+	writeFiles(t, dir, map[string]string{"CLAUDE.md": "v2"})
+	run("rm", "-q", "docs/guide.md")
+	run("commit", "-qam", "second")
+
+	src, err := NewGitSource(dir, "HEAD~1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+
+	if data, err := src.ReadFile(filepath.Join(dir, "CLAUDE.md")); err != nil || string(data) != "v1" {
+		t.Errorf("expected the first revision, got %q %v", data, err)
+	}
+	if data, err := src.ReadFile(filepath.Join(dir, "docs", "guide.md")); err != nil || string(data) != "guide" {
+		t.Errorf("expected the deleted file, got %q %v", data, err)
+	}
+	if info, err := src.Stat(filepath.Join(dir, "docs")); err != nil || !info.IsDir() {
+		t.Errorf("expected docs to be a directory, got %v %v", info, err)
+	}
+	if _, err := src.ReadFile(filepath.Join(dir, "missing.md")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected not-exist, got %v", err)
+	}
+	if got := strings.Join(src.ListFiles(dir), ","); got != "CLAUDE.md,docs/guide.md" {
+		t.Errorf("unexpected files %s", got)
+	}
+	if src.LastModified(filepath.Join(dir, "CLAUDE.md")) != src.Now() {
+		t.Errorf("expected the revision's own time")
+	}
+
+	if _, err := NewGitSource(dir, "no-such-branch"); err == nil {
+		t.Error("expected an error for an unknown revision")
+	}
+}
+
+func TestGitSource_Staged(t *testing.T) {
+	dir, run := gitRepo(t)
+	writeFiles(t, dir, map[string]string{"CLAUDE.md": "committed"})
+	run("add", ".")
+	run("commit", "-qm", "first")
+	writeFiles(t, dir, map[string]string{"CLAUDE.md": "staged"})
+	run("add", "CLAUDE.md")
+	writeFiles(t, dir, map[string]string{"CLAUDE.md": "unstaged", "new.md": "untracked"})
+
+	src, err := NewGitSource(dir, StagedRevision)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+
+	if data, _ := src.ReadFile(filepath.Join(dir, "CLAUDE.md")); string(data) != "staged" {
+		t.Errorf("expected the staged content, got %q", data)
+	}
+	if _, err := src.Stat(filepath.Join(dir, "new.md")); err == nil {
+		t.Error("expected untracked files to be missing from the index")
+	}
+}
+
+func TestStagedFiles(t *testing.T) {
+	dir, run := gitRepo(t)
+	writeFiles(t, dir, map[string]string{"CLAUDE.md": "v1", "docs/old.md": "old", "docs/keep.md": "keep"})
+
+	// Before the first commit everything in the index is staged
+	run("add", ".")
+	if staged, err := StagedFiles(dir); err != nil || len(staged) != 3 {
+		t.Fatalf("expected all three files staged, got %v %v", staged, err)
+	}
+	run("commit", "-qm", "first")
+
+	writeFiles(t, dir, map[string]string{"CLAUDE.md": "v2", "docs/keep.md": "edited, not staged"})
+	run("add", "CLAUDE.md")
+	run("mv", "docs/old.md", "docs/new.md")
+
+	staged, err := StagedFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(sortedKeys(staged), ","); got != "CLAUDE.md,docs/new.md,docs/old.md" {
+		t.Errorf("unexpected staged files %s", got)
+	}
+}
+
+func TestGitSource_DrivesAnalysis(t *testing.T) {
+	dir, run := gitRepo(t)
+	writeFiles(t, dir, map[string]string{"Makefile": "lint:\n\ttrue\n"})
+	run("add", ".")
+	run("commit", "-qm", "first")
+	writeFiles(t, dir, map[string]string{"Makefile": "test:\n\ttrue\n"})
+	run("commit", "-qam", "second")
+
+	src, err := NewGitSource(dir, "HEAD~1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	SetSource(src)
+	defer SetSource(WorkingTree{})
+
+	ctx := BuildContext(filepath.Join(dir, "CLAUDE.md"), "Run `make lint` and `make test`.")
+	dead := ValidateCommands(ctx, dir)
+	if len(dead) != 1 || dead[0].Name != "test" {
+		t.Errorf("expected the old Makefile to be checked, got %+v", dead)
+	}
+}
@@ -0,0 +1,253 @@
+package rules
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Span locates a piece of matched text within a file
+type Span struct {
+	Line    int    // 1-based line number
+	Column  int    // 1-based column, counted in characters
+	Text    string // the matched text
+	Snippet string // the full source line, trimmed
+}
+
+// SpanFunc returns the locations that made a spec match
+type SpanFunc func(ctx *AnalysisContext, spec *MatchSpec) []Span
+
+// SpanRegistry holds location finders for actions that match content.
+// Actions that check for absence or compare file-level metrics have no locations.
+var SpanRegistry map[CheckAction]SpanFunc
+
+func init() {
+	SpanRegistry = map[CheckAction]SpanFunc{
+		ActionContains:    spansContains,
+		ActionRegexMatch:  spansRegexMatch,
+		ActionIsPresent:   spansIsPresent,
+		ActionAnd:         spansAnd,
+		ActionOr:          spansOr,
+		ActionLessThan:    spansSectionLessThan,
+		ActionGreaterThan: spansSectionGreaterThan,
+	}
+}
+
+// FindSpans returns the locations in ctx that caused spec to match, ordered by position
+func FindSpans(ctx *AnalysisContext, spec *MatchSpec) []Span {
+	spanFn, ok := SpanRegistry[spec.Action]
+	if !ok {
+		return nil
+	}
+	return sortSpans(spanFn(ctx, spec))
+}
+
+// spanContent returns the text spans are computed against, or false when the
+// spec checks a non-content metric that has no position in the file
+func spanContent(ctx *AnalysisContext, spec *MatchSpec) (string, bool) {
+	if spec.Metric != "" && spec.Metric != MetricContent {
+		return "", false
+	}
+	return specContent(ctx, spec), true
+}
+
+func spansContains(ctx *AnalysisContext, spec *MatchSpec) []Span {
+	content, ok := spanContent(ctx, spec)
+	if !ok {
+		return nil
+	}
+
+	needles := spec.Patterns
+	if len(needles) == 0 && spec.Value != nil {
+		needles = []string{toString(spec.Value)}
+	}
+
+	var spans []Span
+	for _, needle := range needles {
+		spans = append(spans, findSubstringSpans(content, needle, true)...)
+	}
+	return spans
+}
+
+func spansRegexMatch(ctx *AnalysisContext, spec *MatchSpec) []Span {
+	content, ok := spanContent(ctx, spec)
+	if !ok {
+		return nil
+	}
+
+	patterns := spec.Patterns
+	if len(patterns) == 0 && spec.Value != nil {
+		patterns = []string{toString(spec.Value)}
+	}
+
+	var spans []Span
+	for _, pattern := range patterns {
+		re, err := regexp.Compile("(?i)" + pattern)
+		if err != nil {
+			continue
+		}
+		spans = append(spans, findRegexSpans(content, re)...)
+	}
+	return spans
+}
+
+func spansIsPresent(ctx *AnalysisContext, spec *MatchSpec) []Span {
+	content := specContent(ctx, spec)
+	var spans []Span
+
+	if len(spec.Patterns) > 0 {
+		for _, pattern := range spec.Patterns {
+			re, err := regexp.Compile("(?i)" + pattern)
+			if err != nil {
+				spans = append(spans, findSubstringSpans(content, pattern, true)...)
+				continue
+			}
+			spans = append(spans, findRegexSpans(content, re)...)
+		}
+		return spans
+	}
+
+	if spec.Value != nil {
+		return findSubstringSpans(content, toString(spec.Value), false)
+	}
+
+	return nil
+}
+
+func spansAnd(ctx *AnalysisContext, spec *MatchSpec) []Span {
+	var spans []Span
+	for _, sub := range spec.SubMatch {
+		spans = append(spans, FindSpans(ctx, &sub)...)
+	}
+	return spans
+}
+
+func spansOr(ctx *AnalysisContext, spec *MatchSpec) []Span {
+	var spans []Span
+	for _, sub := range spec.SubMatch {
+		if EvaluateSpec(ctx, &sub) {
+			spans = append(spans, FindSpans(ctx, &sub)...)
+		}
+	}
+	return spans
+}
+
+// Section-scoped metric checks point at the heading of each offending section
+func spansSectionLessThan(ctx *AnalysisContext, spec *MatchSpec) []Span {
+	return sectionSpans(ctx, spec, lessThan)
+}
+
+// spansSectionGreaterThan also locates count metrics that record where the
+// items they count appear
+func spansSectionGreaterThan(ctx *AnalysisContext, spec *MatchSpec) []Span {
+	if spec.Section == "" {
+		return ctx.MetricSpans[spec.Metric]
+	}
+	return sectionSpans(ctx, spec, greaterThan)
+}
+
+func sectionSpans(ctx *AnalysisContext, spec *MatchSpec, cmp func(actual, threshold int) bool) []Span {
+	if spec.Section == "" {
+		return nil
+	}
+	lines := strings.Split(ctx.Content, "\n")
+	var spans []Span
+	for _, s := range matchingSections(ctx, spec, cmp) {
+		snippet := ""
+		if s.Line <= len(lines) {
+			snippet = strings.TrimSpace(lines[s.Line-1])
+		}
+		spans = append(spans, Span{Line: s.Line, Column: 1, Text: s.Title, Snippet: snippet})
+	}
+	return spans
+}
+
+func findRegexSpans(content string, re *regexp.Regexp) []Span {
+	var spans []Span
+	for _, loc := range re.FindAllStringIndex(content, -1) {
+		if loc[0] == loc[1] {
+			continue
+		}
+		spans = append(spans, spanAt(content, loc[0], loc[1]))
+	}
+	return spans
+}
+
+func findSubstringSpans(content, needle string, foldCase bool) []Span {
+	if needle == "" {
+		return nil
+	}
+
+	haystack := content
+	if foldCase {
+		// ToLower keeps byte offsets stable for ASCII, which covers rule patterns
+		haystack = strings.ToLower(content)
+		needle = strings.ToLower(needle)
+		if len(haystack) != len(content) {
+			haystack = content
+		}
+	}
+
+	var spans []Span
+	offset := 0
+	for {
+		idx := strings.Index(haystack[offset:], needle)
+		if idx < 0 {
+			break
+		}
+		start := offset + idx
+		end := start + len(needle)
+		spans = append(spans, spanAt(content, start, end))
+		offset = end
+	}
+	return spans
+}
+
+// spanAt converts a byte range in content into a Span
+func spanAt(content string, start, end int) Span {
+	line := strings.Count(content[:start], "\n") + 1
+	lineStart := strings.LastIndex(content[:start], "\n") + 1
+	lineEnd := strings.Index(content[start:], "\n")
+	if lineEnd < 0 {
+		lineEnd = len(content)
+	} else {
+		lineEnd += start
+	}
+
+	// Multi-line matches are reported up to the end of their first line
+	if end > lineEnd {
+		end = lineEnd
+	}
+
+	return Span{
+		Line:    line,
+		Column:  utf8.RuneCountInString(content[lineStart:start]) + 1,
+		Text:    content[start:end],
+		Snippet: strings.TrimSpace(content[lineStart:lineEnd]),
+	}
+}
+
+// sortSpans orders spans by position and drops spans overlapping an earlier one,
+// which happens when several patterns of a rule match the same text
+func sortSpans(spans []Span) []Span {
+	if len(spans) == 0 {
+		return nil
+	}
+	sort.SliceStable(spans, func(i, j int) bool {
+		if spans[i].Line != spans[j].Line {
+			return spans[i].Line < spans[j].Line
+		}
+		return spans[i].Column < spans[j].Column
+	})
+
+	out := spans[:1]
+	for _, s := range spans[1:] {
+		last := out[len(out)-1]
+		if s.Line == last.Line && s.Column < last.Column+utf8.RuneCountInString(last.Text) {
+			continue
+		}
+		out = append(out, s)
+	}
+	return out
+}
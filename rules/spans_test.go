@@ -0,0 +1,100 @@
+package rules
+
+import (
+	"testing"
+)
+
+func TestFindSpans_RegexMatch(t *testing.T) {
+	ctx := makeCtx("# Style\n- Use camelCase for vars\n- Use snake_case for columns", 3, 2, nil)
+	spec := &MatchSpec{Action: ActionRegexMatch, Patterns: []string{"use\\s+camelCase", "use\\s+snake_case"}}
+
+	spans := FindSpans(ctx, spec)
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	if spans[0].Line != 2 || spans[0].Column != 3 || spans[0].Text != "Use camelCase" {
+		t.Errorf("unexpected first span: %+v", spans[0])
+	}
+	if spans[1].Line != 3 || spans[1].Snippet != "- Use snake_case for columns" {
+		t.Errorf("unexpected second span: %+v", spans[1])
+	}
+}
+
+func TestFindSpans_OverlappingPatternsReportedOnce(t *testing.T) {
+	ctx := makeCtx("Use single quotes for strings", 1, 1, nil)
+	spec := &MatchSpec{Action: ActionRegexMatch, Patterns: []string{"(single|double)\\s*quotes?", "use\\s+(single|double)\\s+quotes"}}
+
+	spans := FindSpans(ctx, spec)
+	if len(spans) != 1 || spans[0].Column != 1 {
+		t.Errorf("expected a single span at column 1, got %+v", spans)
+	}
+}
+
+func TestFindSpans_Contains(t *testing.T) {
+	ctx := makeCtx("intro\nsee TODO here and todo there", 2, 0, nil)
+
+	spans := FindSpans(ctx, &MatchSpec{Action: ActionContains, Value: "todo"})
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 case-insensitive spans, got %d", len(spans))
+	}
+	if spans[0].Line != 2 || spans[0].Column != 5 || spans[0].Text != "TODO" {
+		t.Errorf("unexpected span: %+v", spans[0])
+	}
+}
+
+func TestFindSpans_IsPresentValueIsCaseSensitive(t *testing.T) {
+	ctx := makeCtx("Make test\nmake test", 2, 0, nil)
+
+	spans := FindSpans(ctx, &MatchSpec{Action: ActionIsPresent, Value: "make test"})
+	if len(spans) != 1 || spans[0].Line != 2 {
+		t.Errorf("expected one span on line 2, got %+v", spans)
+	}
+}
+
+func TestFindSpans_NoLocations(t *testing.T) {
+	ctx := makeCtx("hello", 500, 0, map[string]any{"stack": "go"})
+
+	tests := []struct {
+		name string
+		spec MatchSpec
+	}{
+		{"metric comparison", MatchSpec{Metric: MetricLineCount, Action: ActionGreaterThan, Value: 300}},
+		{"absence check", MatchSpec{Action: ActionRegexNotMatch, Patterns: []string{"pytest"}}},
+		{"non-content metric", MatchSpec{Metric: "stack", Action: ActionContains, Value: "go"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if spans := FindSpans(ctx, &tc.spec); len(spans) != 0 {
+				t.Errorf("expected no spans, got %+v", spans)
+			}
+		})
+	}
+}
+
+func TestFindSpans_AndOr(t *testing.T) {
+	ctx := makeCtx("line one\nuse tabs", 2, 1, nil)
+
+	and := &MatchSpec{Action: ActionAnd, SubMatch: []MatchSpec{
+		{Metric: MetricLineCount, Action: ActionGreaterThan, Value: 1},
+		{Action: ActionRegexMatch, Patterns: []string{"tabs"}},
+	}}
+	if spans := FindSpans(ctx, and); len(spans) != 1 || spans[0].Line != 2 {
+		t.Errorf("and: expected span from regex sub-match, got %+v", spans)
+	}
+
+	or := &MatchSpec{Action: ActionOr, SubMatch: []MatchSpec{
+		{Action: ActionContains, Value: "spaces"},
+		{Action: ActionContains, Value: "line"},
+	}}
+	if spans := FindSpans(ctx, or); len(spans) != 1 || spans[0].Text != "line" {
+		t.Errorf("or: expected span only from matching sub-spec, got %+v", spans)
+	}
+}
+
+func TestSpanAt_MultiByteColumns(t *testing.T) {
+	content := "→ use tabs"
+	span := spanAt(content, len("→ "), len(content))
+	if span.Column != 3 {
+		t.Errorf("expected column counted in characters (3), got %d", span.Column)
+	}
+}
@@ -1,7 +1,6 @@
 package rules
 
 import (
-	"os"
 	"path/filepath"
 )
 
@@ -21,6 +20,8 @@ func DefaultStackMarkers() []StackMarker {
 		{Name: "typescript", Markers: []string{"tsconfig.json"}, IsDir: false},
 		{Name: "rust", Markers: []string{"Cargo.toml"}, IsDir: false},
 		{Name: "make", Markers: []string{"Makefile", "makefile", "GNUmakefile"}, IsDir: false},
+		{Name: "task", Markers: []string{"Taskfile.yml", "Taskfile.yaml", "taskfile.yml", "taskfile.yaml", "Taskfile.dist.yml", "Taskfile.dist.yaml"}, IsDir: false},
+		{Name: "just", Markers: []string{"justfile", "Justfile", ".justfile"}, IsDir: false},
 		{Name: "docker", Markers: []string{"Dockerfile", "docker-compose.yml", "docker-compose.yaml", "compose.yml", "compose.yaml"}, IsDir: false},
 		{Name: "github-actions", Markers: []string{".github/workflows"}, IsDir: true},
 	}
@@ -37,7 +38,7 @@ func DetectStacksWithMarkers(rootDir string, markers []StackMarker) []string {
 	for _, sm := range markers {
 		for _, marker := range sm.Markers {
 			path := filepath.Join(rootDir, marker)
-			info, err := os.Stat(path)
+			info, err := activeSource.Stat(path)
 			if err != nil {
 				continue
 			}
@@ -0,0 +1,183 @@
+package rules
+
+import (
+	"regexp"
+	"strings"
+)
+
+// suppressDirectivePattern matches HTML-comment directives such as
+// <!-- context-doctor-disable-next-line CD011 --> or <!-- context-doctor-enable -->
+var suppressDirectivePattern = regexp.MustCompile(`<!--\s*context-doctor-(disable-next-line|disable|enable)\b([^>]*?)-->`)
+
+// allRules is the code recorded when a directive lists no rule codes
+const allRules = "*"
+
+// suppressRange disables a rule code from Start to End (1-based, inclusive).
+// End is 0 when the range is never re-enabled and runs to the end of the file.
+type suppressRange struct {
+	Code  string
+	Start int
+	End   int
+}
+
+// Suppressions records which rules are disabled on which lines of a file
+type Suppressions struct {
+	ranges []suppressRange
+}
+
+// ParseSuppressions scans lines for context-doctor disable/enable directives.
+// With doc, directives in code blocks, frontmatter and HTML blocks other than
+// comments are ignored, so an example of the syntax doesn't take effect.
+func ParseSuppressions(lines []string, doc *MarkdownDoc) *Suppressions {
+	s := &Suppressions{}
+	open := make(map[string]int) // code -> index into ranges of its open disable block
+	ignored := ignoredDirectiveLines(lines, doc)
+
+	for i, line := range lines {
+		lineNum := i + 1
+		if ignored[lineNum] {
+			continue
+		}
+		for _, m := range suppressDirectivePattern.FindAllStringSubmatch(line, -1) {
+			directive := m[1]
+			codes := parseDirectiveCodes(m[2])
+
+			switch directive {
+			case "disable-next-line":
+				for _, code := range codes {
+					s.ranges = append(s.ranges, suppressRange{Code: code, Start: lineNum + 1, End: lineNum + 1})
+				}
+			case "disable":
+				for _, code := range codes {
+					if _, already := open[code]; already {
+						continue
+					}
+					open[code] = len(s.ranges)
+					s.ranges = append(s.ranges, suppressRange{Code: code, Start: lineNum})
+				}
+			case "enable":
+				// A bare enable closes every open block
+				if len(codes) == 1 && codes[0] == allRules {
+					codes = codes[:0]
+					for code := range open {
+						codes = append(codes, code)
+					}
+				}
+				for _, code := range codes {
+					if idx, ok := open[code]; ok {
+						s.ranges[idx].End = lineNum
+						delete(open, code)
+					}
+				}
+			}
+		}
+	}
+
+	return s
+}
+
+// ignoredDirectiveLines returns the lines of doc whose directives are
+// samples: those in code blocks, frontmatter, or HTML blocks that don't open
+// with a comment
+func ignoredDirectiveLines(lines []string, doc *MarkdownDoc) map[int]bool {
+	ignored := map[int]bool{}
+	if doc == nil {
+		return ignored
+	}
+	doc.Walk(func(n *MarkdownNode) bool {
+		switch n.Type {
+		case NodeCodeBlock, NodeFrontmatter:
+		case NodeHTMLBlock:
+			if n.Line <= len(lines) && strings.HasPrefix(strings.TrimSpace(lines[n.Line-1]), "<!--") {
+				return false
+			}
+		default:
+			return true
+		}
+		for l := n.Line; l <= n.EndLine; l++ {
+			ignored[l] = true
+		}
+		return false
+	})
+	return ignored
+}
+
+func parseDirectiveCodes(s string) []string {
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	})
+	if len(fields) == 0 {
+		return []string{allRules}
+	}
+	codes := make([]string, len(fields))
+	for i, f := range fields {
+		codes[i] = strings.ToUpper(f)
+	}
+	return codes
+}
+
+// IsSuppressed reports whether code is disabled on the given line
+func (s *Suppressions) IsSuppressed(code string, line int) bool {
+	if s == nil {
+		return false
+	}
+	code = strings.ToUpper(code)
+	for _, r := range s.ranges {
+		if r.Code != allRules && r.Code != code {
+			continue
+		}
+		if line >= r.Start && (r.End == 0 || line <= r.End) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsSuppressedForFile reports whether code is disabled through the end of the
+// file. File-level findings (line counts, missing content) have no line of
+// their own, so only a disable block that is never re-enabled suppresses them.
+func (s *Suppressions) IsSuppressedForFile(code string) bool {
+	if s == nil {
+		return false
+	}
+	code = strings.ToUpper(code)
+	for _, r := range s.ranges {
+		if (r.Code == allRules || r.Code == code) && r.End == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// applySuppressions moves suppressed locations out of a detected problem.
+// When nothing remains the result no longer counts as detected.
+func applySuppressions(ctx *AnalysisContext, result *RuleResult) {
+	if !result.Passed || result.Rule.Category == "good-practice" || ctx.Suppressions == nil {
+		return
+	}
+
+	if len(result.Spans) == 0 {
+		if ctx.Suppressions.IsSuppressedForFile(result.Rule.Code) {
+			result.Passed = false
+			result.Suppressed = true
+			result.Message = ""
+		}
+		return
+	}
+
+	var kept []Span
+	for _, span := range result.Spans {
+		if ctx.Suppressions.IsSuppressed(result.Rule.Code, span.Line) {
+			result.SuppressedSpans = append(result.SuppressedSpans, span)
+			continue
+		}
+		kept = append(kept, span)
+	}
+	result.Spans = kept
+
+	if len(kept) == 0 {
+		result.Passed = false
+		result.Suppressed = true
+		result.Message = ""
+	}
+}
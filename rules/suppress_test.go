@@ -0,0 +1,155 @@
+package rules
+
+import (
+	"strings"
+	"testing"
+)
+
+// parseSuppressions parses directives the way BuildContext does
+func parseSuppressions(lines []string) *Suppressions {
+	return ParseSuppressions(lines, ParseMarkdown(lines))
+}
+
+func TestParseSuppressions_NextLine(t *testing.T) {
+	s := parseSuppressions([]string{
+		"<!-- context-doctor-disable-next-line CD011, cd013 -->",
+		"- max line length 80, use camelCase",
+		"- max line length 100",
+	})
+
+	if !s.IsSuppressed("CD011", 2) || !s.IsSuppressed("CD013", 2) {
+		t.Error("expected CD011 and CD013 suppressed on line 2 (codes are case-insensitive)")
+	}
+	if s.IsSuppressed("CD011", 3) {
+		t.Error("disable-next-line must only cover the next line")
+	}
+	if s.IsSuppressed("CD012", 2) {
+		t.Error("unlisted codes must not be suppressed")
+	}
+}
+
+func TestParseSuppressions_Block(t *testing.T) {
+	s := parseSuppressions([]string{
+		"intro",
+		"<!-- context-doctor-disable CD050 -->",
+		"write clean code",
+		"<!-- context-doctor-enable CD050 -->",
+		"follow best practices",
+	})
+
+	if !s.IsSuppressed("CD050", 3) {
+		t.Error("expected CD050 suppressed inside the block")
+	}
+	if s.IsSuppressed("CD050", 1) || s.IsSuppressed("CD050", 5) {
+		t.Error("expected CD050 active outside the block")
+	}
+	if s.IsSuppressedForFile("CD050") {
+		t.Error("a closed block must not suppress file-level findings")
+	}
+}
+
+func TestParseSuppressions_IgnoresExamples(t *testing.T) {
+	s := parseSuppressions([]string{
+		"Disable a rule for the next line:",
+		"",
+		"```markdown",
+		"<!-- context-doctor-disable CD011 -->",
+		"```",
+		"",
+		"<pre>",
+		"<!-- context-doctor-disable CD012 -->",
+		"</pre>",
+		"",
+		"- max line length 80",
+		"<!-- context-doctor-disable-next-line CD013 -->",
+		"- use camelCase",
+	})
+
+	if s.IsSuppressed("CD011", 11) || s.IsSuppressedForFile("CD011") {
+		t.Error("a directive in a fenced code block must not disable rules")
+	}
+	if s.IsSuppressed("CD012", 11) {
+		t.Error("a directive inside an HTML block must not disable rules")
+	}
+	if !s.IsSuppressed("CD013", 13) {
+		t.Error("expected the directive outside the examples to apply")
+	}
+}
+
+func TestParseSuppressions_UnclosedAndBare(t *testing.T) {
+	s := parseSuppressions([]string{
+		"<!-- context-doctor-disable -->",
+		"anything",
+		"<!-- context-doctor-disable CD001 -->",
+	})
+
+	if !s.IsSuppressed("CD999", 2) {
+		t.Error("a bare disable should suppress every rule")
+	}
+	if !s.IsSuppressedForFile("CD001") {
+		t.Error("an unclosed disable should suppress file-level findings")
+	}
+
+	s = parseSuppressions([]string{
+		"<!-- context-doctor-disable CD010 -->",
+		"<!-- context-doctor-disable CD011 -->",
+		"<!-- context-doctor-enable -->",
+		"tail",
+	})
+	if s.IsSuppressed("CD010", 4) || s.IsSuppressed("CD011", 4) {
+		t.Error("a bare enable should close every open block")
+	}
+}
+
+func TestSuppressions_NilSafe(t *testing.T) {
+	var s *Suppressions
+	if s.IsSuppressed("CD001", 1) || s.IsSuppressedForFile("CD001") {
+		t.Error("nil suppressions must not suppress anything")
+	}
+}
+
+func TestEvaluate_HonoursSuppressions(t *testing.T) {
+	content := strings.Join([]string{
+		"# Style",
+		"<!-- context-doctor-disable-next-line CD011 -->",
+		"- max line length 80",
+		"- maximum width 100",
+		"<!-- context-doctor-disable CD014 -->",
+		"- never use semicolons",
+	}, "\n")
+	ctx := BuildContext("CLAUDE.md", content)
+
+	engine := NewEngine([]Rule{
+		{Code: "CD011", MatchSpec: MatchSpec{Action: ActionRegexMatch, Patterns: []string{"(max|maximum)\\s*(line)?\\s*(length|width)"}}},
+		{Code: "CD014", MatchSpec: MatchSpec{Action: ActionRegexMatch, Patterns: []string{"semicolons?"}}},
+		{Code: "CD001", MatchSpec: MatchSpec{Metric: MetricLineCount, Action: ActionGreaterThan, Value: 1}},
+	})
+	results := engine.Evaluate(ctx)
+
+	partial := results[0]
+	if !partial.Passed || partial.Suppressed {
+		t.Error("CD011: expected still detected on its unsuppressed line")
+	}
+	if len(partial.Spans) != 1 || partial.Spans[0].Line != 4 || len(partial.SuppressedSpans) != 1 {
+		t.Errorf("CD011: expected line 4 kept and line 3 suppressed, got %+v / %+v", partial.Spans, partial.SuppressedSpans)
+	}
+
+	full := results[1]
+	if full.Passed || !full.Suppressed || full.Message != "" {
+		t.Errorf("CD014: expected fully suppressed, got %+v", full)
+	}
+
+	if !results[2].Passed {
+		t.Error("CD001: file-level finding must not be suppressed by a closed or unrelated block")
+	}
+}
+
+func TestEvaluate_SuppressionIgnoresGoodPractice(t *testing.T) {
+	ctx := BuildContext("CLAUDE.md", "<!-- context-doctor-disable -->\nsee docs/a.md")
+	engine := NewEngine([]Rule{
+		{Code: "CD040", Category: "good-practice", MatchSpec: MatchSpec{Action: ActionRegexMatch, Patterns: []string{"see\\s+\\S+\\.md"}}},
+	})
+	if results := engine.Evaluate(ctx); !results[0].Passed {
+		t.Error("good-practice results should not be suppressed")
+	}
+}
@@ -0,0 +1,231 @@
+package rules
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DefaultTokenizer is the tokenizer used when none is configured
+const DefaultTokenizer = "estimate"
+
+// Tokenizer counts the tokens a model would see for a piece of text
+type Tokenizer interface {
+	Name() string
+	CountTokens(text string) int
+}
+
+// TokenizerFactory creates a tokenizer. vocab is the vocabulary file given with
+// -tokenizer-vocab; tokenizers that bundle their data ignore it.
+type TokenizerFactory func(vocab string) (Tokenizer, error)
+
+// TokenizerRegistry holds the available tokenizers by name
+var TokenizerRegistry map[string]TokenizerFactory
+
+func init() {
+	TokenizerRegistry = map[string]TokenizerFactory{
+		"estimate": func(string) (Tokenizer, error) { return estimateTokenizer{}, nil },
+		"chars":    func(string) (Tokenizer, error) { return charsTokenizer{}, nil },
+		"bpe":      LoadBPETokenizer,
+	}
+}
+
+// activeTokenizer counts tokens for every context built by BuildContext
+var activeTokenizer Tokenizer = estimateTokenizer{}
+
+// NewTokenizer creates the named tokenizer from the registry
+func NewTokenizer(name, vocab string) (Tokenizer, error) {
+	factory, ok := TokenizerRegistry[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown tokenizer %q (available: %s)", name, strings.Join(TokenizerNames(), ", "))
+	}
+	return factory(vocab)
+}
+
+// TokenizerNames returns the registered tokenizer names, sorted
+func TokenizerNames() []string {
+	names := make([]string, 0, len(TokenizerRegistry))
+	for name := range TokenizerRegistry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetTokenizer makes t the tokenizer used for token metrics
+func SetTokenizer(t Tokenizer) {
+	activeTokenizer = t
+}
+
+// ActiveTokenizer returns the tokenizer used for token metrics
+func ActiveTokenizer() Tokenizer {
+	return activeTokenizer
+}
+
+// CountTokens counts the tokens in text with the active tokenizer
+func CountTokens(text string) int {
+	if text == "" {
+		return 0
+	}
+	return activeTokenizer.CountTokens(text)
+}
+
+// pretokenizePattern splits text roughly the way GPT-2's BPE does before
+// merging: contractions, words with their leading space, numbers in groups of
+// up to three digits, punctuation runs and whitespace. Newer encodings such as
+// cl100k_base split differently (their pattern needs lookahead, which regexp
+// lacks), so counts with their vocabularies are close but not exact.
+var pretokenizePattern = regexp.MustCompile(`'(?:[sdmt]|ll|ve|re)| ?\pL+| ?\pN{1,3}| ?[^\s\pL\pN]+|\s+`)
+
+// estimateTokenizer approximates BPE token counts without a vocabulary. Common
+// words are a single token and longer runs are split every few characters.
+type estimateTokenizer struct{}
+
+func (estimateTokenizer) Name() string { return "estimate" }
+
+func (estimateTokenizer) CountTokens(text string) int {
+	count := 0
+	for _, piece := range pretokenizePattern.FindAllString(text, -1) {
+		word := strings.TrimPrefix(piece, " ")
+		first, _ := utf8.DecodeRuneInString(word)
+		switch {
+		case unicode.IsSpace(first):
+			count++
+		case unicode.IsLetter(first):
+			count += letterTokens(word)
+		case unicode.IsDigit(first):
+			count++
+		default:
+			count += ceilDiv(utf8.RuneCountInString(word), 3)
+		}
+	}
+	return count
+}
+
+// letterTokens estimates a run of letters: about six Latin letters per token,
+// one token per character for scripts that vocabularies rarely merge
+func letterTokens(word string) int {
+	latin, other := 0, 0
+	for _, r := range word {
+		if r < 0x0250 {
+			latin++
+		} else {
+			other++
+		}
+	}
+	return max(1, ceilDiv(latin, 6)+other)
+}
+
+// charsTokenizer is the "four characters per token" rule of thumb
+type charsTokenizer struct{}
+
+func (charsTokenizer) Name() string { return "chars" }
+
+func (charsTokenizer) CountTokens(text string) int {
+	return ceilDiv(utf8.RuneCountInString(text), 4)
+}
+
+func ceilDiv(n, d int) int {
+	return (n + d - 1) / d
+}
+
+// BPETokenizer is a byte-level BPE tokenizer using a tiktoken rank file, where
+// each line is a base64 token followed by its merge rank (e.g. cl100k_base.tiktoken).
+// Text is split with pretokenizePattern, not the encoding's own pattern.
+type BPETokenizer struct {
+	name  string
+	ranks map[string]int
+}
+
+// LoadBPETokenizer reads a tiktoken rank file
+func LoadBPETokenizer(path string) (Tokenizer, error) {
+	if path == "" {
+		return nil, fmt.Errorf("the bpe tokenizer needs a vocabulary file (-tokenizer-vocab)")
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tokenizer vocabulary: %w", err)
+	}
+	defer f.Close()
+
+	t := &BPETokenizer{
+		name:  "bpe:" + strings.TrimSuffix(filepath.Base(path), ".tiktoken"),
+		ranks: make(map[string]int),
+	}
+	scanner := bufio.NewScanner(f)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: expected \"<base64 token> <rank>\"", path, lineNum)
+		}
+		token, err := base64.StdEncoding.DecodeString(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid token: %w", path, lineNum, err)
+		}
+		rank, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid rank: %w", path, lineNum, err)
+		}
+		t.ranks[string(token)] = rank
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read tokenizer vocabulary: %w", err)
+	}
+	if len(t.ranks) == 0 {
+		return nil, fmt.Errorf("tokenizer vocabulary %s is empty", path)
+	}
+	return t, nil
+}
+
+func (t *BPETokenizer) Name() string { return t.name }
+
+func (t *BPETokenizer) CountTokens(text string) int {
+	count := 0
+	for _, piece := range pretokenizePattern.FindAllString(text, -1) {
+		count += t.countPiece(piece)
+	}
+	return count
+}
+
+// countPiece merges the lowest-ranked adjacent pair until no pair is in the
+// vocabulary; the remaining parts are the tokens
+func (t *BPETokenizer) countPiece(piece string) int {
+	if _, ok := t.ranks[piece]; ok {
+		return 1
+	}
+
+	parts := make([]string, len(piece))
+	for i := range len(piece) {
+		parts[i] = piece[i : i+1]
+	}
+
+	for len(parts) > 1 {
+		best, bestRank := -1, math.MaxInt
+		for i := 0; i < len(parts)-1; i++ {
+			if rank, ok := t.ranks[parts[i]+parts[i+1]]; ok && rank < bestRank {
+				best, bestRank = i, rank
+			}
+		}
+		if best < 0 {
+			break
+		}
+		parts[best] += parts[best+1]
+		parts = append(parts[:best+1], parts[best+2:]...)
+	}
+	return len(parts)
+}
@@ -0,0 +1,135 @@
+package rules
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEstimateTokenizer(t *testing.T) {
+	tok := estimateTokenizer{}
+
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"Hello world", 2},
+		{"Run make test", 3},
+		{"internationalization", 4}, // 20 letters, split every 6
+		{"2024", 2},                 // numbers are grouped by three digits
+		{"```", 1},
+		{"line one\nline two", 5},
+	}
+
+	for _, tt := range tests {
+		if got := tok.CountTokens(tt.text); got != tt.want {
+			t.Errorf("CountTokens(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestCharsTokenizer(t *testing.T) {
+	if got := (charsTokenizer{}).CountTokens("abcdefghi"); got != 3 {
+		t.Errorf("expected 3 tokens for 9 chars, got %d", got)
+	}
+}
+
+func writeVocab(t *testing.T, tokens ...string) string {
+	t.Helper()
+	var b strings.Builder
+	for rank, tok := range tokens {
+		fmt.Fprintf(&b, "%s %d\n", base64.StdEncoding.EncodeToString([]byte(tok)), rank)
+	}
+	path := filepath.Join(t.TempDir(), "test.tiktoken")
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestBPETokenizer(t *testing.T) {
+	path := writeVocab(t, "te", "st", "test", " t", " te", " tes")
+
+	tok, err := NewTokenizer("bpe", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tok.Name() != "bpe:test" {
+		t.Errorf("unexpected name %q", tok.Name())
+	}
+
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"test", 1},      // whole piece is a token
+		{"tests", 2},     // test + s
+		{" test", 2},     // " te" + "st" merge before " tes"
+		{"test test", 3}, // test, " te", "st"
+		{"xyz", 3},       // unknown bytes stay single tokens
+	}
+
+	for _, tt := range tests {
+		if got := tok.CountTokens(tt.text); got != tt.want {
+			t.Errorf("CountTokens(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestLoadBPETokenizer_Errors(t *testing.T) {
+	if _, err := LoadBPETokenizer(""); err == nil {
+		t.Error("expected error without a vocabulary file")
+	}
+
+	path := filepath.Join(t.TempDir(), "bad.tiktoken")
+	if err := os.WriteFile(path, []byte("not-base64!! 0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadBPETokenizer(path); err == nil {
+		t.Error("expected error for invalid token")
+	}
+}
+
+func TestNewTokenizer_Unknown(t *testing.T) {
+	_, err := NewTokenizer("nope", "")
+	if err == nil || !strings.Contains(err.Error(), "estimate") {
+		t.Errorf("expected error listing available tokenizers, got %v", err)
+	}
+}
+
+func TestTokenCountMetric(t *testing.T) {
+	SetTokenizer(charsTokenizer{})
+	defer SetTokenizer(estimateTokenizer{})
+
+	ctx := BuildContext("CLAUDE.md", sectionedDoc)
+	if want := CountTokens(sectionedDoc); ctx.TokenCount != want {
+		t.Errorf("TokenCount = %d, want %d", ctx.TokenCount, want)
+	}
+
+	// Build & Test covers its own three lines, not the Integration subsection
+	build := ctx.Sections.FindSections("build")[0]
+	own := strings.Join(ctx.Lines[build.Line-1:build.Line+2], "\n")
+	if want := CountTokens(own); build.TokenCount != want {
+		t.Errorf("build section TokenCount = %d, want %d", build.TokenCount, want)
+	}
+
+	spec := &MatchSpec{Metric: MetricTokenCount, Action: ActionGreaterThan, Value: 10}
+	if !EvaluateSpec(ctx, spec) {
+		t.Error("expected file-level tokenCount check to match")
+	}
+
+	spec = &MatchSpec{Metric: MetricTokenCount, Action: ActionGreaterThan, Value: 12, Section: AllSections}
+	spans := FindSpans(ctx, spec)
+	if len(spans) == 0 {
+		t.Fatal("expected section-scoped tokenCount check to point at headings")
+	}
+	for _, s := range spans {
+		if !strings.HasPrefix(s.Snippet, "#") {
+			t.Errorf("expected span on a heading, got %q", s.Snippet)
+		}
+	}
+}
@@ -49,6 +49,7 @@ type MetricType string
 const (
 	MetricLineCount        MetricType = "lineCount"
 	MetricInstructionCount MetricType = "instructionCount"
+	MetricTokenCount       MetricType = "tokenCount"
 	MetricContent          MetricType = "content"
 )
 
@@ -59,6 +60,8 @@ type MatchSpec struct {
 	Value    any         `yaml:"value,omitempty" json:"value,omitempty"`
 	Patterns []string    `yaml:"patterns,omitempty" json:"patterns,omitempty"`
 	SubMatch []MatchSpec `yaml:"subMatch,omitempty" json:"subMatch,omitempty"`
+	Nodes    []NodeType  `yaml:"nodes,omitempty" json:"nodes,omitempty"`     // markdown nodes content actions look at (default: all but code and HTML; every line for absence checks)
+	Section  string      `yaml:"section,omitempty" json:"section,omitempty"` // heading regex ("*" = every section) limiting the check to matching sections
 }
 
 // Rule defines a single check rule
@@ -72,6 +75,7 @@ type Rule struct {
 	MatchSpec    MatchSpec `yaml:"matchSpec" json:"matchSpec"`
 	ErrorMessage string    `yaml:"errorMessage" json:"errorMessage"`
 	Suggestion   string    `yaml:"suggestion,omitempty" json:"suggestion,omitempty"`
+	Fix          *FixSpec  `yaml:"fix,omitempty" json:"fix,omitempty"`
 	Links        []string  `yaml:"links,omitempty" json:"links,omitempty"`
 }
 
@@ -83,18 +87,39 @@ type RulesFile struct {
 
 // RuleResult represents the result of evaluating a rule
 type RuleResult struct {
-	Rule    Rule
-	Passed  bool
-	Message string
-	Details map[string]any
+	Rule            Rule
+	Passed          bool
+	Message         string
+	Spans           []Span // where in the file the rule matched (content-matching actions only)
+	Suppressed      bool   // the problem was detected but every location is disabled by a directive
+	SuppressedSpans []Span // locations disabled by context-doctor-disable directives
+	Baselined       bool   // the problem was detected but every location is recorded in the baseline
+	BaselinedSpans  []Span // locations hidden because they are recorded in the baseline
+	Details         map[string]any
+}
+
+// ImportedContent is the size of the files a context file pulls in with @imports
+type ImportedContent struct {
+	Files        int
+	Lines        int
+	Instructions int
+	Tokens       int
 }
 
 // AnalysisContext holds all computed metrics for rule evaluation
 type AnalysisContext struct {
 	FilePath         string
+	Format           *ContextFormat // nil for files matching no registered format
+	Frontmatter      *Frontmatter   // nil unless the format declares one and the file opens with it
 	Content          string
 	Lines            []string
 	LineCount        int
 	InstructionCount int
+	TokenCount       int             // tokens counted by the active tokenizer
+	Imported         ImportedContent // @imports counted into LineCount, InstructionCount and TokenCount
+	Suppressions     *Suppressions   // inline context-doctor-disable directives
+	Markdown         *MarkdownDoc    // block structure of Content
+	Sections         *Section        // heading tree; the root holds any text before the first heading
 	Metrics          map[string]any
+	MetricSpans      map[MetricType][]Span // where the items a count metric counts appear, e.g. each dead command
 }
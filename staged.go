@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"context-doctor/rules"
+)
+
+var staged bool
+
+// runStaged analyses the index version of the context files at target, read
+// from git objects together with their referenced docs and the repo files
+// they are checked against, so partially staged edits are judged as they
+// will be committed. In a directory, only the context files that are staged
+// or reference a staged doc are analysed.
+func runStaged(target string, gates gateOptions) int {
+	dir := target
+	if info, err := os.Stat(target); err != nil || !info.IsDir() {
+		dir = filepath.Dir(absPath(target))
+	}
+	root := rules.GetGitRoot(dir)
+	if root == "" {
+		fmt.Fprintf(os.Stderr, "Error: %s is not in a git repository\n", target)
+		return exitError
+	}
+
+	src, err := rules.NewGitSource(root, rules.StagedRevision)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitError
+	}
+	defer src.Close()
+	rules.SetSource(src)
+
+	info, err := src.Stat(target)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s is not in the index\n", target)
+		return exitError
+	}
+
+	if !info.IsDir() {
+		fa, err := analyzeFile(target)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return exitError
+		}
+		return reportGateFailures(gates.checkFile(fa, target))
+	}
+
+	files, err := stagedContextFiles(root, target)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitError
+	}
+	if len(files) == 0 {
+		fmt.Fprintf(os.Stderr, "No staged context files in %s\n", target)
+		return exitOK
+	}
+	ra := analyzeRepo(target, files)
+	if len(ra.Analyses) == 0 {
+		return exitError
+	}
+	return reportGateFailures(gates.checkRepo(ra))
+}
+
+// stagedContextFiles returns the context files under dir, as the active
+// source lists them, that are staged themselves or reference a staged doc,
+// directly or through other docs
+func stagedContextFiles(root, dir string) ([]string, error) {
+	changed, err := rules.StagedFiles(root)
+	if err != nil {
+		return nil, err
+	}
+	isStaged := func(path string) bool {
+		rel, ok := rules.RepoPath(root, path)
+		return ok && changed[rel]
+	}
+
+	var files []string
+	for _, rel := range rules.ActiveSource().ListFiles(dir) {
+		if rules.ContextFileFormat(rel) == nil {
+			continue
+		}
+		path := filepath.Join(dir, filepath.FromSlash(rel))
+		if isStaged(path) || referencesStaged(path, isStaged) {
+			files = append(files, path)
+		}
+	}
+	return files, nil
+}
+
+// referencesStaged reports whether a doc in the reference tree of the
+// context file at path is staged, including one the index deletes
+func referencesStaged(path string, isStaged func(string) bool) bool {
+	content, err := rules.ActiveSource().ReadFile(path)
+	if err != nil {
+		return false
+	}
+	ctx := rules.BuildContext(path, string(content))
+	for _, ref := range rules.FlattenRefs(rules.ResolveReferences(ctx, filepath.Dir(path), staleThreshold)) {
+		if isStaged(ref.ResolvedPath) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"context-doctor/rules"
+)
+
+func TestStagedContextFiles(t *testing.T) {
+	root, git := gitRepo(t)
+
+	defer func(prev float64) { similarityThreshold = prev }(similarityThreshold)
+	similarityThreshold = rules.DefaultSimilarityThreshold
+
+	writeFiles(t, root, map[string]string{
+		"CLAUDE.md":     "# Project\n\nSee docs/guide.md for details.\n",
+		"docs/guide.md": "# Guide\n",
+		"api/AGENTS.md": "# API\n",
+		"web/AGENTS.md": "# Web\n",
+	})
+	git("add", ".")
+	git("commit", "-qm", "initial")
+
+	// The guide is staged, which pulls in the context file referencing it;
+	// api is staged, web only edited and new/CLAUDE.md untracked
+	writeFiles(t, root, map[string]string{
+		"docs/guide.md": "# Guide\n\n- Indent with 4 spaces\n",
+		"api/AGENTS.md": "# API v2\n",
+	})
+	git("add", "docs/guide.md", "api/AGENTS.md")
+	writeFiles(t, root, map[string]string{
+		"web/AGENTS.md": "# Web v2\n",
+		"new/CLAUDE.md": "# New\n",
+	})
+
+	src, err := rules.NewGitSource(root, rules.StagedRevision)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	defer rules.SetSource(rules.ActiveSource())
+	rules.SetSource(src)
+
+	files, err := stagedContextFiles(root, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, f := range files {
+		got = append(got, diffPath(root, f))
+	}
+	if strings.Join(got, ",") != "CLAUDE.md,api/AGENTS.md" {
+		t.Errorf("unexpected staged context files %v", got)
+	}
+
+	// The analysis sees the staged guide, not the working tree
+	fa, err := buildAnalysis(filepath.Join(root, "CLAUDE.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if countFindingsAtOrAbove(fa, rules.SeverityWarning) == 0 {
+		t.Error("expected the staged guide's indentation rule to be found")
+	}
+}